@@ -0,0 +1,74 @@
+// Command matcha-gen writes matcha expected structs for the JSON responses in an OpenAPI 3 document,
+// along with example payloads that match them. It is meant to be run by go generate, e.g.
+//
+//	//go:generate go run github.com/ingresso-group/go-matcha/cmd/matcha-gen -spec openapi.yaml -out expected_gen.go -examples testdata/examples
+//
+// Each response gets a struct named after its operation and status, such as GetBooking200Response,
+// and its example is written to a file of the same name in the examples directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ingresso-group/go-matcha/matcha"
+)
+
+func main() {
+	spec := flag.String("spec", "", "the OpenAPI 3 document to read, as JSON or YAML")
+	out := flag.String("out", "", "the Go file to write, or nothing to write to standard output")
+	packageName := flag.String("package", os.Getenv("GOPACKAGE"), "the package of the Go file, which go generate sets")
+	examples := flag.String("examples", "", "a directory to write example payloads to, or nothing to not write any")
+	flag.Parse()
+
+	if err := generate(*spec, *out, *packageName, *examples); err != nil {
+		fmt.Fprintf(os.Stderr, "matcha-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func generate(specPath string, out string, packageName string, examplesDir string) error {
+	if specPath == "" {
+		return fmt.Errorf("-spec is required")
+	}
+	if packageName == "" {
+		return fmt.Errorf("-package is required when not run by go generate")
+	}
+
+	spec, err := matcha.ReadOpenAPI(specPath)
+	if err != nil {
+		return err
+	}
+	source, err := matcha.GenerateExpected(spec, matcha.GenerateOptions{Package: packageName, Source: filepath.ToSlash(specPath)})
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(source)
+	} else {
+		err = ioutil.WriteFile(out, source, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if examplesDir == "" {
+		return nil
+	}
+	examples, err := matcha.GenerateExamples(spec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(examplesDir, 0755); err != nil {
+		return err
+	}
+	for name, example := range examples {
+		if err := ioutil.WriteFile(filepath.Join(examplesDir, name+".json"), example, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,5 @@
+package examples
+
+// The expected structs for the bookings API and their example payloads are generated from its OpenAPI
+// document
+//go:generate go run ../cmd/matcha-gen -spec testdata/openapi/bookings.yaml -out bookings_expected.go -examples testdata/bookings
@@ -0,0 +1,33 @@
+// Code generated by matcha-gen from testdata/openapi/bookings.yaml. DO NOT EDIT.
+
+package examples
+
+// ListBookings200Response is the 200 response of listBookings
+type ListBookings200Response struct {
+	Count   int       `json:"count" min:"0"`
+	Results []Booking `json:"results"`
+}
+
+// GetBooking200Response is the 200 response of getBooking
+type GetBooking200Response = Booking
+
+// GetBookingDefaultResponse is the default response of getBooking
+type GetBookingDefaultResponse = Error
+
+// Booking is the Booking schema
+type Booking struct {
+	// Optional properties aren't checked: notes
+	BookingID string `json:"booking_id" pattern:"^B[0-9]{6}$"`
+	Created   string `json:"created" pattern:"^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})$"`
+	Price     struct {
+		Amount   float64 `json:"amount" min:"0"`
+		Currency string  `json:"currency" max:"3" min:"3"`
+	} `json:"price"`
+	Seats  []string `json:"seats" max:"10" min:"1"`
+	Status string   `json:"status" enum:"reserved|confirmed|cancelled"`
+}
+
+// Error is the Error schema
+type Error struct {
+	Message string `json:"message"`
+}
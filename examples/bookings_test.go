@@ -0,0 +1,46 @@
+package examples
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/ingresso-group/go-matcha/matcha"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGeneratedBookings(t *testing.T) {
+	Convey("Given the expected structs generated from the OpenAPI document", t, func() {
+		expected := map[string]interface{}{
+			"ListBookings200Response":   ListBookings200Response{},
+			"GetBooking200Response":     GetBooking200Response{},
+			"GetBookingDefaultResponse": GetBookingDefaultResponse{},
+		}
+
+		Convey("The generated examples should match them", func() {
+			for name, expectedFormat := range expected {
+				example, err := ioutil.ReadFile("testdata/bookings/" + name + ".json")
+				So(err, ShouldBeNil)
+				So(example, matcha.ShouldMatchExpectedJSONResponse, expectedFormat, nil)
+			}
+		})
+
+		Convey("A booking that breaks each tag should say which", func() {
+			example, err := ioutil.ReadFile("testdata/bookings/GetBooking200Response.json")
+			So(err, ShouldBeNil)
+			mutations := []struct{ from, to, failure string }{
+				{`"B000000"`, `"B12"`, "BookingID: 'B12' does not match expected pattern: ^B[0-9]{6}$"},
+				{`"2024-01-01T00:00:00Z"`, `"today"`, `Created: 'today' does not match expected pattern: ^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})$`},
+				{`"amount": 0`, `"amount": -1`, "Amount: -1 is less than the minimum: 0"},
+				{`"currency": "str"`, `"currency": "GBPX"`, "Currency: length 4 is more than the maximum: 3"},
+				{"[\n    \"A12\"\n  ]", "[]", "Seats: length 0 is less than the minimum: 1"},
+				{`"reserved"`, `"lost"`, "Status: 'lost' is not one of: reserved, confirmed, cancelled"},
+			}
+			for _, mutation := range mutations {
+				booking := strings.Replace(string(example), mutation.from, mutation.to, 1)
+				So(booking, ShouldNotEqual, string(example))
+				So(matcha.ShouldMatchExpectedJSONResponse([]byte(booking), GetBooking200Response{}, nil), ShouldEqual, mutation.failure)
+			}
+		})
+	})
+}
@@ -47,3 +47,8 @@ func TestGetWeatherData(t *testing.T) {
 		})
 	})
 }
+
+func TestWeatherDataSnapshot(t *testing.T) {
+	// Count and Created have 'capture' and 'pattern' tags, so they are masked in the snapshot
+	matcha.Snapshot(t, "weather", GetWeatherData(), expectedResponseFormat{})
+}
@@ -0,0 +1,216 @@
+package matcha
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Parallel matches the elements of arrays using a pool of workers. Values are still captured in the
+// order of the elements, but an 'equals' or 'in' tag can't refer to a value captured from an earlier
+// element of the same array.
+func Parallel(workers int) Option {
+	return func(m *Matcher) {
+		m.workers = workers
+	}
+}
+
+// MaxErrors stops matching an array once n of its elements have failed to match
+func MaxErrors(n int) Option {
+	return func(m *Matcher) {
+		m.maxErrors = n
+	}
+}
+
+// AggregateErrors reports an error that is the same for many elements of an array once, with how
+// many elements it was found in and the index of the first of them
+func AggregateErrors() Option {
+	return func(m *Matcher) {
+		m.aggregateErrors = true
+	}
+}
+
+// shouldMatchExpectedElement matches one element of an array
+func (m *Matcher) shouldMatchExpectedElement(actual interface{}, expectedType reflect.Type, fieldName string, i int) string {
+	// Array fields don't have names, so use something intuitive
+	newFieldName := fmt.Sprintf("%v array values", fieldName)
+	if m.fullPaths {
+		newFieldName = fmt.Sprintf("%v[%d]", fieldName, i)
+	}
+	if actual == nil && m.nullablePaths[newFieldName] {
+		return success
+	}
+	m.enterPath(fmt.Sprintf("[%d]", i))
+	defer m.leavePath()
+	return m.shouldMatchExpectedField(actual, expectedType, newFieldName)
+}
+
+// shouldMatchExpectedElements matches the elements of an array in order, and returns the result for
+// each element until MaxErrors of them have failed
+func (m *Matcher) shouldMatchExpectedElements(actual []interface{}, expectedType reflect.Type, fieldName string) []string {
+	results := make([]string, 0, len(actual))
+	failures := 0
+	for i, element := range actual {
+		results = append(results, m.shouldMatchExpectedElement(element, expectedType, fieldName, i))
+		if results[i] != success {
+			failures++
+			if m.maxErrors > 0 && failures >= m.maxErrors {
+				break
+			}
+		}
+	}
+	return results
+}
+
+// shouldMatchExpectedElementsInParallel matches the elements of an array with a pool of workers. Each
+// element is matched by its own copy of the matcher, and what it captured is merged back in the order
+// of the elements, so the results are the same as matching them in order.
+func (m *Matcher) shouldMatchExpectedElementsInParallel(actual []interface{}, expectedType reflect.Type, fieldName string) []string {
+	results := make([]string, len(actual))
+	capturing := m.capturedValues != nil || m.records != nil
+	var elementMatchers []*Matcher
+	if capturing {
+		elementMatchers = make([]*Matcher, len(actual))
+	}
+	variables := m.variables
+	if variables == nil {
+		variables = m.variableStore()
+	}
+
+	var next, failures int64
+	var wait sync.WaitGroup
+	for w := 0; w < m.workers; w++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for {
+				if m.maxErrors > 0 && atomic.LoadInt64(&failures) >= int64(m.maxErrors) {
+					return
+				}
+				i := int(atomic.AddInt64(&next, 1) - 1)
+				if i >= len(actual) {
+					return
+				}
+				elementMatcher := m.elementMatcher(variables)
+				results[i] = elementMatcher.shouldMatchExpectedElement(actual[i], expectedType, fieldName, i)
+				if results[i] != success {
+					atomic.AddInt64(&failures, 1)
+				}
+				if capturing {
+					elementMatchers[i] = elementMatcher
+				}
+			}
+		}()
+	}
+	wait.Wait()
+
+	// Every element before the last one taken has been matched, so the first MaxErrors failures are
+	// the same ones that matching in order would find
+	matched := len(actual)
+	if int(next) < matched {
+		matched = int(next)
+	}
+	failed := 0
+	for i := 0; i < matched; i++ {
+		if results[i] != success {
+			failed++
+			if m.maxErrors > 0 && failed >= m.maxErrors {
+				matched = i + 1
+			}
+		}
+	}
+	for i := 0; i < matched && capturing; i++ {
+		m.mergeElementCaptures(elementMatchers[i])
+	}
+	return results[:matched]
+}
+
+// elementMatcher copies the matcher for matching one element on its own goroutine
+func (m *Matcher) elementMatcher(variables CapturedValues) *Matcher {
+	elementMatcher := *m
+	elementMatcher.workers = 0
+	elementMatcher.variables = variables
+	elementMatcher.path = append([]string(nil), m.path...)
+	if m.capturedValues != nil {
+		elementMatcher.capturedValues = make(CapturedValues)
+	}
+	if m.records != nil {
+		elementMatcher.records = &CaptureRecords{}
+	}
+	return &elementMatcher
+}
+
+func (m *Matcher) mergeElementCaptures(elementMatcher *Matcher) {
+	for key, values := range elementMatcher.capturedValues {
+		m.capturedValues[key] = append(m.capturedValues[key], values...)
+	}
+	if m.records != nil {
+		*m.records = append(*m.records, *elementMatcher.records...)
+	}
+}
+
+// arrayErrors turns the results for the elements of an array into its errors
+func (m *Matcher) arrayErrors(results []string, length int, fieldName string) []string {
+	var errorList []string
+	if m.aggregateErrors {
+		errorList = m.aggregatedErrors(results, length, fieldName)
+	} else {
+		for _, result := range results {
+			if result != success {
+				errorList = append(errorList, result)
+			}
+		}
+	}
+	if len(results) < length {
+		errorList = append(errorList, fmt.Sprintf("Stopped matching %v after %d errors", fieldName, m.maxErrors))
+	}
+	return errorList
+}
+
+type errorGroup struct {
+	line     string // The error as it was found in the first element
+	elements int
+	first    int
+	last     int
+}
+
+// aggregatedErrors groups the lines of the errors of each element, with any full paths to the element
+// written as 'name[*]' so they are the same for every element
+func (m *Matcher) aggregatedErrors(results []string, length int, fieldName string) []string {
+	groups := make(map[string]*errorGroup)
+	var order []string
+	for i, result := range results {
+		if result == success {
+			continue
+		}
+		for _, line := range strings.Split(result, "\n") {
+			key := line
+			if m.fullPaths {
+				key = strings.Replace(line, fmt.Sprintf("%v[%d]", fieldName, i), fieldName+"[*]", -1)
+			}
+			group, ok := groups[key]
+			if !ok {
+				group = &errorGroup{line: line, first: i, last: -1}
+				groups[key] = group
+				order = append(order, key)
+			}
+			if group.last != i {
+				group.elements++
+				group.last = i
+			}
+		}
+	}
+
+	errorList := make([]string, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+		if group.elements == 1 {
+			errorList = append(errorList, group.line)
+		} else {
+			errorList = append(errorList, fmt.Sprintf("%v (in %d of %d elements, first at [%d])", key, group.elements, length, group.first))
+		}
+	}
+	return errorList
+}
@@ -0,0 +1,128 @@
+package matcha
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedPricedItems struct {
+	Items []struct {
+		ID    string  `json:"id" capture:"id"`
+		Price float64 `json:"price"`
+	} `json:"items"`
+}
+
+// pricedItems makes a document with n items, where every item at a multiple of badEvery has a string price
+func pricedItems(n int, badEvery int) []byte {
+	var document bytes.Buffer
+	document.WriteString(`{"items": [`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			document.WriteString(",")
+		}
+		if badEvery > 0 && i%badEvery == 0 {
+			fmt.Fprintf(&document, `{"id": "i%d", "price": "free"}`, i)
+		} else {
+			fmt.Fprintf(&document, `{"id": "i%d", "price": %d}`, i, i)
+		}
+	}
+	document.WriteString("]}")
+	return document.Bytes()
+}
+
+func TestParallel(t *testing.T) {
+
+	Convey("Given a large array", t, func() {
+
+		document := pricedItems(2000, 7)
+
+		Convey("When it is matched in parallel", func() {
+
+			sequentialValues := make(CapturedValues)
+			var sequentialRecords CaptureRecords
+			sequential := ShouldMatchExpectedJSONResponse(document, expectedPricedItems{}, sequentialValues, RecordCaptures(&sequentialRecords))
+			parallelValues := make(CapturedValues)
+			var parallelRecords CaptureRecords
+			parallel := ShouldMatchExpectedJSONResponse(document, expectedPricedItems{}, parallelValues, RecordCaptures(&parallelRecords), Parallel(8))
+
+			Convey("It should give the same result as matching in order", func() {
+				So(parallel, ShouldNotEqual, "")
+				So(parallel, ShouldEqual, sequential)
+			})
+
+			Convey("It should capture values in the order of the elements", func() {
+				So(parallelValues["id"], ShouldHaveLength, 2000)
+				So(parallelValues, ShouldResemble, sequentialValues)
+				So(parallelRecords, ShouldResemble, sequentialRecords)
+				So(parallelRecords[1999].Path, ShouldEqual, "items[1999].id")
+			})
+
+		})
+
+		Convey("When errors are limited", func() {
+
+			sequential := ShouldMatchExpectedJSONResponse(document, expectedPricedItems{}, nil, MaxErrors(3))
+			parallel := ShouldMatchExpectedJSONResponse(document, expectedPricedItems{}, nil, MaxErrors(3), Parallel(4))
+
+			Convey("It should stop after that many elements have failed", func() {
+				So(sequential, ShouldStartWith, "Expected 'price' to be: 'float64' (but was: 'string')!\n"+
+					"Expected 'price' to be: 'float64' (but was: 'string')!\n"+
+					"Expected 'price' to be: 'float64' (but was: 'string')!\n"+
+					"Stopped matching items after 3 errors")
+			})
+
+			Convey("It should find the same errors in parallel", func() {
+				So(parallel, ShouldEqual, sequential)
+			})
+
+			Convey("It should only capture values from the elements it matched", func() {
+				capturedValues := make(CapturedValues)
+				ShouldMatchExpectedJSONResponse(document, expectedPricedItems{}, capturedValues, MaxErrors(3), Parallel(4))
+				So(capturedValues["id"], ShouldHaveLength, 15)
+				So(capturedValues["id"][14], ShouldEqual, "i14")
+			})
+
+		})
+
+		Convey("When errors are aggregated", func() {
+
+			failure := ShouldMatchExpectedJSONResponse(document, expectedPricedItems{}, nil, AggregateErrors(), Parallel(4))
+
+			Convey("It should report each different error once", func() {
+				So(failure, ShouldEqual, "Expected 'price' to be: 'float64' (but was: 'string')! (in 286 of 2000 elements, first at [0])")
+			})
+
+		})
+
+		Convey("When every element matches", func() {
+
+			Convey("It should succeed", func() {
+				So(ShouldMatchExpectedJSONResponse(pricedItems(500, 0), expectedPricedItems{}, nil, Parallel(4), MaxErrors(1), AggregateErrors()), ShouldEqual, "")
+			})
+
+		})
+
+	})
+
+	Convey("Given errors named by their full path", t, func() {
+
+		matcher := Matcher{format: "json", fullPaths: true, aggregateErrors: true}
+		document := []interface{}{
+			map[string]interface{}{"id": "a", "price": "free"},
+			map[string]interface{}{"id": "b", "price": 1.0},
+			map[string]interface{}{"id": 3.0, "price": "free"},
+		}
+		failure := matcher.shouldMatchExpectedField(document, reflect.TypeOf(expectedPricedItems{}.Items), "data.items")
+
+		Convey("It should aggregate them with the index replaced", func() {
+			So(failure, ShouldEqual, "Expected 'data.items[*].price' to be: 'float64' (but was: 'string')! (in 2 of 3 elements, first at [0])\n"+
+				"Expected 'data.items[2].id' to be: 'string' (but was: 'float64')!")
+		})
+
+	})
+
+}
@@ -0,0 +1,104 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+)
+
+// binaryReader reads from a binary document, checking that it doesn't run past the end
+type binaryReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *binaryReader) next(n uint64) ([]byte, error) {
+	if n > uint64(len(r.data)-r.offset) {
+		return nil, fmt.Errorf("unexpected end of data at byte %d", r.offset)
+	}
+	value := r.data[r.offset : r.offset+int(n)]
+	r.offset += int(n)
+	return value, nil
+}
+
+func (r *binaryReader) uint(size int) (uint64, error) {
+	value, err := r.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(value[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(value)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(value)), nil
+	}
+	return binary.BigEndian.Uint64(value), nil
+}
+
+// checkCount stops a corrupt length from allocating more elements than there are bytes left
+func (r *binaryReader) checkCount(count uint64) error {
+	if count > uint64(len(r.data)-r.offset) {
+		return fmt.Errorf("length %d at byte %d is longer than the data", count, r.offset)
+	}
+	return nil
+}
+
+// unsignedValue keeps integers as an int64 unless they are too big for one
+func unsignedValue(value uint64) interface{} {
+	if value > 1<<63-1 {
+		return value
+	}
+	return int64(value)
+}
+
+// mapKey turns a map key that isn't a string into one, so that binary maps fit in the generic tree
+func mapKey(key interface{}) string {
+	switch value := key.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	}
+	return fmt.Sprintf("%v", key)
+}
+
+// shouldMatchBinaryDocument matches a decoded binary document, showing it as JSON if it doesn't match
+// because the document itself can't be read
+func shouldMatchBinaryDocument(format string, name string, actual interface{}, expectedList []interface{}) string {
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+
+	matcher := Matcher{format: format, capturedValues: capturedValues, store: store}
+	if equal := matcher.applyOptions(expectedList[2:]); equal != success {
+		return equal
+	}
+	if equal := matcher.capturePaths(actual); equal != success {
+		return equal
+	}
+	expectedType := expectedTypeOf(expectedList[0])
+	result := matcher.shouldMatchExpectedField(actual, expectedType, "Result")
+	if result != success {
+		if differences := matcher.describeDifferences(actual, expectedType); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
+		result = fmt.Sprintf("%v\n%v data as JSON:\n%v", result, name, indentedJSON(actual))
+	}
+	return result
+}
+
+func indentedJSON(value interface{}) string {
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(bytes.TrimSpace(encoded.Bytes()))
+}
@@ -0,0 +1,317 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CaptureRecord is a captured value with where it was found
+type CaptureRecord struct {
+	Key     string
+	Value   interface{}
+	Path    string                 // e.g. 'results[2].date'
+	Indices []int                  // The index in each array the value is in, outermost first, e.g. [2]
+	Parent  map[string]interface{} // The object the value is a field of, or nil when streaming
+}
+
+// CaptureRecords are captures in the order they were made
+type CaptureRecords []CaptureRecord
+
+// RecordCaptures records every value captured by a 'capture' tag along with where it was found, as well
+// as adding it to the captured values
+func RecordCaptures(records *CaptureRecords) Option {
+	return func(m *Matcher) {
+		m.records = records
+	}
+}
+
+// ByKey returns the records of values captured under a key
+func (r CaptureRecords) ByKey(key string) CaptureRecords {
+	var records CaptureRecords
+	for _, record := range r {
+		if record.Key == key {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// GroupByParent groups captured values by the object they were found in, such as each element of an
+// array, in the order the objects were found. Each group maps capture keys to values, so values from
+// the same element stay together even when some elements are missing a field.
+func (r CaptureRecords) GroupByParent() []map[string]interface{} {
+	var groups []map[string]interface{}
+	groupIndexes := make(map[string]int)
+	for _, record := range r {
+		parentPath := record.Path[:strings.LastIndex(record.Path, ".")+1]
+		i, ok := groupIndexes[parentPath]
+		if !ok {
+			i = len(groups)
+			groupIndexes[parentPath] = i
+			groups = append(groups, make(map[string]interface{}))
+		}
+		groups[i][record.Key] = record.Value
+	}
+	return groups
+}
+
+// captureRecord describes a value being captured at the current path
+func (m *Matcher) captureRecord(key string, value interface{}, parent map[string]interface{}) CaptureRecord {
+	record := CaptureRecord{
+		Key:     key,
+		Value:   value,
+		Path:    strings.TrimPrefix(strings.Join(m.path, ""), "."),
+		Indices: []int{},
+		Parent:  parent,
+	}
+	for _, segment := range m.path {
+		if strings.HasPrefix(segment, "[") {
+			index, _ := strconv.Atoi(strings.Trim(segment, "[]"))
+			record.Indices = append(record.Indices, index)
+		}
+	}
+	return record
+}
+
+// All returns every value captured under a key
+func (c CapturedValues) All(key string) ([]interface{}, error) {
+	values := c[key]
+	if len(values) == 0 {
+		return nil, fmt.Errorf("No values captured for '%v'", key)
+	}
+	return values, nil
+}
+
+// Len returns how many values were captured under a key. It is an error if nothing ever tried to
+// capture under the key, which usually means the key is misspelt.
+func (c CapturedValues) Len(key string) (int, error) {
+	values, ok := c[key]
+	if !ok {
+		return 0, fmt.Errorf("No values captured for '%v'", key)
+	}
+	return len(values), nil
+}
+
+// First returns the first value captured under a key
+func (c CapturedValues) First(key string) (interface{}, error) {
+	values, err := c.All(key)
+	if err != nil {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// Float returns the first value captured under a key as a number
+func (c CapturedValues) Float(key string) (float64, error) {
+	value, err := c.First(key)
+	if err != nil {
+		return 0, err
+	}
+	number, ok := numberValue(value)
+	if !ok {
+		return 0, fmt.Errorf("Captured value '%v' is a %v, not a number", key, kindOf(value))
+	}
+	return number, nil
+}
+
+// Int returns the first value captured under a key as a whole number
+func (c CapturedValues) Int(key string) (int, error) {
+	number, err := c.Float(key)
+	if err != nil {
+		return 0, err
+	}
+	if number != math.Trunc(number) || number > math.MaxInt64 || number < math.MinInt64 {
+		return 0, fmt.Errorf("Captured value '%v' is %v, which isn't a whole number", key, number)
+	}
+	return int(number), nil
+}
+
+// String returns the first value captured under a key as a string
+func (c CapturedValues) String(key string) (string, error) {
+	value, err := c.First(key)
+	if err != nil {
+		return "", err
+	}
+	text, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("Captured value '%v' is a %v, not a string", key, kindOf(value))
+	}
+	return text, nil
+}
+
+// Time returns the first value captured under a key as a time.Time. Strings are parsed as RFC 3339.
+func (c CapturedValues) Time(key string) (time.Time, error) {
+	value, err := c.First(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	datetime, err := decodeTime(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Captured value '%v' %v", key, err)
+	}
+	return datetime, nil
+}
+
+// DecodeInto converts the values captured under a key into a Go value. If destination points to a
+// slice every value is decoded, otherwise only the first is. Objects are decoded into structs with
+// the same field names as JSON assertions use, from 'json' tags or the snake case of the field name.
+func (c CapturedValues) DecodeInto(key string, destination interface{}) error {
+	return c.DecodeFormatInto(key, "json", destination)
+}
+
+// DecodeFormatInto is DecodeInto for values captured from a document in another format, e.g. "xml"
+// or "yaml", so that struct fields are named from that format's tags as its assertions name them
+func (c CapturedValues) DecodeFormatInto(key string, format string, destination interface{}) error {
+	target := reflect.ValueOf(destination)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("Expected a non-nil pointer to decode '%v' into (but got: %T)!", key, destination)
+	}
+	target = target.Elem()
+
+	values, err := c.All(key)
+	if err != nil {
+		return err
+	}
+	matcher := Matcher{format: format}
+	if target.Kind() == reflect.Slice && target.Type().Elem().Kind() != reflect.Uint8 {
+		return matcher.decodeValue(values, target, key)
+	}
+	return matcher.decodeValue(values[0], target, key)
+}
+
+func decodeTime(value interface{}) (time.Time, error) {
+	switch datetime := value.(type) {
+	case time.Time:
+		return datetime, nil
+	case string:
+		parsed, err := time.Parse(time.RFC3339, datetime)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("'%v' isn't an RFC 3339 datetime", datetime)
+		}
+		return parsed, nil
+	}
+	return time.Time{}, fmt.Errorf("is a %v, not a datetime", kindOf(value))
+}
+
+// decodeValue sets target to a value from a document, converting it to the target's type
+func (m *Matcher) decodeValue(value interface{}, target reflect.Value, path string) error {
+	mismatch := func() error {
+		return fmt.Errorf("Can't decode %v into %v at '%v'", kindOf(value), target.Type(), path)
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr:
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+		element := reflect.New(target.Type().Elem())
+		if err := m.decodeValue(value, element.Elem(), path); err != nil {
+			return err
+		}
+		target.Set(element)
+	case reflect.Interface:
+		if value == nil {
+			target.Set(reflect.Zero(target.Type()))
+		} else if !reflect.TypeOf(value).AssignableTo(target.Type()) {
+			return mismatch()
+		} else {
+			target.Set(reflect.ValueOf(value))
+		}
+	case reflect.String:
+		text, ok := value.(string)
+		if !ok {
+			return mismatch()
+		}
+		target.SetString(text)
+	case reflect.Bool:
+		boolean, ok := value.(bool)
+		if !ok {
+			return mismatch()
+		}
+		target.SetBool(boolean)
+	case reflect.Float32, reflect.Float64:
+		number, ok := numberValue(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetFloat(number)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		number, ok := numberValue(value)
+		if !ok || number != math.Trunc(number) || target.OverflowInt(int64(number)) {
+			return mismatch()
+		}
+		target.SetInt(int64(number))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		number, ok := numberValue(value)
+		if !ok || number < 0 || number != math.Trunc(number) || target.OverflowUint(uint64(number)) {
+			return mismatch()
+		}
+		target.SetUint(uint64(number))
+	case reflect.Slice:
+		if bytes, ok := value.([]byte); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+			target.SetBytes(append([]byte{}, bytes...))
+			return nil
+		}
+		elements, ok := value.([]interface{})
+		if !ok {
+			return mismatch()
+		}
+		slice := reflect.MakeSlice(target.Type(), len(elements), len(elements))
+		for i, element := range elements {
+			if err := m.decodeValue(element, slice.Index(i), fmt.Sprintf("%v[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		target.Set(slice)
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok || target.Type().Key().Kind() != reflect.String {
+			return mismatch()
+		}
+		decoded := reflect.MakeMapWithSize(target.Type(), len(object))
+		for key, element := range object {
+			elementValue := reflect.New(target.Type().Elem()).Elem()
+			if err := m.decodeValue(element, elementValue, path+"."+key); err != nil {
+				return err
+			}
+			decoded.SetMapIndex(reflect.ValueOf(key).Convert(target.Type().Key()), elementValue)
+		}
+		target.Set(decoded)
+	case reflect.Struct:
+		if target.Type() == timeType {
+			datetime, err := decodeTime(value)
+			if err != nil {
+				return mismatch()
+			}
+			target.Set(reflect.ValueOf(datetime))
+			return nil
+		}
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch()
+		}
+		for i := 0; i < target.NumField(); i++ {
+			field := target.Type().Field(i)
+			if field.PkgPath != "" {
+				// Unexported fields can't be set
+				continue
+			}
+			fieldName := m.getFieldName(field)
+			fieldValue, ok := object[fieldName]
+			if !ok {
+				continue
+			}
+			if err := m.decodeValue(fieldValue, target.Field(i), path+"."+fieldName); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("'%v' is of a type I don't know how to decode into", target.Type())
+	}
+	return nil
+}
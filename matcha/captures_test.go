@@ -0,0 +1,207 @@
+package matcha
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type capturedBooking struct {
+	ID      string    `json:"id"`
+	Seats   int       `json:"seats"`
+	Price   *float64  `json:"price"`
+	Created time.Time `json:"created"`
+	Tags    []string  `json:"tags"`
+	Venue   struct {
+		Name string
+	} `json:"venue"`
+}
+
+func TestCapturedValueAccessors(t *testing.T) {
+
+	Convey("Given some captured values", t, func() {
+
+		created := time.Date(2017, 3, 22, 10, 0, 0, 0, time.UTC)
+		capturedValues := CapturedValues{
+			"count":   {2.0, 3.0},
+			"price":   {2.5},
+			"name":    {"Stalls"},
+			"created": {"2017-03-22T10:00:00Z"},
+			"updated": {created},
+			"empty":   {},
+		}
+
+		Convey("When they are read with the type we expect", func() {
+
+			Convey("It should return them without errors", func() {
+				first, err := capturedValues.First("count")
+				So(first, ShouldEqual, 2.0)
+				So(err, ShouldBeNil)
+				all, err := capturedValues.All("count")
+				So(all, ShouldResemble, []interface{}{2.0, 3.0})
+				So(err, ShouldBeNil)
+				length, err := capturedValues.Len("empty")
+				So(length, ShouldEqual, 0)
+				So(err, ShouldBeNil)
+				number, err := capturedValues.Int("count")
+				So(number, ShouldEqual, 2)
+				So(err, ShouldBeNil)
+				price, err := capturedValues.Float("price")
+				So(price, ShouldEqual, 2.5)
+				So(err, ShouldBeNil)
+				name, err := capturedValues.String("name")
+				So(name, ShouldEqual, "Stalls")
+				So(err, ShouldBeNil)
+				datetime, err := capturedValues.Time("created")
+				So(datetime, ShouldEqual, created)
+				So(err, ShouldBeNil)
+				datetime, err = capturedValues.Time("updated")
+				So(datetime, ShouldEqual, created)
+				So(err, ShouldBeNil)
+			})
+
+		})
+
+		Convey("When they are read with a different type", func() {
+
+			Convey("It should return errors instead of panicking", func() {
+				_, err := capturedValues.Int("price")
+				So(err.Error(), ShouldEqual, "Captured value 'price' is 2.5, which isn't a whole number")
+				_, err = capturedValues.Float("name")
+				So(err.Error(), ShouldEqual, "Captured value 'name' is a string, not a number")
+				_, err = capturedValues.String("count")
+				So(err.Error(), ShouldEqual, "Captured value 'count' is a float64, not a string")
+				_, err = capturedValues.Time("name")
+				So(err.Error(), ShouldEqual, "Captured value 'name' 'Stalls' isn't an RFC 3339 datetime")
+				_, err = capturedValues.First("empty")
+				So(err.Error(), ShouldEqual, "No values captured for 'empty'")
+				_, err = capturedValues.Len("missing")
+				So(err.Error(), ShouldEqual, "No values captured for 'missing'")
+			})
+
+		})
+
+	})
+
+}
+
+func TestDecodeInto(t *testing.T) {
+
+	Convey("Given captured objects", t, func() {
+
+		capturedValues := CapturedValues{
+			"bookings": {
+				map[string]interface{}{"id": "b-1", "seats": 2.0, "price": 10.5, "created": "2017-03-22T10:00:00Z",
+					"tags": []interface{}{"vip"}, "venue": map[string]interface{}{"name": "Palladium"}},
+				map[string]interface{}{"id": "b-2", "seats": 1.0, "price": nil},
+			},
+			"broken": {map[string]interface{}{"id": "b-3", "tags": []interface{}{"vip", 7.0}}},
+		}
+
+		Convey("When they are decoded into a struct", func() {
+
+			var booking capturedBooking
+			err := capturedValues.DecodeInto("bookings", &booking)
+
+			Convey("It should decode the first value with the same field names as matching", func() {
+				So(err, ShouldBeNil)
+				So(booking.ID, ShouldEqual, "b-1")
+				So(booking.Seats, ShouldEqual, 2)
+				So(*booking.Price, ShouldEqual, 10.5)
+				So(booking.Created, ShouldEqual, time.Date(2017, 3, 22, 10, 0, 0, 0, time.UTC))
+				So(booking.Tags, ShouldResemble, []string{"vip"})
+				So(booking.Venue.Name, ShouldEqual, "Palladium")
+			})
+
+		})
+
+		Convey("When they are decoded into a slice", func() {
+
+			var bookings []capturedBooking
+			err := capturedValues.DecodeInto("bookings", &bookings)
+
+			Convey("It should decode every value", func() {
+				So(err, ShouldBeNil)
+				So(len(bookings), ShouldEqual, 2)
+				So(bookings[1].ID, ShouldEqual, "b-2")
+				So(bookings[1].Price, ShouldBeNil)
+			})
+
+		})
+
+		Convey("When they were captured from another format", func() {
+
+			captured := CapturedValues{"venues": {map[string]interface{}{"Venue-Name": "Palladium", "name": "wrong"}}}
+			var venue struct {
+				Name string `json:"name" xml:"Venue-Name" yaml:"Venue-Name"`
+			}
+
+			Convey("It should use that format's field names", func() {
+				So(captured.DecodeFormatInto("venues", "xml", &venue), ShouldBeNil)
+				So(venue.Name, ShouldEqual, "Palladium")
+				So(captured.DecodeInto("venues", &venue), ShouldBeNil)
+				So(venue.Name, ShouldEqual, "wrong")
+			})
+
+		})
+
+		Convey("When they don't fit the type", func() {
+
+			Convey("It should return an error", func() {
+				var booking capturedBooking
+				err := capturedValues.DecodeInto("broken", &booking)
+				So(err.Error(), ShouldEqual, "Can't decode float64 into string at 'broken.tags[1]'")
+				err = capturedValues.DecodeInto("bookings", booking)
+				So(err.Error(), ShouldEqual, "Expected a non-nil pointer to decode 'bookings' into (but got: matcha.capturedBooking)!")
+			})
+
+		})
+
+	})
+
+}
+
+type expectedCaptureContext struct {
+	Results []struct {
+		Name string `json:"name" capture:"name"`
+		Date string `json:"date" capture:"date" pattern:".*"`
+	} `json:"results"`
+}
+
+func TestRecordCaptures(t *testing.T) {
+
+	Convey("Given an array of objects that capture values", t, func() {
+
+		var expected expectedCaptureContext
+		document := []byte(`{"results": [{"name": "a", "date": "2017-03-22"}, {"name": "b"}, {"name": "c", "date": "2017-03-24"}]}`)
+
+		Convey("When captures are recorded", func() {
+
+			capturedValues := make(CapturedValues)
+			var records CaptureRecords
+			failure := ShouldMatchExpectedJSONResponse(document, expected, capturedValues, RecordCaptures(&records))
+
+			Convey("It should record each value with its path, indices and parent", func() {
+				So(failure, ShouldStartWith, "No field 'date' found in response")
+				So(capturedValues["date"], ShouldResemble, []interface{}{"2017-03-22", "2017-03-24"})
+				dates := records.ByKey("date")
+				So(len(dates), ShouldEqual, 2)
+				So(dates[1].Path, ShouldEqual, "results[2].date")
+				So(dates[1].Indices, ShouldResemble, []int{2})
+				So(dates[1].Parent["name"], ShouldEqual, "c")
+			})
+
+			Convey("It should group values by the element they came from", func() {
+				So(records.GroupByParent(), ShouldResemble, []map[string]interface{}{
+					{"name": "a", "date": "2017-03-22"},
+					{"name": "b"},
+					{"name": "c", "date": "2017-03-24"},
+				})
+			})
+
+		})
+
+	})
+
+}
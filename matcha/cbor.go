@@ -0,0 +1,273 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// cborBreak ends a CBOR item of indefinite length
+const cborBreak = 0xff
+
+// ShouldMatchExpectedCBOR matches a CBOR document. Integers are decoded as an int64 (or a uint64 if
+// they are too big), byte strings as a []byte and tagged datetimes as a time.Time. Field names come
+// from 'cbor' tags, or from 'json' tags so that the same structs can be used for both.
+func ShouldMatchExpectedCBOR(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) < 2 {
+		return fmt.Sprintf("ShouldMatchExpectedCBOR expects three arguments: the actual CBOR as a byte slice, the expected format as a Struct, and a map to hold captured values, optionally followed by Options")
+	}
+
+	actualCBOR, ok := actual.([]byte)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be a byte slice")
+	}
+	actualResponse, err := decodeCBOR(actualCBOR)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to decode CBOR: %v", err)
+	}
+	return shouldMatchBinaryDocument("cbor", "CBOR", actualResponse, expectedList)
+}
+
+func decodeCBOR(data []byte) (interface{}, error) {
+	reader := &binaryReader{data: data}
+	value, err := reader.cborValue()
+	if err != nil {
+		return nil, err
+	}
+	if reader.offset != len(data) {
+		return nil, fmt.Errorf("unexpected data after the end of the document at byte %d", reader.offset)
+	}
+	return value, nil
+}
+
+// cborArgument reads the number that follows the initial byte of an item. Indefinite lengths are
+// reported separately as they have no number.
+func (r *binaryReader) cborArgument(info byte) (uint64, bool, error) {
+	switch {
+	case info < 24:
+		return uint64(info), false, nil
+	case info <= 27:
+		value, err := r.uint(1 << (info - 24))
+		return value, false, err
+	case info == 31:
+		return 0, true, nil
+	}
+	return 0, false, fmt.Errorf("invalid additional information %d at byte %d", info, r.offset-1)
+}
+
+// atBreak checks for, and reads past, the end of an item of indefinite length
+func (r *binaryReader) atBreak() (bool, error) {
+	if r.offset >= len(r.data) {
+		return false, fmt.Errorf("unexpected end of data at byte %d", r.offset)
+	}
+	if r.data[r.offset] == cborBreak {
+		r.offset++
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *binaryReader) cborValue() (interface{}, error) {
+	start := r.offset
+	initial, err := r.uint(1)
+	if err != nil {
+		return nil, err
+	}
+	major, info := byte(initial>>5), byte(initial&0x1f)
+	if major == 7 {
+		return r.cborSimple(info, start)
+	}
+	argument, indefinite, err := r.cborArgument(info)
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major < 2 || major > 5) {
+		return nil, fmt.Errorf("indefinite length not allowed at byte %d", start)
+	}
+
+	switch major {
+	case 0:
+		return unsignedValue(argument), nil
+	case 1:
+		if argument > 1<<63-1 {
+			return nil, fmt.Errorf("negative integer at byte %d is too big", start)
+		}
+		return -1 - int64(argument), nil
+	case 2, 3:
+		value, err := r.cborString(major, argument, indefinite)
+		if err != nil || major == 2 {
+			return value, err
+		}
+		return string(value), nil
+	case 4:
+		return r.cborArray(argument, indefinite)
+	case 5:
+		return r.cborMap(argument, indefinite)
+	}
+	return r.cborTagged(argument)
+}
+
+// cborString reads a byte or text string, joining the chunks of one with an indefinite length
+func (r *binaryReader) cborString(major byte, length uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		value, err := r.next(length)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, value...), nil
+	}
+
+	value := []byte{}
+	for {
+		done, err := r.atBreak()
+		if err != nil {
+			return nil, err
+		}
+		if done {
+			return value, nil
+		}
+		start := r.offset
+		initial, _ := r.uint(1)
+		chunkLength, chunkIndefinite, err := r.cborArgument(byte(initial & 0x1f))
+		if err != nil {
+			return nil, err
+		}
+		if byte(initial>>5) != major || chunkIndefinite {
+			return nil, fmt.Errorf("invalid chunk of string at byte %d", start)
+		}
+		chunk, err := r.next(chunkLength)
+		if err != nil {
+			return nil, err
+		}
+		value = append(value, chunk...)
+	}
+}
+
+func (r *binaryReader) cborArray(count uint64, indefinite bool) (interface{}, error) {
+	if err := r.checkCount(count); err != nil {
+		return nil, err
+	}
+	array := make([]interface{}, 0, count)
+	for i := uint64(0); indefinite || i < count; i++ {
+		if indefinite {
+			done, err := r.atBreak()
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+		value, err := r.cborValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+	}
+	return array, nil
+}
+
+func (r *binaryReader) cborMap(count uint64, indefinite bool) (interface{}, error) {
+	if err := r.checkCount(count); err != nil {
+		return nil, err
+	}
+	object := make(map[string]interface{}, count)
+	for i := uint64(0); indefinite || i < count; i++ {
+		if indefinite {
+			done, err := r.atBreak()
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				break
+			}
+		}
+		key, err := r.cborValue()
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.cborValue()
+		if err != nil {
+			return nil, err
+		}
+		object[mapKey(key)] = value
+	}
+	return object, nil
+}
+
+// cborTagged decodes the datetime tags. Other tags are ignored and their content is used as it is.
+func (r *binaryReader) cborTagged(tag uint64) (interface{}, error) {
+	start := r.offset
+	content, err := r.cborValue()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case 0:
+		text, ok := content.(string)
+		if !ok {
+			return nil, fmt.Errorf("datetime at byte %d is not a string", start)
+		}
+		datetime, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid datetime at byte %d: %v", start, err)
+		}
+		return datetime.UTC(), nil
+	case 1:
+		seconds, ok := numberValue(content)
+		if !ok {
+			return nil, fmt.Errorf("epoch datetime at byte %d is not a number", start)
+		}
+		whole, fraction := math.Modf(seconds)
+		return time.Unix(int64(whole), int64(fraction*1e9)).UTC(), nil
+	}
+	return content, nil
+}
+
+func (r *binaryReader) cborSimple(info byte, start int) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		bits, err := r.uint(2)
+		return float16Value(uint16(bits)), err
+	case 26:
+		bits, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 27:
+		bits, err := r.uint(8)
+		return math.Float64frombits(bits), err
+	case 31:
+		return nil, fmt.Errorf("unexpected break at byte %d", start)
+	}
+	return nil, fmt.Errorf("unsupported simple value %d at byte %d", info, start)
+}
+
+// float16Value converts a half-precision float, which Go has no type for
+func float16Value(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
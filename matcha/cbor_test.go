@@ -0,0 +1,108 @@
+package matcha
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// cborBooking is a CBOR document with ints, floats, byte strings, a tagged datetime and integer map
+// keys. The ratings and ticket have an indefinite length.
+var cborBooking = []byte{
+	0xa7,
+	0x62, 'i', 'd', 0x63, 'b', '-', '1',
+	0x65, 's', 'e', 'a', 't', 's', 0x02,
+	0x65, 'p', 'r', 'i', 'c', 'e', 0xf9, 0x4a, 0x40,
+	0x66, 't', 'i', 'c', 'k', 'e', 't', 0x5f, 0x41, 0xde, 0x41, 0xad, 0xff,
+	0x67, 'c', 'r', 'e', 'a', 't', 'e', 'd', 0xc1, 0x1a, 0x5a, 0x4b, 0x1c, 0x40,
+	0x67, 'r', 'a', 't', 'i', 'n', 'g', 's', 0x9f, 0x05, 0x21, 0xfa, 0x3f, 0xc0, 0x00, 0x00, 0xff,
+	0x69, 'b', 'y', '_', 'n', 'u', 'm', 'b', 'e', 'r', 0xa1, 0x01, 0x63, 'o', 'n', 'e',
+}
+
+func TestCBORDecoding(t *testing.T) {
+
+	Convey("Given a CBOR document", t, func() {
+
+		Convey("When it is decoded", func() {
+
+			decoded, err := decodeCBOR(cborBooking)
+
+			Convey("It should keep integers, byte strings and datetimes apart", func() {
+				So(err, ShouldBeNil)
+				So(decoded, ShouldResemble, map[string]interface{}{
+					"id":        "b-1",
+					"seats":     int64(2),
+					"price":     12.5,
+					"ticket":    []byte{0xde, 0xad},
+					"created":   time.Date(2018, 1, 2, 5, 44, 32, 0, time.UTC),
+					"ratings":   []interface{}{int64(5), int64(-2), 1.5},
+					"by_number": map[string]interface{}{"1": "one"},
+				})
+			})
+
+		})
+
+		Convey("When a datetime string is tagged", func() {
+
+			decoded, err := decodeCBOR(append([]byte{0xc0, 0x74}, "2018-01-02T05:43:28Z"...))
+
+			Convey("It should be decoded as a time.Time", func() {
+				So(err, ShouldBeNil)
+				So(decoded, ShouldResemble, time.Date(2018, 1, 2, 5, 43, 28, 0, time.UTC))
+			})
+
+		})
+
+		Convey("When a break is out of place", func() {
+
+			_, err := decodeCBOR([]byte{0x82, 0x01, 0xff})
+
+			Convey("It should return an error", func() {
+				So(err.Error(), ShouldEqual, "unexpected break at byte 2")
+			})
+
+		})
+
+	})
+
+}
+
+func TestCBORMatching(t *testing.T) {
+
+	Convey("Given an expected format", t, func() {
+
+		var expected expectedBinaryBooking
+
+		Convey("When the document matches", func() {
+
+			Convey("It should return success", func() {
+				success := ShouldMatchExpectedCBOR(cborBooking, expected, nil)
+				So(success, ShouldEqual, "")
+			})
+
+			Convey("It should apply Options", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedCBOR(cborBooking, expected, capturedValues, Capture("$.by_number.1", "number"))
+				So(success, ShouldEqual, "")
+				So(capturedValues["number"], ShouldResemble, []interface{}{"one"})
+			})
+
+		})
+
+		Convey("When the document doesn't match", func() {
+
+			// {"id": h'01', "seats": 0}
+			document := []byte{0xa2, 0x62, 'i', 'd', 0x41, 0x01, 0x65, 's', 'e', 'a', 't', 's', 0x00}
+
+			Convey("It should show the document as JSON", func() {
+				failure := ShouldMatchExpectedCBOR(document, expected, nil)
+				So(failure, ShouldStartWith, TypeErrorString("id", "string", "[]uint8")+"\nSeats: 0 is less than the minimum: 1\n")
+				So(failure, ShouldEndWith, "CBOR data as JSON:\n{\n  \"id\": \"AQ==\",\n  \"seats\": 0\n}")
+			})
+
+		})
+
+	})
+
+}
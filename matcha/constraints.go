@@ -0,0 +1,68 @@
+package matcha
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// shouldMatchConstraints checks the 'min', 'max' and 'enum' tags of a field. For numbers 'min' and
+// 'max' limit the value itself, and for strings and arrays they limit the length. 'enum' lists the
+// allowed values separated by '|'.
+func (m *Matcher) shouldMatchConstraints(actual interface{}, expectedField reflect.StructField) string {
+
+	var errorList []string
+	plan := compiledField(expectedField)
+	if plan.limitError != "" {
+		return plan.limitError
+	}
+	for _, limit := range plan.limits {
+		var measured float64
+		description := ""
+		switch actualValue := actual.(type) {
+		case float64, int64, uint64:
+			measured, _ = numberValue(actualValue)
+			description = fmt.Sprintf("%v", actualValue)
+		case string:
+			measured = float64(utf8.RuneCountInString(actualValue))
+			description = fmt.Sprintf("length %v", measured)
+		case []interface{}:
+			measured = float64(len(actualValue))
+			description = fmt.Sprintf("length %v", measured)
+		default:
+			return fmt.Sprintf("'%v' tag cannot be used on %v fields: %v", limit.constraint, kindOf(actual), expectedField.Name)
+		}
+
+		if limit.constraint == "min" && measured < limit.value {
+			errorList = append(errorList, fmt.Sprintf("%v: %v is less than the minimum: %v", expectedField.Name, description, limit.tag))
+		}
+		if limit.constraint == "max" && measured > limit.value {
+			errorList = append(errorList, fmt.Sprintf("%v: %v is more than the maximum: %v", expectedField.Name, description, limit.tag))
+		}
+	}
+
+	if plan.enum != nil {
+		actualString := fmt.Sprintf("%v", actual)
+		found := false
+		for _, value := range plan.enum {
+			if value == actualString {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errorList = append(errorList, fmt.Sprintf("%v: '%v' is not one of: %v", expectedField.Name, actualString, strings.Join(plan.enum, ", ")))
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// hasValueTags reports whether a field has tags that need its whole value, rather than just its type
+func hasValueTags(field reflect.StructField) bool {
+	return compiledField(field).valueTags
+}
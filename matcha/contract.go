@@ -0,0 +1,362 @@
+package matcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Contract is a set of interactions between a consumer and a provider that can be written to
+// a file by the consumer and verified by the provider
+type Contract struct {
+	Consumer     string        `json:"consumer"`
+	Provider     string        `json:"provider"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single request we expect the consumer to make and the response it expects back
+type Interaction struct {
+	Description string              `json:"description"`
+	Request     InteractionRequest  `json:"request"`
+	Response    InteractionResponse `json:"response"`
+}
+
+// InteractionRequest is the serialisable form of a RequestExpectation, along with an example
+// request the provider can replay
+type InteractionRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`              // Path template, e.g. '/bookings/{booking_id}'
+	Query   map[string]string `json:"query,omitempty"`   // Query parameters mapped to the pattern their value should match
+	Headers map[string]string `json:"headers,omitempty"` // Headers mapped to the pattern their value should match
+	Format  string            `json:"format,omitempty"`  // Should be 'json' or 'xml', defaults to 'json'
+	Schema  *Schema           `json:"schema,omitempty"`
+	Example Example           `json:"example"`
+}
+
+// InteractionResponse is the response the consumer expects, along with an example of it
+type InteractionResponse struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"` // Headers mapped to the pattern their value should match
+	Format  string            `json:"format,omitempty"`  // Should be 'json' or 'xml', defaults to 'json'
+	Schema  *Schema           `json:"schema,omitempty"`
+	Example Example           `json:"example"`
+}
+
+// Example is a concrete request or response payload
+type Example struct {
+	Path    string            `json:"path,omitempty"` // Only used for requests, may include a query string
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// Schema is a serialisable description of an expected struct
+type Schema struct {
+	Type    string    `json:"type"`            // One of 'string', 'number', 'integer', 'boolean', 'time', 'array' or 'object'
+	Name    string    `json:"name,omitempty"`  // Name of the field in the document, empty for the root and array items
+	Field   string    `json:"field,omitempty"` // Name of the field in the expected struct, used in error messages
+	Pattern string    `json:"pattern,omitempty"`
+	Min     string    `json:"min,omitempty"`    // The 'min' tag of the field
+	Max     string    `json:"max,omitempty"`    // The 'max' tag of the field
+	Enum    string    `json:"enum,omitempty"`   // The 'enum' tag of the field, e.g. 'open|closed'
+	Equals  string    `json:"equals,omitempty"` // The 'equals' tag of the field
+	In      string    `json:"in,omitempty"`     // The 'in' tag of the field
+	Fields  []*Schema `json:"fields,omitempty"` // Only used for objects
+	Items   *Schema   `json:"items,omitempty"`  // Only used for arrays
+}
+
+// schemaTags are the tags of an expected struct field that are kept in its schema, in the order they
+// are written back
+var schemaTags = []string{"pattern", "min", "max", "enum", "equals", "in"}
+
+func (s *Schema) tag(name string) *string {
+	switch name {
+	case "pattern":
+		return &s.Pattern
+	case "min":
+		return &s.Min
+	case "max":
+		return &s.Max
+	case "enum":
+		return &s.Enum
+	case "equals":
+		return &s.Equals
+	}
+	return &s.In
+}
+
+// SchemaOf describes an expected struct so it can be written to a contract. Field names are worked
+// out the same way as when matching documents in the given format.
+func SchemaOf(expected interface{}, format string) (*Schema, error) {
+	matcher := Matcher{format: format}
+	return matcher.schemaOf(expectedTypeOf(expected))
+}
+
+func (m *Matcher) schemaOf(expectedType reflect.Type) (*Schema, error) {
+	switch expectedType.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Slice:
+		items, err := m.schemaOf(expectedType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Struct:
+		if expectedType == timeType {
+			return &Schema{Type: "time"}, nil
+		}
+		schema := &Schema{Type: "object"}
+		for i := 0; i < expectedType.NumField(); i++ {
+			field := expectedType.Field(i)
+			fieldSchema, err := m.schemaOf(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			fieldSchema.Name = m.getFieldName(field)
+			fieldSchema.Field = field.Name
+			for _, name := range schemaTags {
+				*fieldSchema.tag(name) = field.Tag.Get(name)
+			}
+			schema.Fields = append(schema.Fields, fieldSchema)
+		}
+		return schema, nil
+	}
+	return nil, fmt.Errorf("'%v' is of a type I don't know how to handle", expectedType)
+}
+
+// Expected builds a value with the structure described by the schema, which can be passed to any of
+// the assertions in place of a hand-written struct
+func (s *Schema) Expected() interface{} {
+	return reflect.New(s.reflectType()).Elem().Interface()
+}
+
+func (s *Schema) reflectType() reflect.Type {
+	switch s.Type {
+	case "number":
+		return reflect.TypeOf(float64(0))
+	case "integer":
+		return reflect.TypeOf(0)
+	case "time":
+		return timeType
+	case "boolean":
+		return reflect.TypeOf(false)
+	case "array":
+		return reflect.SliceOf(s.Items.reflectType())
+	case "object":
+		fields := make([]reflect.StructField, len(s.Fields))
+		usedNames := make(map[string]bool)
+		for i, field := range s.Fields {
+			// Field names have to be exported, so the real name goes in the tags
+			tag := fmt.Sprintf(`json:%q xml:%q`, field.Name, field.Name)
+			for _, name := range schemaTags {
+				if value := *field.tag(name); value != "" {
+					tag += fmt.Sprintf(` %v:%q`, name, value)
+				}
+			}
+			fieldName := field.Field
+			if !isExportedIdentifier(fieldName) || usedNames[fieldName] {
+				fieldName = exportedFieldName(field.Name, i, usedNames)
+			}
+			usedNames[fieldName] = true
+			fields[i] = reflect.StructField{
+				Name: fieldName,
+				Type: field.reflectType(),
+				Tag:  reflect.StructTag(tag),
+			}
+		}
+		return reflect.StructOf(fields)
+	}
+	return reflect.TypeOf("")
+}
+
+// validate checks a schema read from a file describes a struct that can be built, naming where in the
+// contract it is if not
+func (s *Schema) validate(location string) error {
+	switch s.Type {
+	case "string", "number", "integer", "boolean", "time":
+	case "array":
+		if s.Items == nil {
+			return fmt.Errorf("Array schema has no items: %v", location)
+		}
+		return s.Items.validate(location + "[]")
+	case "object":
+		for _, field := range s.Fields {
+			if field == nil {
+				return fmt.Errorf("Object schema has an empty field: %v", location)
+			}
+			if err := field.validate(location + "." + field.Name); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Unknown schema type '%v': %v", s.Type, location)
+	}
+	return nil
+}
+
+// isExportedIdentifier checks a name read from a contract can be used as the name of a struct field
+func isExportedIdentifier(name string) bool {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != "" && unicode.IsUpper([]rune(name)[0])
+}
+
+// exportedFieldName makes a valid, unique, exported Go identifier that resembles the document name
+// so that error messages stay readable
+func exportedFieldName(name string, index int, usedNames map[string]bool) string {
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			runes[i] = '_'
+		}
+	}
+	if len(runes) > 0 && unicode.IsLetter(runes[0]) && unicode.IsUpper(unicode.ToUpper(runes[0])) {
+		runes[0] = unicode.ToUpper(runes[0])
+	} else {
+		runes = append([]rune("Field_"), runes...)
+	}
+	fieldName := string(runes)
+	if usedNames[fieldName] {
+		fieldName = fmt.Sprintf("%v_%d", fieldName, index)
+	}
+	usedNames[fieldName] = true
+	return fieldName
+}
+
+// Expectation converts the request back into a RequestExpectation, e.g. for use in a stub server
+func (r InteractionRequest) Expectation() RequestExpectation {
+	expectation := RequestExpectation{
+		Method:  r.Method,
+		Path:    r.Path,
+		Query:   r.Query,
+		Headers: r.Headers,
+		Format:  r.Format,
+	}
+	if r.Schema != nil {
+		expectation.Body = r.Schema.Expected()
+	}
+	return expectation
+}
+
+// WriteContract writes a contract to a file as JSON
+func WriteContract(path string, contract Contract) error {
+	data, err := json.MarshalIndent(contract, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// ReadContract reads a contract written by WriteContract
+func ReadContract(path string) (Contract, error) {
+	var contract Contract
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return contract, err
+	}
+	if err = json.Unmarshal(data, &contract); err != nil {
+		return contract, err
+	}
+	for _, interaction := range contract.Interactions {
+		if schema := interaction.Request.Schema; schema != nil {
+			if err := schema.validate(interaction.Description + " request"); err != nil {
+				return contract, err
+			}
+		}
+		if schema := interaction.Response.Schema; schema != nil {
+			if err := schema.validate(interaction.Description + " response"); err != nil {
+				return contract, err
+			}
+		}
+	}
+	return contract, nil
+}
+
+// VerifyContract replays the example request of every interaction against the handler, served by a
+// local httptest.Server, and checks each response matches what the consumer expects
+func VerifyContract(contract Contract, handler http.Handler) string {
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	var errorList []string
+	for _, interaction := range contract.Interactions {
+		if equal := verifyInteraction(server, interaction); equal != success {
+			errorList = append(errorList, fmt.Sprintf("Interaction '%v' failed:\n%v", interaction.Description, equal))
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+func verifyInteraction(server *httptest.Server, interaction Interaction) string {
+	example := interaction.Request.Example
+	request, err := http.NewRequest(interaction.Request.Method, server.URL+example.Path, strings.NewReader(example.Body))
+	if err != nil {
+		return fmt.Sprintf("Was not possible to build the example request: %v", err)
+	}
+	for name, value := range example.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := server.Client().Do(request)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to send the example request: %v", err)
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to read the response body: %v", err)
+	}
+
+	expected := interaction.Response
+	var errorList []string
+	if expected.Status != 0 && expected.Status != response.StatusCode {
+		errorList = append(errorList, fmt.Sprintf("Expected response status to be: '%v' (but was: '%v')!", expected.Status, response.StatusCode))
+	}
+	for _, name := range sortedKeys(expected.Headers) {
+		if _, ok := response.Header[http.CanonicalHeaderKey(name)]; !ok {
+			errorList = append(errorList, fmt.Sprintf("No header '%v' found in response", name))
+			continue
+		}
+		if equal := shouldMatchValuePattern(name, response.Header.Get(name), expected.Headers[name]); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+	if expected.Schema != nil {
+		var equal string
+		switch expected.Format {
+		case "", "json":
+			equal = ShouldMatchExpectedJSONResponse(body, expected.Schema.Expected(), nil)
+		case "xml":
+			equal = ShouldMatchExpectedXMLResponse(body, expected.Schema.Expected(), nil)
+		default:
+			equal = fmt.Sprintf("Unknown response body format: %v", expected.Format)
+		}
+		if equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
@@ -0,0 +1,214 @@
+package matcha
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedBookingResponse struct {
+	BookingID string `json:"booking_id" pattern:"^[A-Z]{3}[0-9]+$"`
+	Seats     []struct {
+		Row    string
+		Number float64
+	}
+	Confirmed bool
+}
+
+func bookingContract() Contract {
+	requestSchema, err := SchemaOf(expectedBookingRequest{}, "json")
+	So(err, ShouldBeNil)
+	responseSchema, err := SchemaOf(expectedBookingResponse{}, "json")
+	So(err, ShouldBeNil)
+
+	return Contract{
+		Consumer: "box-office",
+		Provider: "bookings",
+		Interactions: []Interaction{
+			{
+				Description: "create a booking",
+				Request: InteractionRequest{
+					Method:  "POST",
+					Path:    "/events/{event_id}/bookings",
+					Headers: map[string]string{"Content-Type": "application/json"},
+					Schema:  requestSchema,
+					Example: Example{
+						Path:    "/events/25/bookings",
+						Headers: map[string]string{"Content-Type": "application/json"},
+						Body:    `{"customer": "Jane", "seats": 2}`,
+					},
+				},
+				Response: InteractionResponse{
+					Status: http.StatusCreated,
+					Schema: responseSchema,
+					Example: Example{
+						Body: `{"booking_id": "ABC123", "seats": [{"row": "A", "number": 1}], "confirmed": true}`,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+
+	Convey("Given an expected struct", t, func() {
+
+		Convey("When converted to a schema", func() {
+
+			schema, err := SchemaOf(expectedBookingResponse{}, "json")
+
+			Convey("It should describe every field", func() {
+				So(err, ShouldBeNil)
+				So(schema.Type, ShouldEqual, "object")
+				So(len(schema.Fields), ShouldEqual, 3)
+				So(schema.Fields[0].Name, ShouldEqual, "booking_id")
+				So(schema.Fields[0].Pattern, ShouldEqual, "^[A-Z]{3}[0-9]+$")
+				So(schema.Fields[1].Type, ShouldEqual, "array")
+				So(schema.Fields[1].Items.Fields[1].Name, ShouldEqual, "number")
+			})
+
+			Convey("It should match the same documents as the struct", func() {
+				So([]byte(`{"booking_id": "ABC123", "seats": [], "confirmed": true}`), ShouldMatchExpectedJSONResponse, schema.Expected(), nil)
+				failure := ShouldMatchExpectedJSONResponse([]byte(`{"booking_id": "123", "seats": [], "confirmed": true}`), schema.Expected(), nil)
+				So(failure, ShouldStartWith, "BookingID: '123' does not match expected pattern: ^[A-Z]{3}[0-9]+$")
+			})
+
+		})
+
+		Convey("When it has integers, times and constraint tags", func() {
+
+			expected := struct {
+				Count   int       `min:"1" max:"9"`
+				Status  string    `enum:"open|closed"`
+				Created time.Time `json:"created"`
+			}{}
+			schema, err := SchemaOf(expected, "json")
+
+			Convey("It should keep them in the schema", func() {
+				So(err, ShouldBeNil)
+				So(schema.Fields[0].Type, ShouldEqual, "integer")
+				So(schema.Fields[0].Min, ShouldEqual, "1")
+				So(schema.Fields[1].Enum, ShouldEqual, "open|closed")
+				So(schema.Fields[2].Type, ShouldEqual, "time")
+				So(reflect.TypeOf(schema.Expected()).Field(2).Type, ShouldEqual, timeType)
+			})
+
+			Convey("It should match the same documents as the struct", func() {
+				schema.Fields = schema.Fields[:2]
+				So([]byte(`{"count": 3, "status": "open"}`), ShouldMatchExpectedJSONResponse, schema.Expected(), nil)
+				failure := ShouldMatchExpectedJSONResponse([]byte(`{"count": 12, "status": "pending"}`), schema.Expected(), nil)
+				So(failure, ShouldStartWith, "Count: 12 is more than the maximum: 9\nStatus: 'pending' is not one of: open, closed")
+			})
+
+		})
+
+		Convey("When it has a field of an unknown type", func() {
+
+			_, err := SchemaOf(struct{ Lookup map[string]string }{}, "json")
+
+			Convey("It should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+
+		})
+
+	})
+
+}
+
+func TestContracts(t *testing.T) {
+
+	Convey("Given a contract", t, func() {
+
+		contract := bookingContract()
+
+		Convey("When written to a file and read back", func() {
+
+			dir, err := ioutil.TempDir("", "matcha")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "contract.json")
+			So(WriteContract(path, contract), ShouldBeNil)
+			readContract, err := ReadContract(path)
+
+			Convey("It should be unchanged", func() {
+				So(err, ShouldBeNil)
+				So(readContract, ShouldResemble, contract)
+			})
+
+		})
+
+		Convey("When a schema in the file is invalid", func() {
+
+			dir, err := ioutil.TempDir("", "matcha")
+			So(err, ShouldBeNil)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "contract.json")
+
+			Convey("An array without items should be rejected", func() {
+				contract.Interactions[0].Response.Schema = &Schema{Type: "object", Fields: []*Schema{{Type: "array", Name: "seats"}}}
+				So(WriteContract(path, contract), ShouldBeNil)
+				_, err := ReadContract(path)
+				So(err.Error(), ShouldEqual, "Array schema has no items: "+contract.Interactions[0].Description+" response.seats")
+			})
+
+			Convey("An unknown type should be rejected", func() {
+				contract.Interactions[0].Request.Schema = &Schema{Type: "array", Items: &Schema{Type: "date"}}
+				So(WriteContract(path, contract), ShouldBeNil)
+				_, err := ReadContract(path)
+				So(err.Error(), ShouldEqual, "Unknown schema type 'date': "+contract.Interactions[0].Description+" request[]")
+			})
+
+		})
+
+		Convey("When the request is converted back into an expectation", func() {
+
+			expectation := contract.Interactions[0].Request.Expectation()
+
+			Convey("It should match the example request", func() {
+				example := contract.Interactions[0].Request.Example
+				request, _ := http.NewRequest("POST", "http://localhost"+example.Path, nil)
+				request.Body = ioutil.NopCloser(strings.NewReader(example.Body))
+				request.Header.Set("Content-Type", "application/json")
+				So(request, ShouldMatchExpectedRequest, expectation, nil)
+			})
+
+		})
+
+		Convey("When verified against a provider that honours it", func() {
+
+			provider := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte(`{"booking_id": "XYZ987", "seats": [{"row": "B", "number": 4}], "confirmed": false}`))
+			})
+
+			Convey("It should return success", func() {
+				So(VerifyContract(contract, provider), ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When verified against a provider that breaks it", func() {
+
+			provider := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"booking_id": "XYZ987", "seats": [{"row": "B"}], "confirmed": false}`))
+			})
+
+			Convey("It should return an error string", func() {
+				failure := VerifyContract(contract, provider)
+				So(failure, ShouldStartWith, "Interaction 'create a booking' failed:\nExpected response status to be: '201' (but was: '200')!\nNo field 'number' found in response")
+			})
+
+		})
+
+	})
+
+}
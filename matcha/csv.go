@@ -0,0 +1,202 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVOptions control how CSV and TSV documents are read and which columns they must have
+type CSVOptions struct {
+	Comma    rune // The column separator, ',' if not set. Use '\t' for TSV
+	NoHeader bool // There is no header row, so columns are matched to the struct fields in order
+	Ordered  bool // The expected columns must be in the same order in the header as in the struct
+	Strict   bool // Every column must be an expected column
+}
+
+// ShouldMatchExpectedCSV matches every row of a CSV document against the same expected struct. Columns
+// are matched to fields by the name in their 'csv' tag, and each value is parsed into the type of its
+// field before it is checked.
+func ShouldMatchExpectedCSV(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 3 {
+		return fmt.Sprintf("ShouldMatchExpectedCSV expects four arguments: the actual CSV as a byte slice or io.Reader, the expected format of each row as a Struct, a map to hold captured values, and CSVOptions")
+	}
+
+	var reader io.Reader
+	switch actualCSV := actual.(type) {
+	case io.Reader:
+		reader = actualCSV
+	case []byte:
+		reader = bytes.NewReader(actualCSV)
+	default:
+		return fmt.Sprintf("Expected first argument to be a byte slice or io.Reader")
+	}
+	expectedType := expectedTypeOf(expectedList[0])
+	if expectedType == nil || expectedType.Kind() != reflect.Struct {
+		return fmt.Sprintf("Expected second argument to be a Struct")
+	}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+	var opts CSVOptions
+	if expectedList[2] != nil {
+		opts, ok = expectedList[2].(CSVOptions)
+		if !ok {
+			return fmt.Sprintf("Expected fourth argument to be CSVOptions or nil")
+		}
+	}
+
+	matcher := Matcher{format: "csv", capturedValues: capturedValues, store: store}
+	csvReader := csv.NewReader(reader)
+	if opts.Comma != 0 {
+		csvReader.Comma = opts.Comma
+	}
+
+	var errorList []string
+	var columns map[string]int
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errorList = append(errorList, fmt.Sprintf("Was not possible to read CSV: %v", err))
+			break
+		}
+
+		if columns == nil {
+			var headerErrors []string
+			columns, headerErrors = matcher.csvColumns(row, expectedType, opts)
+			errorList = append(errorList, headerErrors...)
+			if columns == nil {
+				break
+			}
+			if !opts.NoHeader {
+				continue
+			}
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		if equal := matcher.shouldMatchCSVRow(row, columns, expectedType); equal != success {
+			errorList = append(errorList, fmt.Sprintf("Line %d: %v", line, equal))
+		}
+	}
+	if columns == nil && errorList == nil && !opts.NoHeader {
+		errorList = append(errorList, fmt.Sprintf("Expected a header row (but the CSV was empty)!"))
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// csvColumns finds the column of each expected field, from the header or from the order of the fields.
+// If any expected column is missing the rows can't be checked, so no columns are returned.
+func (m *Matcher) csvColumns(header []string, expectedType reflect.Type, opts CSVOptions) (map[string]int, []string) {
+
+	var errorList []string
+	columns := make(map[string]int)
+	if opts.NoHeader {
+		for i := 0; i < expectedType.NumField(); i++ {
+			fieldName := m.getFieldName(expectedType.Field(i))
+			if i >= len(header) {
+				errorList = append(errorList, fmt.Sprintf("No column '%v' found in row (expected it in column %d)", fieldName, i+1))
+				continue
+			}
+			columns[fieldName] = i
+		}
+		if opts.Strict && len(header) > expectedType.NumField() {
+			errorList = append(errorList, fmt.Sprintf("Expected %d columns (but got: %d)!", expectedType.NumField(), len(header)))
+		}
+		if len(columns) < expectedType.NumField() {
+			return nil, errorList
+		}
+		return columns, errorList
+	}
+
+	headerColumns := make(map[string]int)
+	for i, name := range header {
+		headerColumns[name] = i
+	}
+	var expectedNames []string
+	for i := 0; i < expectedType.NumField(); i++ {
+		fieldName := m.getFieldName(expectedType.Field(i))
+		expectedNames = append(expectedNames, fieldName)
+		column, ok := headerColumns[fieldName]
+		if !ok {
+			errorList = append(errorList, fmt.Sprintf("No column '%v' found in header", fieldName))
+			continue
+		}
+		columns[fieldName] = column
+	}
+
+	if opts.Strict {
+		for _, name := range header {
+			if _, ok := columns[name]; !ok {
+				errorList = append(errorList, fmt.Sprintf("Unexpected column '%v' found in header", name))
+			}
+		}
+	}
+	if opts.Ordered {
+		previous := -1
+		for _, name := range expectedNames {
+			column, ok := columns[name]
+			if !ok {
+				continue
+			}
+			if column < previous {
+				errorList = append(errorList, fmt.Sprintf("Expected columns in the order: %v (but got: %v)!", strings.Join(expectedNames, ", "), strings.Join(header, ", ")))
+				break
+			}
+			previous = column
+		}
+	}
+
+	if len(columns) < expectedType.NumField() {
+		return nil, errorList
+	}
+	return columns, errorList
+}
+
+func (m *Matcher) shouldMatchCSVRow(row []string, columns map[string]int, expectedType reflect.Type) string {
+	actualRow := make(map[string]interface{})
+	for i := 0; i < expectedType.NumField(); i++ {
+		field := expectedType.Field(i)
+		fieldName := m.getFieldName(field)
+		actualRow[fieldName] = csvValue(row[columns[fieldName]], field.Type)
+	}
+	return m.shouldMatchExpectedObject(actualRow, expectedType, "Result")
+}
+
+// csvValue parses a value into the type of its field. Values that can't be parsed are left as strings
+// so that the mismatch is reported like any other.
+func csvValue(value string, expectedType reflect.Type) interface{} {
+	switch expectedType.Kind() {
+	case reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case reflect.Bool:
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			return boolean
+		}
+	case reflect.Struct:
+		if expectedType == timeType {
+			if datetime, err := time.Parse(time.RFC3339, value); err == nil {
+				return datetime
+			}
+		}
+	}
+	return value
+}
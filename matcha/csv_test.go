@@ -0,0 +1,107 @@
+package matcha
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedCSVRow struct {
+	ID       string  `csv:"id" capture:"" pattern:"^[A-Z]+-[0-9]+$"`
+	Quantity float64 `csv:"quantity" min:"1" max:"10"`
+	Paid     bool    `csv:"paid"`
+	Status   string  `csv:"status" enum:"open|closed"`
+}
+
+func TestCSVMatching(t *testing.T) {
+
+	Convey("Given an expected row format", t, func() {
+
+		var expected expectedCSVRow
+
+		Convey("When every row matches", func() {
+
+			document := []byte("status,id,quantity,paid,notes\nopen,AB-1,2,true,\"first, second\"\nclosed,AB-2,10,false,\n")
+
+			Convey("It should return success and capture from every row", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedCSV(document, expected, capturedValues, nil)
+				So(success, ShouldEqual, "")
+				So(capturedValues["id"], ShouldResemble, []interface{}{"AB-1", "AB-2"})
+			})
+
+		})
+
+		Convey("When the document is tab-separated without a header", func() {
+
+			document := strings.NewReader("AB-1\t2\ttrue\topen\n")
+
+			Convey("It should match columns to fields in order", func() {
+				success := ShouldMatchExpectedCSV(document, expected, nil, CSVOptions{Comma: '\t', NoHeader: true})
+				So(success, ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When a field is an integer", func() {
+
+			expected := struct {
+				ID    string `csv:"id"`
+				Seats int    `csv:"seats" min:"1"`
+				Row   uint8  `csv:"row"`
+			}{}
+
+			Convey("It should read the column as a number", func() {
+				success := ShouldMatchExpectedCSV([]byte("id,seats,row\nAB-1,2,7\n"), expected, nil, nil)
+				So(success, ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When rows don't match", func() {
+
+			document := []byte("id,quantity,paid,status\nab-1,2,yes,open\nAB-2,0,true,pending\nAB-3,many,false,open\n")
+
+			Convey("It should report each error with its line number", func() {
+				failure := ShouldMatchExpectedCSV(document, expected, nil, nil)
+				So(failure, ShouldEqual, strings.Join([]string{
+					"Line 2: ID: 'ab-1' does not match expected pattern: ^[A-Z]+-[0-9]+$\n" + TypeErrorString("paid", "bool", "string"),
+					"Line 3: Quantity: 0 is less than the minimum: 1\nStatus: 'pending' is not one of: open, closed",
+					"Line 4: " + TypeErrorString("quantity", "float64", "string"),
+				}, "\n"))
+			})
+
+		})
+
+		Convey("When the header doesn't have the expected columns", func() {
+
+			Convey("It should report missing columns without checking rows", func() {
+				failure := ShouldMatchExpectedCSV([]byte("id,quantity\nAB-1,2\n"), expected, nil, nil)
+				So(failure, ShouldEqual, "No column 'paid' found in header\nNo column 'status' found in header")
+			})
+
+			Convey("It should report extra columns in strict mode", func() {
+				failure := ShouldMatchExpectedCSV([]byte("id,quantity,paid,status,notes\nAB-1,2,true,open,\n"), expected, nil, CSVOptions{Strict: true})
+				So(failure, ShouldEqual, "Unexpected column 'notes' found in header")
+			})
+
+			Convey("It should report columns out of order in ordered mode", func() {
+				failure := ShouldMatchExpectedCSV([]byte("quantity,id,paid,status\n2,AB-1,true,open\n"), expected, nil, CSVOptions{Ordered: true})
+				So(failure, ShouldEqual, "Expected columns in the order: id, quantity, paid, status (but got: quantity, id, paid, status)!")
+			})
+
+		})
+
+		Convey("When the document is empty", func() {
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedCSV([]byte(""), expected, nil, nil)
+				So(failure, ShouldEqual, "Expected a header row (but the CSV was empty)!")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,305 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	Added   = "added"   // The value is only in the actual document
+	Removed = "removed" // The value is only in the expected document
+	Changed = "changed" // The value is in both documents but is different
+)
+
+// Difference is a single node that differs between two documents
+type Difference struct {
+	Type     string // One of Added, Removed or Changed
+	Path     string // e.g. 'query.results[2].date'
+	Expected interface{}
+	Actual   interface{}
+}
+
+func (d Difference) String() string {
+	path := d.Path
+	if path == "" {
+		path = "(root)"
+	}
+	switch d.Type {
+	case Added:
+		return fmt.Sprintf("+ %v: %v", path, diffValue(d.Actual))
+	case Removed:
+		return fmt.Sprintf("- %v: %v", path, diffValue(d.Expected))
+	}
+	return fmt.Sprintf("~ %v: %v => %v", path, diffValue(d.Expected), diffValue(d.Actual))
+}
+
+// DiffOptions control how documents are compared
+type DiffOptions struct {
+	UnorderedArrays  bool     // Compare every array without regard to the order of its elements
+	UnorderedPaths   []string // Compare only the arrays at these paths without regard to order, e.g. 'results[*].tags'
+	NumericTolerance float64  // Numbers that differ by no more than this are treated as equal
+	IgnoreAdded      bool     // Don't report values that are only in the actual document
+}
+
+var arrayIndexPattern = regexp.MustCompile(`\[[0-9]+\]`)
+
+// Diff compares two documents and returns every node that was added, removed or changed, ordered by
+// path. Documents can be JSON or XML as a byte slice, or already decoded into maps and slices.
+//
+// An expected value can also be a reflect.Type, in which case only the type of the actual value is
+// compared. This is how the assertions describe where a document differs from an expected struct.
+func Diff(expected interface{}, actual interface{}, opts DiffOptions) ([]Difference, error) {
+	expectedTree, err := diffDocument(expected)
+	if err != nil {
+		return nil, fmt.Errorf("Was not possible to decode expected document: %v", err)
+	}
+	actualTree, err := diffDocument(actual)
+	if err != nil {
+		return nil, fmt.Errorf("Was not possible to decode actual document: %v", err)
+	}
+	return opts.diff(expectedTree, actualTree, ""), nil
+}
+
+func diffDocument(document interface{}) (interface{}, error) {
+	switch data := document.(type) {
+	case []byte:
+		format := "json"
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+			format = "xml"
+		}
+		return decodeSnapshot(data, format)
+	case string:
+		return diffDocument([]byte(data))
+	}
+	return document, nil
+}
+
+func (opts DiffOptions) diff(expected interface{}, actual interface{}, path string) []Difference {
+	if expectedType, ok := expected.(reflect.Type); ok {
+		if actual == nil || !jsonKindMatches(expectedType, reflect.TypeOf(actual)) {
+			return []Difference{{Type: Changed, Path: path, Expected: expected, Actual: actual}}
+		}
+		return nil
+	}
+
+	expectedMap, expectedIsMap := expected.(map[string]interface{})
+	actualMap, actualIsMap := actual.(map[string]interface{})
+	if expectedIsMap && actualIsMap {
+		return opts.diffMaps(expectedMap, actualMap, path)
+	}
+	expectedSlice, expectedIsSlice := expected.([]interface{})
+	actualSlice, actualIsSlice := actual.([]interface{})
+	if expectedIsSlice && actualIsSlice {
+		if opts.isUnordered(path) {
+			return opts.diffUnorderedSlices(expectedSlice, actualSlice, path)
+		}
+		return opts.diffSlices(expectedSlice, actualSlice, path)
+	}
+
+	if !opts.valuesEqual(expected, actual) {
+		return []Difference{{Type: Changed, Path: path, Expected: expected, Actual: actual}}
+	}
+	return nil
+}
+
+func (opts DiffOptions) diffMaps(expected map[string]interface{}, actual map[string]interface{}, path string) []Difference {
+	var differences []Difference
+	var keys []string
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	for key := range actual {
+		if _, ok := expected[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := strings.TrimPrefix(path+"."+key, ".")
+		expectedValue, inExpected := expected[key]
+		actualValue, inActual := actual[key]
+		switch {
+		case !inActual:
+			differences = append(differences, Difference{Type: Removed, Path: childPath, Expected: expectedValue})
+		case !inExpected:
+			if !opts.IgnoreAdded {
+				differences = append(differences, Difference{Type: Added, Path: childPath, Actual: actualValue})
+			}
+		default:
+			differences = append(differences, opts.diff(expectedValue, actualValue, childPath)...)
+		}
+	}
+	return differences
+}
+
+func (opts DiffOptions) diffSlices(expected []interface{}, actual []interface{}, path string) []Difference {
+	var differences []Difference
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		childPath := fmt.Sprintf("%v[%d]", path, i)
+		switch {
+		case i >= len(actual):
+			differences = append(differences, Difference{Type: Removed, Path: childPath, Expected: expected[i]})
+		case i >= len(expected):
+			if !opts.IgnoreAdded {
+				differences = append(differences, Difference{Type: Added, Path: childPath, Actual: actual[i]})
+			}
+		default:
+			differences = append(differences, opts.diff(expected[i], actual[i], childPath)...)
+		}
+	}
+	return differences
+}
+
+// diffUnorderedSlices pairs each expected element with the first equal actual element that hasn't
+// already been paired. Anything left over has been removed or added.
+func (opts DiffOptions) diffUnorderedSlices(expected []interface{}, actual []interface{}, path string) []Difference {
+	var differences []Difference
+	paired := make([]bool, len(actual))
+	for i, expectedValue := range expected {
+		found := false
+		for j, actualValue := range actual {
+			if !paired[j] && opts.diff(expectedValue, actualValue, "") == nil {
+				paired[j] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			differences = append(differences, Difference{Type: Removed, Path: fmt.Sprintf("%v[%d]", path, i), Expected: expectedValue})
+		}
+	}
+	if !opts.IgnoreAdded {
+		for j, actualValue := range actual {
+			if !paired[j] {
+				differences = append(differences, Difference{Type: Added, Path: fmt.Sprintf("%v[%d]", path, j), Actual: actualValue})
+			}
+		}
+	}
+	return differences
+}
+
+func (opts DiffOptions) isUnordered(path string) bool {
+	if opts.UnorderedArrays {
+		return true
+	}
+	genericPath := arrayIndexPattern.ReplaceAllString(path, "[*]")
+	for _, unorderedPath := range opts.UnorderedPaths {
+		if unorderedPath == genericPath {
+			return true
+		}
+	}
+	return false
+}
+
+func (opts DiffOptions) valuesEqual(expected interface{}, actual interface{}) bool {
+	expectedNumber, expectedIsNumber := numberValue(expected)
+	actualNumber, actualIsNumber := numberValue(actual)
+	if expectedIsNumber && actualIsNumber {
+		return math.Abs(expectedNumber-actualNumber) <= opts.NumericTolerance
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+// jsonKindMatches checks an actual value's type against an expected type in the same way as the matcher
+func jsonKindMatches(expectedType reflect.Type, actualType reflect.Type) bool {
+	switch expectedType.Kind() {
+	case reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, isNumber := numberValue(reflect.Zero(actualType).Interface())
+		return isNumber
+	case reflect.Slice:
+		return actualType.Kind() == reflect.Slice
+	case reflect.Struct:
+		if expectedType == timeType {
+			return actualType == timeType
+		}
+		return actualType.Kind() == reflect.Map
+	}
+	return expectedType == actualType
+}
+
+func diffValue(value interface{}) string {
+	if valueType, ok := value.(reflect.Type); ok {
+		return fmt.Sprintf("<%v>", valueType)
+	}
+	var encoded bytes.Buffer
+	encoder := json.NewEncoder(&encoded)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSpace(encoded.String())
+}
+
+// expectedSkeleton builds a document with the structure of the expected type, with the expected type
+// of each value in place of the value itself. Arrays get one element for each element of the actual
+// array so that every element is compared.
+func (m *Matcher) expectedSkeleton(actual interface{}, expectedType reflect.Type) interface{} {
+	switch expectedType.Kind() {
+	case reflect.Slice:
+		actualSlice, ok := actual.([]interface{})
+		if !ok {
+			if m.format == "xml" {
+				return m.expectedSkeleton(actual, expectedType.Elem())
+			}
+			return expectedType
+		}
+		skeleton := make([]interface{}, len(actualSlice))
+		for i, element := range actualSlice {
+			skeleton[i] = m.expectedSkeleton(element, expectedType.Elem())
+		}
+		return skeleton
+	case reflect.Struct:
+		actualMap, _ := actual.(map[string]interface{})
+		if actualMap == nil || expectedType == timeType {
+			return expectedType
+		}
+		skeleton := make(map[string]interface{})
+		for i := 0; i < expectedType.NumField(); i++ {
+			field := expectedType.Field(i)
+			fieldName := m.getFieldName(field)
+			skeleton[fieldName] = m.expectedSkeleton(actualMap[fieldName], field.Type)
+		}
+		return skeleton
+	}
+	return expectedType
+}
+
+// maxDescribedDifferences is how many differences from the expected structure a failure lists
+const maxDescribedDifferences = 20
+
+// describeDifferences lists where the actual document differs in structure from the expected type.
+// It is left out when MaxErrors or AggregateErrors are given, as they are asked for to keep failures
+// on large documents short, and otherwise stops after maxDescribedDifferences.
+func (m *Matcher) describeDifferences(actual interface{}, expectedType reflect.Type) string {
+	if m.maxErrors > 0 || m.aggregateErrors {
+		return success
+	}
+	skeleton := m.expectedSkeleton(actual, expectedType)
+	differences := DiffOptions{IgnoreAdded: true}.diff(skeleton, actual, "")
+	if differences == nil {
+		return success
+	}
+	if len(differences) > maxDescribedDifferences {
+		more := len(differences) - maxDescribedDifferences
+		return "Differences from expected structure:\n" + formatDifferences(differences[:maxDescribedDifferences]) +
+			fmt.Sprintf("\n... and %d more", more)
+	}
+	return "Differences from expected structure:\n" + formatDifferences(differences)
+}
+
+// formatDifferences puts each difference on its own line
+func formatDifferences(differences []Difference) string {
+	lines := make([]string, len(differences))
+	for i, difference := range differences {
+		lines[i] = difference.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,159 @@
+package matcha
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiff(t *testing.T) {
+
+	Convey("Given two JSON documents", t, func() {
+
+		expected := []byte(`{"name": "Jane", "seats": [1, 2, 3], "price": 10.5, "tags": ["a", "b"]}`)
+
+		Convey("When they are the same", func() {
+
+			differences, err := Diff(expected, []byte(`{"tags": ["a", "b"], "price": 10.5, "seats": [1, 2, 3], "name": "Jane"}`), DiffOptions{})
+
+			Convey("It should return no differences", func() {
+				So(err, ShouldBeNil)
+				So(differences, ShouldBeEmpty)
+			})
+
+		})
+
+		Convey("When nodes have been added, removed and changed", func() {
+
+			differences, err := Diff(expected, []byte(`{"seats": [1, 2], "price": 11, "tags": ["a", "b"], "status": "paid"}`), DiffOptions{})
+
+			Convey("It should list every difference ordered by path", func() {
+				So(err, ShouldBeNil)
+				So(differences, ShouldResemble, []Difference{
+					{Type: Removed, Path: "name", Expected: "Jane"},
+					{Type: Changed, Path: "price", Expected: 10.5, Actual: float64(11)},
+					{Type: Removed, Path: "seats[2]", Expected: float64(3)},
+					{Type: Added, Path: "status", Actual: "paid"},
+				})
+				So(formatDifferences(differences), ShouldEqual, "- name: \"Jane\"\n~ price: 10.5 => 11\n- seats[2]: 3\n+ status: \"paid\"")
+			})
+
+		})
+
+		Convey("When arrays are in a different order", func() {
+
+			actual := []byte(`{"name": "Jane", "seats": [3, 1, 2], "price": 10.5, "tags": ["b", "c"]}`)
+
+			Convey("It should report them as changed by default", func() {
+				differences, _ := Diff(expected, actual, DiffOptions{})
+				So(len(differences), ShouldEqual, 5)
+			})
+
+			Convey("It should ignore the order of every array if configured", func() {
+				differences, _ := Diff(expected, actual, DiffOptions{UnorderedArrays: true})
+				So(formatDifferences(differences), ShouldEqual, "- tags[0]: \"a\"\n+ tags[1]: \"c\"")
+			})
+
+			Convey("It should ignore the order of only the configured arrays", func() {
+				differences, _ := Diff(expected, actual, DiffOptions{UnorderedPaths: []string{"seats"}})
+				So(formatDifferences(differences), ShouldEqual, "~ tags[0]: \"a\" => \"b\"\n~ tags[1]: \"b\" => \"c\"")
+			})
+
+		})
+
+		Convey("When numbers are within the tolerance", func() {
+
+			differences, _ := Diff(expected, []byte(`{"name": "Jane", "seats": [1, 2, 3.001], "price": 10.49, "tags": ["a", "b"]}`), DiffOptions{NumericTolerance: 0.01})
+
+			Convey("It should treat them as equal", func() {
+				So(differences, ShouldBeEmpty)
+			})
+
+		})
+
+		Convey("When a document is invalid", func() {
+
+			_, err := Diff(expected, []byte(`{a}`), DiffOptions{})
+
+			Convey("It should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+
+		})
+
+	})
+
+	Convey("Given two XML documents", t, func() {
+
+		differences, err := Diff([]byte(`<seat><row>A</row><number>1</number></seat>`), []byte(`<seat><number>2</number><row>A</row></seat>`), DiffOptions{})
+
+		Convey("It should compare them in the same way", func() {
+			So(err, ShouldBeNil)
+			So(formatDifferences(differences), ShouldEqual, "~ seat.number: 1 => 2")
+		})
+
+	})
+
+	Convey("Given an expected type instead of a value", t, func() {
+
+		expected := map[string]interface{}{"name": reflect.TypeOf("")}
+
+		Convey("It should only compare the type of the actual value", func() {
+			differences, _ := Diff(expected, map[string]interface{}{"name": "Jane"}, DiffOptions{})
+			So(differences, ShouldBeEmpty)
+			differences, _ = Diff(expected, map[string]interface{}{"name": 5.0}, DiffOptions{})
+			So(formatDifferences(differences), ShouldEqual, "~ name: <string> => 5")
+		})
+
+	})
+
+}
+
+func TestMismatchDifferences(t *testing.T) {
+
+	Convey("Given an expected array of structs", t, func() {
+
+		var expected expectedListOfObjects
+
+		Convey("When the actual JSON has a different structure", func() {
+
+			fakeJSON := []byte(`[ { "result": { "attributes": { "string_field": 5 }, "success": true } }, { "result": { "attributes": {} }, "extra": 1 } ]`)
+
+			Convey("It should describe where it differs instead of printing the whole document", func() {
+				failure := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil)
+				So(failure, ShouldEndWith, "Differences from expected structure:\n~ [0].result.attributes.string_field: <string> => 5\n- [1].result.attributes.string_field: <string>\n- [1].result.success: <bool>")
+			})
+
+		})
+
+		Convey("When the actual JSON differs in many elements", func() {
+
+			elements := make([]string, 1000)
+			for i := range elements {
+				elements[i] = `{ "result": { "attributes": { "string_field": 5 }, "success": true } }`
+			}
+			fakeJSON := []byte("[" + strings.Join(elements, ",") + "]")
+
+			Convey("It should only list the first differences", func() {
+				failure := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil)
+				differences := failure[strings.Index(failure, "Differences from expected structure:\n"):]
+				So(strings.Count(differences, "\n"), ShouldEqual, maxDescribedDifferences+1)
+				So(differences, ShouldContainSubstring, "~ [19].result.attributes.string_field: <string> => 5\n")
+				So(differences, ShouldEndWith, "\n... and 980 more")
+			})
+
+			Convey("It should leave them out when MaxErrors or AggregateErrors are given", func() {
+				for _, option := range []Option{MaxErrors(3), AggregateErrors()} {
+					failure := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil, option)
+					So(failure, ShouldNotContainSubstring, "Differences from expected structure")
+					So(len(failure), ShouldBeLessThan, 1000)
+				}
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,83 @@
+package matcha
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// ShouldMatchExpectedForm matches an application/x-www-form-urlencoded body against the expected
+// struct, with field names from 'form' tags. Values are parsed into the type of their field, and keys
+// that are repeated are matched against slice fields. A slice field also accepts a key that isn't
+// repeated, as there is no way to tell a single value from a list of one.
+func ShouldMatchExpectedForm(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return fmt.Sprintf("ShouldMatchExpectedForm expects three arguments: the actual form as a byte slice or url.Values, the expected form format as a Struct, and a map to hold captured values")
+	}
+
+	var values url.Values
+	switch actualForm := actual.(type) {
+	case url.Values:
+		values = actualForm
+	case []byte:
+		var err error
+		values, err = url.ParseQuery(string(actualForm))
+		if err != nil {
+			return fmt.Sprintf("Was not possible to parse form (%v). Form data:\n%v", err, string(actualForm))
+		}
+	default:
+		return fmt.Sprintf("Expected first argument to be a byte slice or url.Values")
+	}
+	expectedType := expectedTypeOf(expectedList[0])
+	if expectedType == nil || expectedType.Kind() != reflect.Struct {
+		return fmt.Sprintf("Expected second argument to be a Struct")
+	}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+
+	matcher := Matcher{format: "form", capturedValues: capturedValues, store: store}
+	actualForm := matcher.formTree(values, expectedType)
+	result := matcher.shouldMatchExpectedField(actualForm, expectedType, "Result")
+	if result != success {
+		if differences := matcher.describeDifferences(actualForm, expectedType); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
+	}
+	return result
+}
+
+// formTree turns form values into an object, parsing the values of expected fields into their type
+func (m *Matcher) formTree(values url.Values, expectedType reflect.Type) map[string]interface{} {
+	fieldTypes := make(map[string]reflect.Type)
+	for i := 0; i < expectedType.NumField(); i++ {
+		fieldTypes[m.getFieldName(expectedType.Field(i))] = expectedType.Field(i).Type
+	}
+
+	tree := make(map[string]interface{})
+	for key, keyValues := range values {
+		fieldType, expected := fieldTypes[key]
+		if !expected {
+			fieldType = reflect.TypeOf("")
+		}
+		elementType := fieldType
+		if fieldType.Kind() == reflect.Slice {
+			elementType = fieldType.Elem()
+		}
+
+		parsed := make([]interface{}, len(keyValues))
+		for i, value := range keyValues {
+			parsed[i] = csvValue(value, elementType)
+		}
+		if len(parsed) == 1 && fieldType.Kind() != reflect.Slice {
+			tree[key] = parsed[0]
+		} else {
+			tree[key] = parsed
+		}
+	}
+	return tree
+}
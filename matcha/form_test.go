@@ -0,0 +1,62 @@
+package matcha
+
+import (
+	"net/url"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedPaymentCallback struct {
+	Reference string   `form:"ref" capture:"" pattern:"^PAY-"`
+	Amount    float64  `form:"amount" min:"0"`
+	Success   bool     `form:"success"`
+	Items     []string `form:"item"`
+}
+
+func TestFormMatching(t *testing.T) {
+
+	Convey("Given an expected form format", t, func() {
+
+		var expected expectedPaymentCallback
+
+		Convey("When the form matches", func() {
+
+			form := []byte("ref=PAY-1&amount=12.50&success=true&item=ticket&item=programme&signature=abc")
+
+			Convey("It should return success", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedForm(form, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["ref"], ShouldResemble, []interface{}{"PAY-1"})
+			})
+
+		})
+
+		Convey("When a repeated key is expected only once", func() {
+
+			form := url.Values{"ref": {"PAY-1", "PAY-2"}, "amount": {"1"}, "success": {"1"}, "item": {"ticket"}}
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedForm(form, expected, nil)
+				So(failure, ShouldStartWith, "Expected a string value for field: Reference but instead got []interface {}")
+			})
+
+		})
+
+		Convey("When values can't be parsed into their field's type", func() {
+
+			form := []byte("ref=PAY-1&amount=lots&success=maybe")
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedForm(form, expected, nil)
+				So(failure, ShouldStartWith, TypeErrorString("amount", "float64", "string")+"\n"+
+					TypeErrorString("success", "bool", "string")+"\n"+
+					"No field 'item' found in response\n")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,594 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp/syntax"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Mutation is a document that is only just invalid for an expected struct: one value in it has been
+// removed or changed so that the matcher rejects it
+type Mutation struct {
+	Path        string // Where the value that was changed is, e.g. 'items[0].price'
+	Description string // What was done to it, e.g. 'wrong type'
+	Document    []byte
+}
+
+// generator makes up values for an expected struct
+type generator struct {
+	Matcher
+	random    *rand.Rand
+	ancestors []reflect.Type // The structs the value being made up is in
+}
+
+const (
+	// maxGenerateAttempts is how many times a value is made up again when it breaks one of its constraints
+	maxGenerateAttempts = 20
+	// maxGenerateDepth is how many times a struct can be nested in itself before its arrays are left empty
+	maxGenerateDepth = 4
+)
+
+// Generate makes up a JSON document that matches an expected struct. Field names are worked out the same
+// way as when matching, and values satisfy the 'pattern', 'min', 'max', 'enum', 'equals' and 'in' tags
+// of their fields. The same seed always gives the same document.
+func Generate(expected interface{}, seed int64) ([]byte, error) {
+	return GenerateDocument(expected, "json", seed)
+}
+
+// GenerateDocument makes up a document in the given format, 'json' or 'xml', that matches an expected
+// struct. XML documents have a single root element, so the expected struct must have a single field.
+func GenerateDocument(expected interface{}, format string, seed int64) ([]byte, error) {
+	g := &generator{Matcher: Matcher{format: format}, random: rand.New(rand.NewSource(seed))}
+	value, err := g.value(expectedTypeOf(expected), "Result", nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return encodeDocument(value, format)
+}
+
+// Mutations makes up a document in the given format that matches an expected struct, then returns
+// variants of it that each break one thing about one value: it is missing, has the wrong type, or
+// breaks a constraint of its field. Only the first element of each array is changed. Every mutation is
+// checked against the expected struct, so each one is known not to match.
+func Mutations(expected interface{}, format string, seed int64) ([]Mutation, error) {
+	g := &generator{Matcher: Matcher{format: format}, random: rand.New(rand.NewSource(seed))}
+	expectedType := expectedTypeOf(expected)
+	value, err := g.value(expectedType, "Result", nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := encodeDocument(value, format); err != nil {
+		return nil, err
+	}
+
+	var mutations []Mutation
+	g.mutate(value, expectedType, nil, nil, func(path []interface{}, description string, mutated interface{}, remove bool) {
+		// Some changes can't be written in every format, such as an XML document without a root element
+		document, err := encodeDocument(replaceAt(value, path, mutated, remove), format)
+		if err != nil || matchesDocument(document, expected, format) {
+			return
+		}
+		mutations = append(mutations, Mutation{Path: describePath(path), Description: description, Document: document})
+	})
+	return mutations, nil
+}
+
+// value makes up a value for an expected type, using the tags of the field it is for, if any. The depth
+// is how many times a struct has been nested in itself, and past maxGenerateDepth arrays are left empty
+// so that types that refer to themselves have an end.
+func (g *generator) value(expectedType reflect.Type, fieldName string, field *reflect.StructField, depth int) (interface{}, error) {
+	if field != nil {
+		if value, ok, err := g.taggedValue(*field); ok || err != nil {
+			return value, err
+		}
+	}
+
+	switch expectedType.Kind() {
+	case reflect.String:
+		return g.stringValue(field)
+	case reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return g.numberValue(expectedType, field), nil
+	case reflect.Bool:
+		return g.random.Intn(2) == 1, nil
+	case reflect.Slice:
+		low, high := 1, 3
+		if depth >= maxGenerateDepth {
+			low, high = 0, 0
+		}
+		if field != nil {
+			low, high = g.limitRange(field, low, high)
+		}
+		if depth >= maxGenerateDepth {
+			// An empty array can't be written in XML, and an array that needs elements would go on forever
+			if g.format == "xml" || (low > 0 && depth > maxGenerateDepth) {
+				return nil, fmt.Errorf("Can't generate a value for field: %v, as its type refers to itself too deeply", fieldName)
+			}
+			high = low
+		}
+		if g.format == "xml" && low < 2 && high >= 2 {
+			// XML arrays with fewer than two elements can't be told apart from a missing or single value
+			low = 2
+		}
+		if high < low {
+			return nil, fmt.Errorf("Can't generate a value for field: %v, as its 'min' is more than its 'max'", fieldName)
+		}
+		elements := make([]interface{}, low+g.random.Intn(high-low+1))
+		for i := range elements {
+			element, err := g.value(expectedType.Elem(), fieldName, nil, depth)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = element
+		}
+		return elements, nil
+	case reflect.Struct:
+		if expectedType == timeType {
+			break
+		}
+		for _, ancestor := range g.ancestors {
+			if ancestor == expectedType {
+				depth++
+				break
+			}
+		}
+		g.ancestors = append(g.ancestors, expectedType)
+		defer func() { g.ancestors = g.ancestors[:len(g.ancestors)-1] }()
+
+		object := make(map[string]interface{})
+		for i := 0; i < expectedType.NumField(); i++ {
+			structField := expectedType.Field(i)
+			value, err := g.value(structField.Type, structField.Name, &structField, depth)
+			if err != nil {
+				return nil, err
+			}
+			object[g.getFieldName(structField)] = value
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("'%v' is of a type I don't know how to generate", expectedType)
+}
+
+// taggedValue picks a value from the 'equals', 'in' or 'enum' tag of a field if it has one
+func (g *generator) taggedValue(field reflect.StructField) (interface{}, bool, error) {
+	if tag, ok := field.Tag.Lookup("equals"); ok {
+		if variablePattern.MatchString(tag) {
+			return nil, true, fmt.Errorf("Can't generate a value for field: %v, as it refers to captured values", field.Name)
+		}
+		value, err := literalValue("equals", tag, field)
+		return value, true, err
+	}
+
+	var choices []string
+	if tag, ok := field.Tag.Lookup("in"); ok {
+		if variablePattern.MatchString(tag) {
+			return nil, true, fmt.Errorf("Can't generate a value for field: %v, as it refers to captured values", field.Name)
+		}
+		choices = strings.Split(tag, "|")
+	} else if plan := compiledField(field); plan.enum != nil {
+		choices = plan.enum
+	}
+	if choices == nil {
+		return nil, false, nil
+	}
+	value, err := literalValue("enum", choices[g.random.Intn(len(choices))], field)
+	return value, true, err
+}
+
+func (g *generator) stringValue(field *reflect.StructField) (string, error) {
+	var pattern string
+	low, high := 5, 10
+	if field != nil {
+		if pattern = field.Tag.Get("pattern"); pattern != "" {
+			// The pattern decides how long its strings are, unless the field limits them too
+			low, high = 0, math.MaxInt32
+		}
+		low, high = g.limitRange(field, low, high)
+	}
+
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		var value string
+		if pattern != "" {
+			var err error
+			if value, err = patternString(pattern, g.random); err != nil {
+				return "", err
+			}
+		} else {
+			letters := make([]rune, low+g.random.Intn(high-low+1))
+			for i := range letters {
+				letters[i] = rune('a' + g.random.Intn(26))
+			}
+			value = string(letters)
+		}
+		length := len([]rune(value))
+		if length < low || length > high {
+			continue
+		}
+		// Numbers and booleans in XML are read as such, so they can't be strings
+		if g.format == "xml" && reflect.TypeOf(xmlValue(value)).Kind() != reflect.String {
+			continue
+		}
+		if g.format == "xml" && strings.TrimSpace(value) != value {
+			continue
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("Can't generate a value for field: %v that satisfies all of its tags", field.Name)
+}
+
+func (g *generator) numberValue(expectedType reflect.Type, field *reflect.StructField) float64 {
+	minimum, maximum := math.Inf(-1), math.Inf(1)
+	if field != nil {
+		for _, limit := range compiledField(*field).limits {
+			if limit.constraint == "min" {
+				minimum = limit.value
+			} else {
+				maximum = limit.value
+			}
+		}
+	}
+	switch {
+	case math.IsInf(minimum, -1) && math.IsInf(maximum, 1):
+		minimum, maximum = 0, 100
+	case math.IsInf(minimum, -1):
+		minimum = maximum - 100
+	case math.IsInf(maximum, 1):
+		maximum = minimum + 100
+	}
+
+	if expectedType.Kind() != reflect.Float64 {
+		low, high := math.Ceil(minimum), math.Floor(maximum)
+		if high < low {
+			return low
+		}
+		return low + float64(g.random.Int63n(int64(high-low)+1))
+	}
+	value := math.Round((minimum+g.random.Float64()*(maximum-minimum))*100) / 100
+	return math.Max(minimum, math.Min(maximum, value))
+}
+
+// limitRange narrows a range of lengths to the 'min' and 'max' tags of a field
+func (g *generator) limitRange(field *reflect.StructField, low int, high int) (int, int) {
+	for _, limit := range compiledField(*field).limits {
+		if limit.constraint == "min" {
+			low = int(math.Ceil(limit.value))
+			if high < low {
+				high = low + 2
+			}
+		} else {
+			high = int(math.Floor(limit.value))
+			if low > high {
+				low = high
+			}
+		}
+	}
+	if low < 0 {
+		low = 0
+	}
+	return low, high
+}
+
+// mutate calls mutation with each change it can make to a value that should stop it matching
+func (g *generator) mutate(value interface{}, expectedType reflect.Type, field *reflect.StructField, path []interface{}, mutation func([]interface{}, string, interface{}, bool)) {
+	if field != nil {
+		mutation(path, "missing", nil, true)
+	}
+	mutation(path, "wrong type", wrongTypeValue(value), false)
+
+	if field != nil {
+		plan := compiledField(*field)
+		for _, limit := range plan.limits {
+			g.mutateLimit(value, limit, path, mutation)
+		}
+		if plan.pattern != nil {
+			if text, ok := value.(string); ok {
+				for _, candidate := range []string{text + "!", "!" + text, ""} {
+					if !plan.pattern.MatchString(candidate) {
+						mutation(path, "doesn't match pattern", candidate, false)
+						break
+					}
+				}
+			}
+		}
+		_, hasEquals := field.Tag.Lookup("equals")
+		_, hasIn := field.Tag.Lookup("in")
+		if plan.enum != nil || hasEquals || hasIn {
+			if text, ok := value.(string); ok {
+				mutation(path, "not an allowed value", "not-"+text, false)
+			} else if number, ok := value.(float64); ok {
+				mutation(path, "not an allowed value", number+0.5, false)
+			}
+		}
+	}
+
+	switch actual := value.(type) {
+	case []interface{}:
+		if len(actual) > 0 {
+			g.mutate(actual[0], expectedType.Elem(), nil, append(append([]interface{}(nil), path...), 0), mutation)
+		}
+	case map[string]interface{}:
+		for i := 0; i < expectedType.NumField(); i++ {
+			structField := expectedType.Field(i)
+			name := g.getFieldName(structField)
+			g.mutate(actual[name], structField.Type, &structField, append(append([]interface{}(nil), path...), name), mutation)
+		}
+	}
+}
+
+// mutateLimit breaks a 'min' or 'max' tag by going just past it
+func (g *generator) mutateLimit(value interface{}, limit fieldLimit, path []interface{}, mutation func([]interface{}, string, interface{}, bool)) {
+	description := fmt.Sprintf("breaks '%v'", limit.constraint)
+	switch actual := value.(type) {
+	case float64:
+		if limit.constraint == "min" {
+			mutation(path, description, math.Floor(limit.value)-1, false)
+		} else {
+			mutation(path, description, math.Ceil(limit.value)+1, false)
+		}
+	case string:
+		if limit.constraint == "min" && limit.value >= 1 {
+			mutation(path, description, string([]rune(actual)[:int(math.Ceil(limit.value))-1]), false)
+		} else if limit.constraint == "max" {
+			mutation(path, description, actual+strings.Repeat("x", int(limit.value)+1-len([]rune(actual))), false)
+		}
+	case []interface{}:
+		if limit.constraint == "min" && limit.value >= 1 && len(actual) > 0 {
+			mutation(path, description, actual[:int(math.Ceil(limit.value))-1], false)
+		} else if limit.constraint == "max" && len(actual) > 0 {
+			longer := append([]interface{}(nil), actual...)
+			for float64(len(longer)) <= limit.value {
+				longer = append(longer, actual[0])
+			}
+			mutation(path, description, longer, false)
+		}
+	}
+}
+
+// wrongTypeValue returns a value of a different type to the one given
+func wrongTypeValue(value interface{}) interface{} {
+	switch value.(type) {
+	case string:
+		return 12.5
+	case float64:
+		return "twelve"
+	case bool:
+		return "yes"
+	case []interface{}:
+		return map[string]interface{}{}
+	}
+	return "text"
+}
+
+// replaceAt returns a copy of a document with the value at a path replaced or removed
+func replaceAt(document interface{}, path []interface{}, value interface{}, remove bool) interface{} {
+	if len(path) == 0 {
+		return value
+	}
+	switch actual := document.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(actual))
+		for key, element := range actual {
+			copied[key] = element
+		}
+		key := path[0].(string)
+		if remove && len(path) == 1 {
+			delete(copied, key)
+		} else {
+			copied[key] = replaceAt(actual[key], path[1:], value, remove)
+		}
+		return copied
+	case []interface{}:
+		copied := append([]interface{}(nil), actual...)
+		index := path[0].(int)
+		if remove && len(path) == 1 {
+			return append(copied[:index], copied[index+1:]...)
+		}
+		copied[index] = replaceAt(actual[index], path[1:], value, remove)
+		return copied
+	}
+	return document
+}
+
+func describePath(path []interface{}) string {
+	var description strings.Builder
+	for _, segment := range path {
+		if index, ok := segment.(int); ok {
+			fmt.Fprintf(&description, "[%d]", index)
+		} else {
+			if description.Len() > 0 {
+				description.WriteString(".")
+			}
+			description.WriteString(segment.(string))
+		}
+	}
+	return description.String()
+}
+
+func matchesDocument(document []byte, expected interface{}, format string) bool {
+	if format == "xml" {
+		return ShouldMatchExpectedXMLResponse(document, expected, nil) == success
+	}
+	return ShouldMatchExpectedJSONResponse(document, expected, nil) == success
+}
+
+func encodeDocument(value interface{}, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		return json.MarshalIndent(value, "", "  ")
+	case "xml":
+		object, ok := value.(map[string]interface{})
+		if !ok || len(object) != 1 {
+			return nil, fmt.Errorf("An XML document must have a single root element, so the expected struct must have a single field")
+		}
+		var document bytes.Buffer
+		for name, root := range object {
+			if err := writeXMLElement(&document, name, root); err != nil {
+				return nil, err
+			}
+		}
+		return document.Bytes(), nil
+	}
+	return nil, fmt.Errorf("Can't generate documents in the format: %v", format)
+}
+
+// writeXMLElement writes a value as an element, with an element for each field of an object and one
+// element for each value in an array
+func writeXMLElement(document *bytes.Buffer, name string, value interface{}) error {
+	switch actual := value.(type) {
+	case []interface{}:
+		for _, element := range actual {
+			if _, nested := element.([]interface{}); nested {
+				return fmt.Errorf("Arrays of arrays can't be written as XML: %v", name)
+			}
+			if err := writeXMLElement(document, name, element); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		fmt.Fprintf(document, "<%v>", name)
+		keys := make([]string, 0, len(actual))
+		for key := range actual {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := writeXMLElement(document, key, actual[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		fmt.Fprintf(document, "<%v>", name)
+		text := fmt.Sprintf("%v", actual)
+		if number, ok := actual.(float64); ok {
+			text = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		if err := xml.EscapeText(document, []byte(text)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(document, "</%v>", name)
+	return nil
+}
+
+// xmlValue converts text the way XML documents are decoded for matching, into a number or boolean if
+// it looks like one
+func xmlValue(text string) interface{} {
+	if number, err := strconv.ParseFloat(text, 64); err == nil {
+		return number
+	}
+	if boolean, err := strconv.ParseBool(text); err == nil {
+		return boolean
+	}
+	return text
+}
+
+// patternExample makes up a string that matches a regular expression, taking the first choice of each
+// alternative and the fewest repetitions it can
+func patternExample(pattern string) (string, error) {
+	return patternString(pattern, nil)
+}
+
+// patternString makes up a string that matches a regular expression, making random choices if given a
+// source of randomness
+func patternString(pattern string, random *rand.Rand) (string, error) {
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", fmt.Errorf("Received invalid regular expression: %v", pattern)
+	}
+	var example strings.Builder
+	writePatternString(&example, parsed.Simplify(), random)
+	return example.String(), nil
+}
+
+func writePatternString(example *strings.Builder, parsed *syntax.Regexp, random *rand.Rand) {
+	// repeat writes the sub-expression at least min times, and up to max times when random
+	repeat := func(min int, max int) {
+		count := min
+		if random != nil && max > min {
+			count += random.Intn(max - min + 1)
+		}
+		for i := 0; i < count; i++ {
+			writePatternString(example, parsed.Sub[0], random)
+		}
+	}
+
+	switch parsed.Op {
+	case syntax.OpLiteral:
+		example.WriteString(string(parsed.Rune))
+	case syntax.OpCharClass:
+		example.WriteRune(classRune(parsed.Rune, random))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		example.WriteRune(classRune([]rune{'a', 'z'}, random))
+	case syntax.OpCapture, syntax.OpConcat:
+		for _, sub := range parsed.Sub {
+			writePatternString(example, sub, random)
+		}
+	case syntax.OpAlternate:
+		choice := 0
+		if random != nil {
+			choice = random.Intn(len(parsed.Sub))
+		}
+		writePatternString(example, parsed.Sub[choice], random)
+	case syntax.OpStar:
+		repeat(0, 3)
+	case syntax.OpPlus:
+		repeat(1, 3)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		max := parsed.Max
+		if max < 0 {
+			max = parsed.Min + 3
+		}
+		repeat(parsed.Min, max)
+	}
+}
+
+// classRune picks a character from a character class, which is given as pairs of the first and last
+// characters of its ranges. Without randomness letters and digits are preferred, and with it any
+// printable ASCII character in the class can be picked.
+func classRune(ranges []rune, random *rand.Rand) rune {
+	if random != nil {
+		var printable []rune
+		for i := 0; i+1 < len(ranges); i += 2 {
+			for r := ranges[i]; r <= ranges[i+1] && r < unicode.MaxASCII; r++ {
+				if unicode.IsPrint(r) {
+					printable = append(printable, r)
+				}
+			}
+		}
+		if len(printable) > 0 {
+			return printable[random.Intn(len(printable))]
+		}
+	}
+
+	for _, preferred := range "a0A" {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= preferred && preferred <= ranges[i+1] {
+				return preferred
+			}
+		}
+	}
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1]; r++ {
+			if unicode.IsPrint(r) {
+				return r
+			}
+		}
+	}
+	if len(ranges) > 0 {
+		return ranges[0]
+	}
+	return 'a'
+}
@@ -0,0 +1,190 @@
+package matcha
+
+import (
+	"encoding/json"
+	"math/rand"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type generatedItem struct {
+	ID       string  `json:"id" pattern:"^[A-Z]{3}-[0-9]{4}$"`
+	Name     string  `min:"3" max:"12"`
+	Price    float64 `min:"0.5" max:"99.5"`
+	Quantity int     `min:"1" max:"9"`
+	Status   string  `enum:"open|closed"`
+	InStock  bool
+}
+
+type generatedBasket struct {
+	Reference string          `pattern:"^(web|app)_[a-f0-9]{8}$"`
+	Currency  string          `equals:"GBP"`
+	Items     []generatedItem `min:"1" max:"4"`
+}
+
+type generatedDocument struct {
+	Basket generatedBasket
+}
+
+type generatedNode struct {
+	Name     string
+	Children []generatedNode
+}
+
+type generatedNonEmptyNode struct {
+	Name     string
+	Children []generatedNonEmptyNode `min:"1"`
+}
+
+func TestGenerate(t *testing.T) {
+
+	Convey("Given an expected struct", t, func() {
+
+		Convey("Generated JSON documents should match it for any seed", func() {
+			for seed := int64(0); seed < 20; seed++ {
+				document, err := Generate(generatedBasket{}, seed)
+				So(err, ShouldBeNil)
+				So(document, ShouldMatchExpectedJSONResponse, generatedBasket{}, nil)
+			}
+		})
+
+		Convey("Generated XML documents should match it for any seed", func() {
+			for seed := int64(0); seed < 20; seed++ {
+				document, err := GenerateDocument(generatedDocument{}, "xml", seed)
+				So(err, ShouldBeNil)
+				So(document, ShouldMatchExpectedXMLResponse, generatedDocument{}, nil)
+			}
+		})
+
+		Convey("The same seed should give the same document", func() {
+			first, _ := Generate(generatedBasket{}, 42)
+			second, _ := Generate(generatedBasket{}, 42)
+			other, _ := Generate(generatedBasket{}, 43)
+			So(string(first), ShouldEqual, string(second))
+			So(string(first), ShouldNotEqual, string(other))
+		})
+
+		Convey("Generated values should use the field names and tags", func() {
+			document, _ := Generate(generatedBasket{}, 7)
+			var basket map[string]interface{}
+			So(json.Unmarshal(document, &basket), ShouldBeNil)
+			So(basket["currency"], ShouldEqual, "GBP")
+			items := basket["items"].([]interface{})
+			So(len(items), ShouldBeBetweenOrEqual, 1, 4)
+			item := items[0].(map[string]interface{})
+			So(item, ShouldContainKey, "id")
+			So(item, ShouldContainKey, "in_stock")
+			So(item["quantity"], ShouldEqual, float64(int(item["quantity"].(float64))))
+		})
+
+		Convey("Fields that refer to captured values can't be generated", func() {
+			_, err := Generate(struct {
+				ID string `equals:"${id}"`
+			}{}, 1)
+			So(err.Error(), ShouldEqual, "Can't generate a value for field: ID, as it refers to captured values")
+		})
+
+		Convey("Types that refer to themselves should end with empty arrays", func() {
+			document, err := Generate(generatedNode{}, 1)
+			So(err, ShouldBeNil)
+			So(document, ShouldMatchExpectedJSONResponse, generatedNode{}, nil)
+			So(string(document), ShouldContainSubstring, `"children": []`)
+
+			mutations, err := Mutations(generatedNode{}, "json", 1)
+			So(err, ShouldBeNil)
+			So(mutations, ShouldNotBeEmpty)
+		})
+
+		Convey("Arrays nested deeply in types that don't refer to themselves should have elements", func() {
+			var deep struct {
+				Levels [][][][][]struct {
+					Tags []string `min:"1"`
+				}
+			}
+			document, err := Generate(deep, 1)
+			So(err, ShouldBeNil)
+			So(document, ShouldMatchExpectedJSONResponse, deep, nil)
+			So(string(document), ShouldContainSubstring, `"tags": [`)
+			So(string(document), ShouldNotContainSubstring, `[]`)
+		})
+
+		Convey("Types that refer to themselves and need elements can't be generated", func() {
+			_, err := Generate(generatedNonEmptyNode{}, 1)
+			So(err.Error(), ShouldEqual, "Can't generate a value for field: Children, as its type refers to itself too deeply")
+			_, err = GenerateDocument(struct{ Root generatedNode }{}, "xml", 1)
+			So(err.Error(), ShouldEqual, "Can't generate a value for field: Children, as its type refers to itself too deeply")
+		})
+
+		Convey("XML documents need a single root element", func() {
+			_, err := GenerateDocument(generatedBasket{}, "xml", 1)
+			So(err.Error(), ShouldEqual, "An XML document must have a single root element, so the expected struct must have a single field")
+		})
+
+		Convey("Unknown formats should be refused", func() {
+			_, err := GenerateDocument(generatedBasket{}, "yaml", 1)
+			So(err.Error(), ShouldEqual, "Can't generate documents in the format: yaml")
+		})
+	})
+}
+
+func TestMutations(t *testing.T) {
+
+	Convey("Given an expected struct", t, func() {
+
+		for _, format := range []string{"json", "xml"} {
+			expected := interface{}(generatedBasket{})
+			if format == "xml" {
+				expected = generatedDocument{}
+			}
+
+			Convey("Every "+format+" mutation should fail to match it", func() {
+				mutations, err := Mutations(expected, format, 3)
+				So(err, ShouldBeNil)
+				So(len(mutations), ShouldBeGreaterThan, 10)
+				for _, mutation := range mutations {
+					So(matchesDocument(mutation.Document, expected, format), ShouldBeFalse)
+				}
+			})
+		}
+
+		Convey("Mutations should describe what was broken and where", func() {
+			mutations, _ := Mutations(generatedBasket{}, "json", 3)
+			described := make(map[string]bool)
+			for _, mutation := range mutations {
+				described[mutation.Path+": "+mutation.Description] = true
+			}
+			So(described, ShouldContainKey, "reference: doesn't match pattern")
+			So(described, ShouldContainKey, "currency: not an allowed value")
+			So(described, ShouldContainKey, "items: breaks 'min'")
+			So(described, ShouldContainKey, "items: breaks 'max'")
+			So(described, ShouldContainKey, "items[0].id: missing")
+			So(described, ShouldContainKey, "items[0].name: breaks 'max'")
+			So(described, ShouldContainKey, "items[0].price: breaks 'min'")
+			So(described, ShouldContainKey, "items[0].quantity: wrong type")
+			So(described, ShouldContainKey, "items[0].status: not an allowed value")
+		})
+	})
+}
+
+func TestPatternString(t *testing.T) {
+
+	Convey("Given a regular expression", t, func() {
+		random := rand.New(rand.NewSource(1))
+		for _, pattern := range []string{`^[A-Z]{3}-\d{4}$`, `^(web|app)_[a-f0-9]+$`, `^a.b?c*\w{2,}$`, `^[^a-z]{2}\.x$`} {
+			Convey("Random strings should match "+pattern, func() {
+				for i := 0; i < 20; i++ {
+					value, err := patternString(pattern, random)
+					So(err, ShouldBeNil)
+					So(regexp.MustCompile(pattern).MatchString(value), ShouldBeTrue)
+				}
+			})
+		}
+
+		Convey("Invalid expressions should be refused", func() {
+			_, err := patternString("[a-", nil)
+			So(err.Error(), ShouldEqual, "Received invalid regular expression: [a-")
+		})
+	})
+}
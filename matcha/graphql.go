@@ -0,0 +1,293 @@
+package matcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GraphQLOptions describe what we expect in a GraphQL response other than its data
+type GraphQLOptions struct {
+	CapturedValues CapturedValues // A map to hold values captured from the data, or nil
+	CaptureStore   *CaptureStore  // A store to merge captured values into, used instead of CapturedValues
+	Errors         []GraphQLError // The errors we expect, in any order. If there are none there must be no errors
+	Partial        bool           // Accept null in the data at the paths of errors or their parents, or no data at all
+	Extensions     interface{}    // The expected format of the extensions as a Struct, or nil to not check them
+}
+
+// GraphQLError describes an error we expect in a GraphQL response. Empty fields aren't checked.
+type GraphQLError struct {
+	Message   string        // A pattern the message must match
+	Path      []interface{} // The path of the field the error happened in, e.g. []interface{}{"booking", "seats", 0}
+	Locations []GraphQLLocation
+	Code      string // The 'code' in the error's extensions
+}
+
+// GraphQLLocation is a place in the query
+type GraphQLLocation struct {
+	Line   int
+	Column int
+}
+
+// ShouldMatchGraphQLResponse checks the envelope of a GraphQL response and matches its data against
+// the expected struct. Fields are named in errors by their path from 'data', so that they mirror the
+// query, e.g. 'data.booking.seats[0].price'.
+func ShouldMatchGraphQLResponse(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return fmt.Sprintf("ShouldMatchGraphQLResponse expects three arguments: the actual GraphQL response as a byte slice, the expected data format as a Struct, and GraphQLOptions")
+	}
+
+	actualJSON, ok := actual.([]byte)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be a byte slice")
+	}
+	expectedType := expectedTypeOf(expectedList[0])
+	var opts GraphQLOptions
+	if expectedList[1] != nil {
+		opts, ok = expectedList[1].(GraphQLOptions)
+		if !ok {
+			return fmt.Sprintf("Expected third argument to be GraphQLOptions or nil")
+		}
+	}
+	var response interface{}
+	if err := json.Unmarshal(actualJSON, &response); err != nil {
+		return fmt.Sprintf("Was not possible to unmarshal JSON into a Go struct. JSON data:\n%v", string(actualJSON))
+	}
+	envelope, ok := response.(map[string]interface{})
+	if !ok {
+		return fmt.Sprintf("Was expecting an object for the GraphQL response, but got %v", kindOf(response))
+	}
+
+	var errorList []string
+	var keys []string
+	for key := range envelope {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key != "data" && key != "errors" && key != "extensions" {
+			errorList = append(errorList, fmt.Sprintf("Unexpected field '%v' in GraphQL response", key))
+		}
+	}
+
+	actualErrors, equal := graphQLErrors(envelope["errors"])
+	if equal != success {
+		errorList = append(errorList, equal)
+	} else if equal := shouldMatchGraphQLErrors(actualErrors, opts.Errors); equal != success {
+		errorList = append(errorList, equal)
+	}
+
+	capturedValues := opts.CapturedValues
+	if opts.CaptureStore != nil {
+		capturedValues = make(CapturedValues)
+		defer opts.CaptureStore.Merge(capturedValues)
+	}
+	matcher := Matcher{format: "json", capturedValues: capturedValues, store: opts.CaptureStore, fullPaths: true}
+	data, hasData := envelope["data"]
+	switch {
+	case !hasData && len(actualErrors) > 0:
+		// Errors before the query could be run, such as syntax errors, mean there is no data
+	case data == nil && hasData && opts.Partial && len(actualErrors) > 0:
+		// Errors in a field that can't be null spread up to the data
+	default:
+		if !hasData {
+			errorList = append(errorList, "No field 'data' found in response")
+			break
+		}
+		if opts.Partial {
+			// A null spreads up to the nearest field that can be null, so it can be at any part of the path
+			matcher.nullablePaths = make(map[string]bool)
+			for _, actualError := range actualErrors {
+				elements, _ := actualError["path"].([]interface{})
+				for i := 1; i <= len(elements); i++ {
+					matcher.nullablePaths["data"+graphQLPath(elements[:i])] = true
+				}
+			}
+		}
+		if equal := matcher.shouldMatchExpectedField(data, expectedType, "data"); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if opts.Extensions != nil {
+		extensionsMatcher := Matcher{format: "json", fullPaths: true}
+		extensions, ok := envelope["extensions"]
+		if !ok {
+			errorList = append(errorList, "No field 'extensions' found in response")
+		} else if equal := extensionsMatcher.shouldMatchExpectedField(extensions, expectedTypeOf(opts.Extensions), "extensions"); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// graphQLErrors checks the shape of the errors in a response, which must each have a message and may
+// have a path, locations and extensions
+func graphQLErrors(errors interface{}) ([]map[string]interface{}, string) {
+	if errors == nil {
+		return nil, success
+	}
+	errorSlice, ok := errors.([]interface{})
+	if !ok {
+		return nil, fmt.Sprintf("Was expecting an array for field: errors")
+	}
+
+	var errorList []string
+	var actualErrors []map[string]interface{}
+	for i, element := range errorSlice {
+		actualError, ok := element.(map[string]interface{})
+		if !ok {
+			errorList = append(errorList, fmt.Sprintf("Was expecting an object for field: errors[%d], but got %v", i, kindOf(element)))
+			continue
+		}
+		if _, ok := actualError["message"].(string); !ok {
+			errorList = append(errorList, fmt.Sprintf("Expected a message string in errors[%d]", i))
+		}
+		if path, ok := actualError["path"]; ok && graphQLPath(path) == "" {
+			errorList = append(errorList, fmt.Sprintf("Expected a path of field names and indexes in errors[%d]", i))
+		}
+		if locations, ok := actualError["locations"]; ok && graphQLLocations(locations) == nil {
+			errorList = append(errorList, fmt.Sprintf("Expected locations with a line and column in errors[%d]", i))
+		}
+		actualErrors = append(actualErrors, actualError)
+	}
+	if errorList != nil {
+		return nil, strings.Join(errorList, "\n")
+	}
+	return actualErrors, success
+}
+
+// shouldMatchGraphQLErrors pairs each expected error with an actual error that matches it
+func shouldMatchGraphQLErrors(actualErrors []map[string]interface{}, expectedErrors []GraphQLError) string {
+	var errorList []string
+	paired := make([]bool, len(actualErrors))
+	for _, expectedError := range expectedErrors {
+		found := false
+		for i, actualError := range actualErrors {
+			if !paired[i] && expectedError.matches(actualError) {
+				paired[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			errorList = append(errorList, fmt.Sprintf("Expected a GraphQL error with %v (but there was none)!", expectedError))
+		}
+	}
+	for i, actualError := range actualErrors {
+		if !paired[i] {
+			errorList = append(errorList, fmt.Sprintf("Unexpected GraphQL error: %v", describeGraphQLError(actualError)))
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+func (e GraphQLError) matches(actualError map[string]interface{}) bool {
+	if e.Message != "" {
+		message, _ := actualError["message"].(string)
+		if matched, err := regexp.MatchString(e.Message, message); err != nil || !matched {
+			return false
+		}
+	}
+	if e.Path != nil && graphQLPath(e.Path) != graphQLPath(actualError["path"]) {
+		return false
+	}
+	if e.Locations != nil && !reflect.DeepEqual(e.Locations, graphQLLocations(actualError["locations"])) {
+		return false
+	}
+	if e.Code != "" && graphQLCode(actualError) != e.Code {
+		return false
+	}
+	return true
+}
+
+func (e GraphQLError) String() string {
+	var parts []string
+	if e.Message != "" {
+		parts = append(parts, fmt.Sprintf("message matching '%v'", e.Message))
+	}
+	if e.Path != nil {
+		parts = append(parts, fmt.Sprintf("path '%v'", strings.TrimPrefix(graphQLPath(e.Path), ".")))
+	}
+	if e.Locations != nil {
+		parts = append(parts, fmt.Sprintf("locations %v", e.Locations))
+	}
+	if e.Code != "" {
+		parts = append(parts, fmt.Sprintf("code '%v'", e.Code))
+	}
+	if parts == nil {
+		return "any message"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeGraphQLError(actualError map[string]interface{}) string {
+	description := fmt.Sprintf("'%v'", actualError["message"])
+	if path := graphQLPath(actualError["path"]); path != "" {
+		description += fmt.Sprintf(" (path: %v)", strings.TrimPrefix(path, "."))
+	}
+	if code := graphQLCode(actualError); code != "" {
+		description += fmt.Sprintf(" (code: %v)", code)
+	}
+	return description
+}
+
+// graphQLPath turns the path of an error into the form the matcher uses for full paths, e.g.
+// '.booking.seats[0]', or an empty string if it isn't a valid path
+func graphQLPath(path interface{}) string {
+	elements, ok := path.([]interface{})
+	if !ok {
+		return ""
+	}
+	var formatted strings.Builder
+	for _, element := range elements {
+		switch value := element.(type) {
+		case string:
+			formatted.WriteString("." + value)
+		case float64:
+			fmt.Fprintf(&formatted, "[%d]", int(value))
+		case int:
+			fmt.Fprintf(&formatted, "[%d]", value)
+		default:
+			return ""
+		}
+	}
+	return formatted.String()
+}
+
+func graphQLLocations(locations interface{}) []GraphQLLocation {
+	locationSlice, ok := locations.([]interface{})
+	if !ok {
+		return nil
+	}
+	parsed := []GraphQLLocation{}
+	for _, element := range locationSlice {
+		location, _ := element.(map[string]interface{})
+		line, lineOK := location["line"].(float64)
+		column, columnOK := location["column"].(float64)
+		if !lineOK || !columnOK {
+			return nil
+		}
+		parsed = append(parsed, GraphQLLocation{Line: int(line), Column: int(column)})
+	}
+	return parsed
+}
+
+func graphQLCode(actualError map[string]interface{}) string {
+	extensions, _ := actualError["extensions"].(map[string]interface{})
+	code, _ := extensions["code"].(string)
+	return code
+}
@@ -0,0 +1,163 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedGraphQLData struct {
+	Booking struct {
+		ID    string `json:"id" capture:"booking_id"`
+		Seats []struct {
+			Row   string  `json:"row"`
+			Price float64 `json:"price"`
+		} `json:"seats"`
+	} `json:"booking"`
+}
+
+type expectedGraphQLExtensions struct {
+	Cost float64 `json:"cost"`
+}
+
+func TestGraphQLMatching(t *testing.T) {
+
+	Convey("Given the expected data of a GraphQL response", t, func() {
+
+		var expected expectedGraphQLData
+
+		Convey("When the response has the data and no errors", func() {
+
+			response := []byte(`{"data": {"booking": {"id": "b-1", "seats": [{"row": "A", "price": 10}]}}, "extensions": {"cost": 3}}`)
+
+			Convey("It should return success and capture values from the data", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{
+					CapturedValues: capturedValues,
+					Extensions:     expectedGraphQLExtensions{},
+				})
+				So(success, ShouldEqual, "")
+				So(capturedValues["booking_id"], ShouldResemble, []interface{}{"b-1"})
+			})
+
+		})
+
+		Convey("When the data doesn't match", func() {
+
+			response := []byte(`{"data": {"booking": {"seats": [{"row": "A", "price": 10}, {"row": 2, "price": 10}]}}, "extensions": {}}`)
+
+			Convey("It should name fields by their path from the data", func() {
+				failure := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Extensions: expectedGraphQLExtensions{}})
+				So(failure, ShouldEqual, "No field 'data.booking.id' found in response\n"+
+					TypeErrorString("data.booking.seats[1].row", "string", "float64")+"\n"+
+					"No field 'extensions.cost' found in response")
+			})
+
+		})
+
+		Convey("When there are errors we don't expect", func() {
+
+			response := []byte(`{"data": null, "errors": [{"message": "Booking not found", "path": ["booking"], "locations": [{"line": 1, "column": 3}], "extensions": {"code": "NOT_FOUND"}}], "debug": true}`)
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchGraphQLResponse(response, expected, nil)
+				So(failure, ShouldEqual, "Unexpected field 'debug' in GraphQL response\n"+
+					"Unexpected GraphQL error: 'Booking not found' (path: booking) (code: NOT_FOUND)\n"+
+					"Was expecting an object for field: data, but got null")
+			})
+
+		})
+
+		Convey("When there are errors we expect with partial data", func() {
+
+			response := []byte(`{
+				"data": {"booking": {"id": "b-1", "seats": [{"row": "A", "price": 10}, null]}},
+				"errors": [{"message": "Seat 2 is unavailable", "path": ["booking", "seats", 1], "locations": [{"line": 3, "column": 5}], "extensions": {"code": "UNAVAILABLE"}}]
+			}`)
+			expectedError := GraphQLError{
+				Message:   "unavailable",
+				Path:      []interface{}{"booking", "seats", 1},
+				Locations: []GraphQLLocation{{Line: 3, Column: 5}},
+				Code:      "UNAVAILABLE",
+			}
+
+			Convey("It should return success if partial results are accepted", func() {
+				success := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Errors: []GraphQLError{expectedError}, Partial: true})
+				So(success, ShouldEqual, "")
+			})
+
+			Convey("It should report the null if partial results aren't accepted", func() {
+				failure := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Errors: []GraphQLError{expectedError}})
+				So(failure, ShouldEqual, "Was expecting an object for field: data.booking.seats[1], but got null")
+			})
+
+			Convey("It should report an expected error that doesn't match", func() {
+				expectedError.Code = "SOLD_OUT"
+				failure := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Errors: []GraphQLError{expectedError}, Partial: true})
+				So(failure, ShouldEqual, "Expected a GraphQL error with message matching 'unavailable', path 'booking.seats[1]', locations [{3 5}], code 'SOLD_OUT' (but there was none)!\n"+
+					"Unexpected GraphQL error: 'Seat 2 is unavailable' (path: booking.seats[1]) (code: UNAVAILABLE)")
+			})
+
+		})
+
+		Convey("When an error spreads a null up to a parent of its path", func() {
+
+			response := []byte(`{
+				"data": {"booking": {"id": "b-1", "seats": [null]}},
+				"errors": [{"message": "Price unavailable", "path": ["booking", "seats", 0, "price"]}]
+			}`)
+
+			Convey("It should accept the null if partial results are accepted", func() {
+				success := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Errors: []GraphQLError{{Message: "Price"}}, Partial: true})
+				So(success, ShouldEqual, "")
+			})
+
+			Convey("It should still report nulls outside the path", func() {
+				response := []byte(`{
+					"data": {"booking": {"id": null, "seats": [null]}},
+					"errors": [{"message": "Price unavailable", "path": ["booking", "seats", 0, "price"]}]
+				}`)
+				failure := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Errors: []GraphQLError{{Message: "Price"}}, Partial: true})
+				So(failure, ShouldEqual, TypeErrorString("data.booking.id", "string", "<nil>"))
+			})
+
+		})
+
+		Convey("When the expected extensions are a compiled plan", func() {
+
+			response := []byte(`{"data": {"booking": {"id": "b-1", "seats": []}}, "extensions": {"cost": "high"}}`)
+
+			Convey("It should match the extensions against the plan", func() {
+				failure := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Extensions: MustCompile(expectedGraphQLExtensions{})})
+				So(failure, ShouldEqual, TypeErrorString("extensions.cost", "float64", "string"))
+			})
+
+		})
+
+		Convey("When a request error means there is no data", func() {
+
+			response := []byte(`{"errors": [{"message": "Syntax error"}]}`)
+
+			Convey("It should only check the errors", func() {
+				success := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Errors: []GraphQLError{{Message: "^Syntax"}}})
+				So(success, ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When the errors have the wrong shape", func() {
+
+			response := []byte(`{"data": null, "errors": [{"path": [true]}, {"message": "x", "locations": [{"line": 1}]}]}`)
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchGraphQLResponse(response, expected, GraphQLOptions{Partial: true})
+				So(failure, ShouldStartWith, "Expected a message string in errors[0]\n"+
+					"Expected a path of field names and indexes in errors[0]\n"+
+					"Expected locations with a line and column in errors[1]\n")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,122 @@
+package matcha
+
+import (
+	"fmt"
+	"sort"
+)
+
+// HALLink can be used in expected structs to describe the links of a HAL resource
+type HALLink struct {
+	Href string `json:"href"`
+}
+
+// ShouldMatchHALDocument matches a HAL resource against the expected struct. Embedded resources are
+// matched as fields of the resource that embeds them, named after their relation, while links are
+// kept in '_links'.
+//
+// Every link must have an 'href', and an embedded resource with a self link must match the link of
+// the same relation in the resource that embeds it, if there is one.
+func ShouldMatchHALDocument(actual interface{}, expectedList ...interface{}) string {
+	document, opts, equal := shouldMatchHypermediaDocument("ShouldMatchHALDocument", actual, expectedList)
+	if equal != success {
+		return equal
+	}
+
+	var errorList []string
+	normalised := normaliseHALResource(document, "resource", opts, &errorList)
+	return shouldMatchNormalisedDocument(normalised, expectedList[0], "resource", opts, errorList)
+}
+
+func normaliseHALResource(resource map[string]interface{}, path string, opts HypermediaOptions, errorList *[]string) map[string]interface{} {
+	node := make(map[string]interface{})
+	for name, value := range resource {
+		if name != "_embedded" {
+			node[name] = value
+		}
+	}
+
+	links, hasLinks := resource["_links"].(map[string]interface{})
+	if _, ok := resource["_links"]; ok && !hasLinks {
+		*errorList = append(*errorList, fmt.Sprintf("Was expecting an object for field: %v._links", path))
+	}
+	for _, rel := range sortedRels(links) {
+		linkSlice, isSlice := links[rel].([]interface{})
+		if !isSlice {
+			linkSlice = []interface{}{links[rel]}
+		}
+		for _, link := range linkSlice {
+			linkObject, _ := link.(map[string]interface{})
+			if _, ok := linkObject["href"].(string); !ok {
+				*errorList = append(*errorList, fmt.Sprintf("Link '%v' of %v has no href", rel, path))
+			}
+		}
+	}
+	if opts.SelfLinks {
+		if _, ok := linkHref(links["self"]); !ok {
+			*errorList = append(*errorList, fmt.Sprintf("No self link found in %v", path))
+		}
+	}
+
+	embedded, _ := resource["_embedded"].(map[string]interface{})
+	for _, rel := range sortedRels(embedded) {
+		if _, ok := node[rel]; ok {
+			*errorList = append(*errorList, fmt.Sprintf("Embedded '%v' of %v has the same name as a property", rel, path))
+			continue
+		}
+		switch value := embedded[rel].(type) {
+		case map[string]interface{}:
+			node[rel] = normaliseHALResource(value, path+"."+rel, opts, errorList)
+			checkHALEmbeddedLink(links, rel, value, path, errorList)
+		case []interface{}:
+			var resources []interface{}
+			for i, element := range value {
+				embeddedResource, ok := element.(map[string]interface{})
+				if !ok {
+					*errorList = append(*errorList, fmt.Sprintf("Was expecting an object for field: %v.%v[%d], but got %v", path, rel, i, kindOf(element)))
+					continue
+				}
+				resources = append(resources, normaliseHALResource(embeddedResource, fmt.Sprintf("%v.%v[%d]", path, rel, i), opts, errorList))
+				checkHALEmbeddedLink(links, rel, embeddedResource, path, errorList)
+			}
+			node[rel] = resources
+		default:
+			*errorList = append(*errorList, fmt.Sprintf("Was expecting an object or array for field: %v._embedded.%v, but got %v", path, rel, kindOf(value)))
+		}
+	}
+	return node
+}
+
+// checkHALEmbeddedLink checks an embedded resource is the one its relation links to
+func checkHALEmbeddedLink(links map[string]interface{}, rel string, embedded map[string]interface{}, path string, errorList *[]string) {
+	relLinks, ok := links[rel]
+	if !ok {
+		return
+	}
+	embeddedLinks, _ := embedded["_links"].(map[string]interface{})
+	self, ok := linkHref(embeddedLinks["self"])
+	if !ok {
+		return
+	}
+	linkSlice, isSlice := relLinks.([]interface{})
+	if !isSlice {
+		linkSlice = []interface{}{relLinks}
+	}
+	var hrefs []string
+	for _, link := range linkSlice {
+		href, _ := linkHref(link)
+		if href == self {
+			return
+		}
+		hrefs = append(hrefs, href)
+	}
+	*errorList = append(*errorList, fmt.Sprintf("Embedded '%v' of %v is '%v', which isn't a '%v' link of it (links: %v)", rel, path, self, rel, hrefs))
+}
+
+func sortedRels(rels map[string]interface{}) []string {
+	var names []string
+	for name := range rels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,73 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedHALOrder struct {
+	Total float64 `json:"total"`
+	Links struct {
+		Self     HALLink `json:"self"`
+		Customer HALLink `json:"customer"`
+	} `json:"_links"`
+	Customer struct {
+		Name string `json:"name" capture:"customer"`
+	} `json:"customer"`
+	Items []struct {
+		Name string `json:"name"`
+	} `json:"items"`
+}
+
+const halOrder = `{
+	"total": 30,
+	"_links": {"self": {"href": "/orders/1"}, "customer": {"href": "/customers/7"}},
+	"_embedded": {
+		"customer": {"name": "Ann", "_links": {"self": {"href": "/customers/7"}}},
+		"items": [
+			{"name": "Ticket", "_links": {"self": {"href": "/items/1"}}},
+			{"name": "Programme", "_links": {"self": {"href": "/items/2"}}}
+		]
+	}
+}`
+
+func TestHALMatching(t *testing.T) {
+
+	Convey("Given the expected format of a HAL resource", t, func() {
+
+		var expected expectedHALOrder
+
+		Convey("When the resource matches", func() {
+
+			Convey("It should match embedded resources as fields", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchHALDocument([]byte(halOrder), expected, HypermediaOptions{CapturedValues: capturedValues, SelfLinks: true})
+				So(success, ShouldEqual, "")
+				So(capturedValues["customer"], ShouldResemble, []interface{}{"Ann"})
+			})
+
+		})
+
+		Convey("When links are broken", func() {
+
+			document := []byte(`{
+				"total": 30,
+				"_links": {"customer": {"href": "/customers/8"}, "help": {"url": "/help"}},
+				"_embedded": {"customer": {"name": "Ann", "_links": {"self": {"href": "/customers/7"}}}, "total": {}}
+			}`)
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchHALDocument(document, expected, HypermediaOptions{SelfLinks: true})
+				So(failure, ShouldStartWith, "Link 'help' of resource has no href\n"+
+					"No self link found in resource\n"+
+					"Embedded 'customer' of resource is '/customers/7', which isn't a 'customer' link of it (links: [/customers/8])\n"+
+					"Embedded 'total' of resource has the same name as a property\n"+
+					"No field 'resource._links.self' found in response\n")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,75 @@
+package matcha
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// HypermediaOptions control the checks made on JSON:API and HAL documents as well as matching them
+type HypermediaOptions struct {
+	CapturedValues CapturedValues // A map to hold captured values, or nil
+	CaptureStore   *CaptureStore  // A store to merge captured values into, used instead of CapturedValues
+	AllIncluded    bool           // JSON:API only: every relationship must point to an included resource
+	SelfLinks      bool           // Every resource must have a self link
+}
+
+// shouldMatchHypermediaDocument reads the arguments shared by the hypermedia assertions, and returns
+// the decoded document or an error string
+func shouldMatchHypermediaDocument(name string, actual interface{}, expectedList []interface{}) (map[string]interface{}, HypermediaOptions, string) {
+	var opts HypermediaOptions
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return nil, opts, fmt.Sprintf("%v expects three arguments: the actual JSON as a byte slice, the expected format as a Struct, and HypermediaOptions", name)
+	}
+
+	actualJSON, ok := actual.([]byte)
+	if !ok {
+		return nil, opts, fmt.Sprintf("Expected first argument to be a byte slice")
+	}
+	if expectedList[1] != nil {
+		opts, ok = expectedList[1].(HypermediaOptions)
+		if !ok {
+			return nil, opts, fmt.Sprintf("Expected third argument to be HypermediaOptions or nil")
+		}
+	}
+	var document interface{}
+	if err := json.Unmarshal(actualJSON, &document); err != nil {
+		return nil, opts, fmt.Sprintf("Was not possible to unmarshal JSON into a Go struct. JSON data:\n%v", string(actualJSON))
+	}
+	documentMap, ok := document.(map[string]interface{})
+	if !ok {
+		return nil, opts, fmt.Sprintf("Was expecting an object for the document, but got %v", kindOf(document))
+	}
+	return documentMap, opts, success
+}
+
+// shouldMatchNormalisedDocument matches a document once its envelope has been turned into plain objects
+func shouldMatchNormalisedDocument(actual interface{}, expected interface{}, rootName string, opts HypermediaOptions, errorList []string) string {
+	capturedValues := opts.CapturedValues
+	if opts.CaptureStore != nil {
+		capturedValues = make(CapturedValues)
+		defer opts.CaptureStore.Merge(capturedValues)
+	}
+	matcher := Matcher{format: "json", capturedValues: capturedValues, store: opts.CaptureStore, fullPaths: true}
+	if equal := matcher.shouldMatchExpectedField(actual, expectedTypeOf(expected), rootName); equal != success {
+		errorList = append(errorList, equal)
+	}
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// linkHref finds the URL of a link, which may be a string or an object with an 'href'
+func linkHref(link interface{}) (string, bool) {
+	switch value := link.(type) {
+	case string:
+		return value, true
+	case map[string]interface{}:
+		href, ok := value["href"].(string)
+		return href, ok
+	}
+	return "", false
+}
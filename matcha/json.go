@@ -3,14 +3,13 @@ package matcha
 import (
 	"encoding/json"
 	"fmt"
-	"reflect"
 )
 
 func ShouldMatchExpectedJSONResponse(actual interface{}, expectedList ...interface{}) string {
 
 	// Check number of arguments
-	if len(expectedList) != 2 {
-		return fmt.Sprintf("ShouldMatchExpectedJSONResponse expects three arguments: the actual JSON as a byte slice, the expected JSON format as a Struct, and a map to hold captured values")
+	if len(expectedList) < 2 {
+		return fmt.Sprintf("ShouldMatchExpectedJSONResponse expects three arguments: the actual JSON as a byte slice, the expected JSON format as a Struct, and a map to hold captured values, optionally followed by Options")
 	}
 
 	actualJSON, ok := actual.([]byte)
@@ -18,24 +17,30 @@ func ShouldMatchExpectedJSONResponse(actual interface{}, expectedList ...interfa
 		return fmt.Sprintf("Expected first argument to be a byte slice")
 	}
 	expectedResponseStruct := expectedList[0]
-	var capturedValues CapturedValues
-	if expectedList[1] != nil {
-		capturedValues, ok = expectedList[1].(CapturedValues)
-		if !ok {
-			return fmt.Sprintf("Expected third argument to be a map[string]interface or nil")
-		}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
 	}
+	defer store.Merge(capturedValues)
 	var actualResponse interface{}
 	err := json.Unmarshal(actualJSON, &actualResponse)
 	if err != nil {
 		return fmt.Sprintf("Was not possible to unmarshal JSON into a Go struct. JSON data:\n%v", string(actualJSON))
 	}
 
-	matcher := Matcher{format: "json", capturedValues: capturedValues}
+	matcher := Matcher{format: "json", capturedValues: capturedValues, store: store}
+	if equal := matcher.applyOptions(expectedList[2:]); equal != success {
+		return equal
+	}
+	if equal := matcher.capturePaths(actualResponse); equal != success {
+		return equal
+	}
 
-	result := matcher.shouldMatchExpectedField(actualResponse, reflect.TypeOf(expectedResponseStruct), "Result")
+	result := matcher.shouldMatchExpectedField(actualResponse, expectedTypeOf(expectedResponseStruct), "Result")
 	if result != success {
-		result = fmt.Sprintf("%v\nJSON data:\n%v", result, string(actualJSON))
+		if differences := matcher.describeDifferences(actualResponse, expectedTypeOf(expectedResponseStruct)); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
 	}
 	return result
 }
@@ -14,6 +14,10 @@ type expectedJSONNumber struct {
 	NumberField float64 `json:"number_field"`
 }
 
+type expectedJSONInteger struct {
+	IntegerField int `json:"integer_field"`
+}
+
 type expectedJSONBool struct {
 	BooleanField bool `json:"boolean_field"`
 }
@@ -45,6 +49,13 @@ type expectedURL struct {
 	URL string `json:"url" pattern:"https://.*"`
 }
 
+type expectedJSONConstraints struct {
+	Rating float64  `json:"rating" min:"0" max:"5"`
+	Code   string   `json:"code" min:"2" max:"3"`
+	Tags   []string `json:"tags" min:"1"`
+	Status string   `json:"status" enum:"open|closed"`
+}
+
 type expectedJSONCapture struct {
 	NumberField float64 `capture:"captured_number"`
 	StringField string  `capture:""`
@@ -153,6 +164,35 @@ func TestJSONNumberMatching(t *testing.T) {
 
 	})
 
+	Convey("Given an expected integer field", t, func() {
+
+		var expected expectedJSONInteger
+
+		Convey("When actual JSON is a whole number", func() {
+
+			fakeJSON := []byte(`{"integer_field": 25}`)
+
+			Convey("It should return success", func() {
+				success := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil)
+				So(success, ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When actual JSON has a fraction", func() {
+
+			fakeJSON := []byte(`{"integer_field": 25.2}`)
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil)
+				expectedErrString := TypeErrorString("integer_field", "int", "float64")
+				So(success, ShouldStartWith, expectedErrString)
+			})
+
+		})
+
+	})
+
 }
 
 func TestJSONBoolMatching(t *testing.T) {
@@ -413,3 +453,35 @@ func TestJSONPatternMatching(t *testing.T) {
 	})
 
 }
+
+func TestJSONConstraints(t *testing.T) {
+
+	Convey("Given expected fields with constraints", t, func() {
+
+		var expected expectedJSONConstraints
+
+		Convey("When actual values meet the constraints", func() {
+
+			fakeJSON := []byte(`{"rating": 5, "code": "GBP", "tags": ["new"], "status": "open"}`)
+
+			Convey("It should return success", func() {
+				success := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil)
+				So(success, ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When actual values don't meet the constraints", func() {
+
+			fakeJSON := []byte(`{"rating": 5.5, "code": "G", "tags": [], "status": "pending"}`)
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedJSONResponse(fakeJSON, expected, nil)
+				So(failure, ShouldEqual, "Rating: 5.5 is more than the maximum: 5\nCode: length 1 is less than the minimum: 2\nTags: length 0 is less than the minimum: 1\nStatus: 'pending' is not one of: open, closed")
+			})
+
+		})
+
+	})
+
+}
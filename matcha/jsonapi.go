@@ -0,0 +1,207 @@
+package matcha
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShouldMatchJSONAPIDocument matches the primary data of a JSON:API document against the expected
+// struct. Each resource is matched as a single object with its 'type', 'id', attributes, 'links' and
+// 'meta', and a field for each relationship that holds the related resource from 'data' or 'included',
+// so expected structs describe resources and relationships rather than the document's nesting.
+//
+// Every included resource must be reached by relationships from the primary data, and no resource
+// may be included twice.
+func ShouldMatchJSONAPIDocument(actual interface{}, expectedList ...interface{}) string {
+	document, opts, equal := shouldMatchHypermediaDocument("ShouldMatchJSONAPIDocument", actual, expectedList)
+	if equal != success {
+		return equal
+	}
+
+	if errors, ok := document["errors"].([]interface{}); ok {
+		var errorList []string
+		for _, element := range errors {
+			documentError, _ := element.(map[string]interface{})
+			errorList = append(errorList, fmt.Sprintf("%v %v: %v", documentError["status"], documentError["title"], documentError["detail"]))
+		}
+		return fmt.Sprintf("Expected a JSON:API document with data (but got errors):\n%v", strings.Join(errorList, "\n"))
+	}
+	data, ok := document["data"]
+	if !ok {
+		return "No field 'data' found in response"
+	}
+
+	resources := &jsonAPIResources{
+		nodes:         make(map[string]map[string]interface{}),
+		relationships: make(map[string][]string),
+		opts:          opts,
+	}
+	var primary []map[string]interface{}
+	dataSlice, isSlice := data.([]interface{})
+	if !isSlice && data != nil {
+		dataSlice = []interface{}{data}
+	}
+	for i, element := range dataSlice {
+		resource, ok := element.(map[string]interface{})
+		if !ok {
+			resources.errorList = append(resources.errorList, fmt.Sprintf("Was expecting an object for resource: data[%d], but got %v", i, kindOf(element)))
+			continue
+		}
+		primary = append(primary, resource)
+	}
+	included, _ := document["included"].([]interface{})
+	var includedResources []map[string]interface{}
+	for i, element := range included {
+		resource, ok := element.(map[string]interface{})
+		if !ok {
+			resources.errorList = append(resources.errorList, fmt.Sprintf("Was expecting an object for resource: included[%d], but got %v", i, kindOf(element)))
+			continue
+		}
+		includedResources = append(includedResources, resource)
+	}
+
+	// Create every node before linking them, as relationships can point in any direction
+	primaryKeys := resources.add(primary)
+	includedKeys := resources.add(includedResources)
+	for _, resource := range append(primary, includedResources...) {
+		resources.link(resource)
+	}
+	resources.checkLinkage(primaryKeys, includedKeys)
+
+	var normalised interface{}
+	if isSlice {
+		nodes := []interface{}{}
+		for _, key := range primaryKeys {
+			nodes = append(nodes, resources.nodes[key])
+		}
+		normalised = nodes
+	} else if len(primaryKeys) > 0 {
+		normalised = resources.nodes[primaryKeys[0]]
+	}
+	return shouldMatchNormalisedDocument(normalised, expectedList[0], "data", opts, resources.errorList)
+}
+
+// jsonAPIResources are the resources of a document, keyed by type and id
+type jsonAPIResources struct {
+	nodes         map[string]map[string]interface{}
+	relationships map[string][]string // The keys of the resources each resource is related to
+	opts          HypermediaOptions
+	errorList     []string
+}
+
+func jsonAPIKey(resourceType string, id string) string {
+	return fmt.Sprintf("'%v' '%v'", resourceType, id)
+}
+
+// add creates a node for each resource, with everything but its relationships
+func (r *jsonAPIResources) add(resources []map[string]interface{}) []string {
+	var keys []string
+	for _, resource := range resources {
+		resourceType, typeOK := resource["type"].(string)
+		id, idOK := resource["id"].(string)
+		if !typeOK || !idOK {
+			r.errorList = append(r.errorList, fmt.Sprintf("Expected every resource to have a 'type' and 'id' string (but got: type %v, id %v)!", kindOf(resource["type"]), kindOf(resource["id"])))
+			continue
+		}
+		key := jsonAPIKey(resourceType, id)
+		if _, ok := r.nodes[key]; ok {
+			r.errorList = append(r.errorList, fmt.Sprintf("Resource %v is in the document more than once", key))
+			continue
+		}
+
+		node := map[string]interface{}{"type": resourceType, "id": id}
+		attributes, _ := resource["attributes"].(map[string]interface{})
+		for name, value := range attributes {
+			node[name] = value
+		}
+		for _, member := range []string{"links", "meta"} {
+			if value, ok := resource[member]; ok {
+				node[member] = value
+			}
+		}
+		if r.opts.SelfLinks {
+			links, _ := resource["links"].(map[string]interface{})
+			if _, ok := linkHref(links["self"]); !ok {
+				r.errorList = append(r.errorList, fmt.Sprintf("Resource %v has no self link", key))
+			}
+		}
+		r.nodes[key] = node
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// link adds a field to a resource's node for each of its relationships
+func (r *jsonAPIResources) link(resource map[string]interface{}) {
+	resourceType, _ := resource["type"].(string)
+	id, _ := resource["id"].(string)
+	key := jsonAPIKey(resourceType, id)
+	node, ok := r.nodes[key]
+	if !ok {
+		return
+	}
+	relationships, _ := resource["relationships"].(map[string]interface{})
+	for _, name := range sortedRels(relationships) {
+		relationship, _ := relationships[name].(map[string]interface{})
+		data, ok := relationship["data"]
+		if !ok {
+			// A relationship can have only links, when its data wasn't requested
+			continue
+		}
+		switch linkage := data.(type) {
+		case nil:
+			node[name] = nil
+		case map[string]interface{}:
+			node[name] = r.resolve(key, name, linkage)
+		case []interface{}:
+			related := []interface{}{}
+			for _, element := range linkage {
+				identifier, _ := element.(map[string]interface{})
+				related = append(related, r.resolve(key, name, identifier))
+			}
+			node[name] = related
+		default:
+			r.errorList = append(r.errorList, fmt.Sprintf("Relationship '%v' of resource %v has invalid data", name, key))
+		}
+	}
+}
+
+// resolve finds the resource a relationship points to. If it isn't in the document the identifier
+// is used in its place.
+func (r *jsonAPIResources) resolve(key string, name string, identifier map[string]interface{}) interface{} {
+	resourceType, typeOK := identifier["type"].(string)
+	id, idOK := identifier["id"].(string)
+	if !typeOK || !idOK {
+		r.errorList = append(r.errorList, fmt.Sprintf("Relationship '%v' of resource %v has invalid data", name, key))
+		return identifier
+	}
+	relatedKey := jsonAPIKey(resourceType, id)
+	r.relationships[key] = append(r.relationships[key], relatedKey)
+	if node, ok := r.nodes[relatedKey]; ok {
+		return node
+	}
+	if r.opts.AllIncluded {
+		r.errorList = append(r.errorList, fmt.Sprintf("Relationship '%v' of resource %v points to resource %v, which isn't included", name, key, relatedKey))
+	}
+	return map[string]interface{}{"type": resourceType, "id": id}
+}
+
+// checkLinkage checks every included resource is reached by relationships from the primary data
+func (r *jsonAPIResources) checkLinkage(primaryKeys []string, includedKeys []string) {
+	reached := make(map[string]bool)
+	queue := append([]string{}, primaryKeys...)
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		if reached[key] {
+			continue
+		}
+		reached[key] = true
+		queue = append(queue, r.relationships[key]...)
+	}
+	for _, key := range includedKeys {
+		if !reached[key] {
+			r.errorList = append(r.errorList, fmt.Sprintf("Included resource %v isn't reached by any relationship from the primary data", key))
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedJSONAPIPerson struct {
+	Type string `json:"type" enum:"people"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type expectedJSONAPIArticle struct {
+	ID       string                `json:"id" capture:"article_id"`
+	Title    string                `json:"title"`
+	Author   expectedJSONAPIPerson `json:"author"`
+	Comments []struct {
+		Body   string                `json:"body"`
+		Author expectedJSONAPIPerson `json:"author"`
+	} `json:"comments"`
+}
+
+const jsonAPIArticles = `{
+	"data": [{
+		"type": "articles", "id": "1",
+		"attributes": {"title": "Rails is Omakase"},
+		"relationships": {
+			"author": {"data": {"type": "people", "id": "9"}},
+			"comments": {"data": [{"type": "comments", "id": "5"}]}
+		},
+		"links": {"self": "/articles/1"}
+	}],
+	"included": [
+		{"type": "people", "id": "9", "attributes": {"name": "Dan"}, "links": {"self": "/people/9"}},
+		{"type": "comments", "id": "5", "attributes": {"body": "First!"},
+			"relationships": {"author": {"data": {"type": "people", "id": "9"}}}, "links": {"self": "/comments/5"}}
+	]
+}`
+
+func TestJSONAPIMatching(t *testing.T) {
+
+	Convey("Given the expected resources of a JSON:API document", t, func() {
+
+		expected := []expectedJSONAPIArticle{}
+
+		Convey("When the document matches", func() {
+
+			Convey("It should resolve relationships to included resources", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchJSONAPIDocument([]byte(jsonAPIArticles), expected, HypermediaOptions{
+					CapturedValues: capturedValues,
+					AllIncluded:    true,
+					SelfLinks:      true,
+				})
+				So(success, ShouldEqual, "")
+				So(capturedValues["article_id"], ShouldResemble, []interface{}{"1"})
+			})
+
+		})
+
+		Convey("When a relationship points to a resource that isn't included", func() {
+
+			document := []byte(`{"data": {"type": "articles", "id": "1", "attributes": {"title": "Hi"},
+				"relationships": {"author": {"data": {"type": "people", "id": "9"}}, "comments": {"data": []}}}}`)
+
+			Convey("It should match the identifier unless every resource must be included", func() {
+				failure := ShouldMatchJSONAPIDocument(document, expectedJSONAPIArticle{}, nil)
+				So(failure, ShouldEqual, "No field 'data.author.name' found in response")
+
+				failure = ShouldMatchJSONAPIDocument(document, expectedJSONAPIArticle{}, HypermediaOptions{AllIncluded: true})
+				So(failure, ShouldStartWith, "Relationship 'author' of resource 'articles' '1' points to resource 'people' '9', which isn't included\n")
+			})
+
+		})
+
+		Convey("When included resources aren't linked or are duplicated", func() {
+
+			document := []byte(`{"data": null, "included": [
+				{"type": "people", "id": "9", "attributes": {"name": "Dan"}},
+				{"type": "people", "id": "9", "attributes": {"name": "Dan"}}
+			]}`)
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchJSONAPIDocument(document, expectedJSONAPIArticle{}, nil)
+				So(failure, ShouldStartWith, "Resource 'people' '9' is in the document more than once\n"+
+					"Included resource 'people' '9' isn't reached by any relationship from the primary data\n")
+			})
+
+		})
+
+		Convey("When the document has errors", func() {
+
+			document := []byte(`{"errors": [{"status": "404", "title": "Not Found", "detail": "No article 1"}]}`)
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchJSONAPIDocument(document, expectedJSONAPIArticle{}, nil)
+				So(failure, ShouldEqual, "Expected a JSON:API document with data (but got errors):\n404 Not Found: No article 1")
+			})
+
+		})
+
+	})
+
+}
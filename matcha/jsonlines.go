@@ -0,0 +1,106 @@
+package matcha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// JSONLinesOptions control how a stream of newline-delimited JSON records is matched
+type JSONLinesOptions struct {
+	MaxErrors  int // Stop reading after this many records fail to match, 0 for no limit
+	MinRecords int // The fewest records we expect
+	MaxRecords int // The most records we expect, 0 for no limit
+}
+
+// ShouldMatchJSONLines matches every record in a stream of newline-delimited JSON against the same
+// expected struct. The stream is read one line at a time, so it is never held in memory all at once.
+func ShouldMatchJSONLines(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 3 {
+		return fmt.Sprintf("ShouldMatchJSONLines expects four arguments: the actual JSON lines as an io.Reader, the expected format of each record as a Struct, a map to hold captured values, and JSONLinesOptions")
+	}
+
+	var reader io.Reader
+	switch actualLines := actual.(type) {
+	case io.Reader:
+		reader = actualLines
+	case []byte:
+		reader = bytes.NewReader(actualLines)
+	default:
+		return fmt.Sprintf("Expected first argument to be an io.Reader")
+	}
+	expectedType := expectedTypeOf(expectedList[0])
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+	var opts JSONLinesOptions
+	if expectedList[2] != nil {
+		opts, ok = expectedList[2].(JSONLinesOptions)
+		if !ok {
+			return fmt.Sprintf("Expected fourth argument to be JSONLinesOptions or nil")
+		}
+	}
+
+	matcher := Matcher{format: "json", capturedValues: capturedValues, store: store}
+	bufferedReader := bufio.NewReader(reader)
+	var errorList []string
+	lineNumber := 0
+	records := 0
+	for {
+		line, err := bufferedReader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			errorList = append(errorList, fmt.Sprintf("Was not possible to read line %d: %v", lineNumber+1, err))
+			break
+		}
+		if len(line) > 0 {
+			lineNumber++
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			records++
+			if opts.MaxRecords > 0 && records > opts.MaxRecords {
+				errorList = append(errorList, fmt.Sprintf("Expected at most %d records (but got more)!", opts.MaxRecords))
+				break
+			}
+			if equal := matcher.shouldMatchJSONLine(line, expectedType); equal != success {
+				errorList = append(errorList, fmt.Sprintf("Line %d: %v", lineNumber, equal))
+				if opts.MaxErrors > 0 && len(errorList) >= opts.MaxErrors {
+					errorList = append(errorList, fmt.Sprintf("Stopped after %d errors", len(errorList)))
+					break
+				}
+			}
+		}
+		if err == io.EOF {
+			if records < opts.MinRecords {
+				errorList = append(errorList, fmt.Sprintf("Expected at least %d records (but got: %d)!", opts.MinRecords, records))
+			}
+			break
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+func (m *Matcher) shouldMatchJSONLine(line []byte, expectedType reflect.Type) string {
+	var actualRecord interface{}
+	if err := json.Unmarshal(line, &actualRecord); err != nil {
+		return fmt.Sprintf("Was not possible to unmarshal JSON into a Go struct. JSON data:\n%v", string(bytes.TrimSpace(line)))
+	}
+	result := m.shouldMatchExpectedField(actualRecord, expectedType, "Result")
+	if result != success {
+		if differences := m.describeDifferences(actualRecord, expectedType); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package matcha
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedJSONLinesRecord struct {
+	ID    string  `json:"id" capture:""`
+	Price float64 `json:"price"`
+}
+
+// repeatingReader produces the same line forever, to check the stream isn't read to the end
+type repeatingReader struct {
+	line string
+}
+
+func (r *repeatingReader) Read(p []byte) (int, error) {
+	return copy(p, r.line), nil
+}
+
+func TestJSONLinesMatching(t *testing.T) {
+
+	Convey("Given an expected record format", t, func() {
+
+		var expected expectedJSONLinesRecord
+
+		Convey("When every record matches", func() {
+
+			stream := strings.NewReader("{\"id\": \"a\", \"price\": 1}\n\n{\"id\": \"b\", \"price\": 2.5}\n{\"id\": \"c\", \"price\": 3}")
+
+			Convey("It should return success and capture from every record", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchJSONLines(stream, expected, capturedValues, JSONLinesOptions{MinRecords: 3, MaxRecords: 3})
+				So(success, ShouldEqual, "")
+				So(capturedValues["id"], ShouldResemble, []interface{}{"a", "b", "c"})
+			})
+
+		})
+
+		Convey("When some records don't match", func() {
+
+			stream := strings.NewReader("{\"id\": \"a\", \"price\": 1}\n{\"id\": \"b\", \"price\": \"2.5\"}\n{bad}\n{\"price\": 3}\n")
+
+			Convey("It should report each error with its line number", func() {
+				failure := ShouldMatchJSONLines(stream, expected, nil, nil)
+				So(failure, ShouldStartWith, "Line 2: "+TypeErrorString("price", "float64", "string"))
+				So(failure, ShouldContainSubstring, "\nLine 3: Was not possible to unmarshal JSON into a Go struct. JSON data:\n{bad}\n")
+				So(failure, ShouldContainSubstring, "\nLine 4: No field 'id' found in response")
+			})
+
+			Convey("It should stop after the maximum number of errors", func() {
+				failure := ShouldMatchJSONLines(stream, expected, nil, JSONLinesOptions{MaxErrors: 1})
+				So(failure, ShouldEndWith, "\nStopped after 1 errors")
+				So(failure, ShouldNotContainSubstring, "Line 3")
+			})
+
+		})
+
+		Convey("When there are too few records", func() {
+
+			stream := strings.NewReader("{\"id\": \"a\", \"price\": 1}\n")
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchJSONLines(stream, expected, nil, JSONLinesOptions{MinRecords: 2})
+				So(failure, ShouldEqual, "Expected at least 2 records (but got: 1)!")
+			})
+
+		})
+
+		Convey("When there are too many records in an endless stream", func() {
+
+			stream := &repeatingReader{line: "{\"id\": \"a\", \"price\": 1}\n"}
+
+			Convey("It should stop reading once the maximum is passed", func() {
+				failure := ShouldMatchJSONLines(io.Reader(stream), expected, nil, JSONLinesOptions{MaxRecords: 1000})
+				So(failure, ShouldEqual, "Expected at most 1000 records (but got more)!")
+			})
+
+		})
+
+	})
+
+}
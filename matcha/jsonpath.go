@@ -0,0 +1,618 @@
+package matcha
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query finds the values at a JSONPath in a document, e.g. '$.items[?(@.price < 10)].name'. The
+// document can be JSON or XML as a byte slice or string, or already decoded into maps and slices.
+//
+// Paths can have child names ('.name' or ['name']), indexes ([0], [-1]), unions ([0,2]), slices
+// ([1:3]), wildcards (.* or [*]), recursive descent (..name) and filters that compare values
+// with ==, !=, <, <=, >, >= and =~ (a regular expression), combined with &&, || and !.
+func Query(document interface{}, path string) ([]interface{}, error) {
+	tree, err := diffDocument(document)
+	if err != nil {
+		return nil, fmt.Errorf("Was not possible to decode document: %v", err)
+	}
+	steps, err := compileJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateJSONPath(steps, tree, tree), nil
+}
+
+// jsonPathStep selects values from each node
+type jsonPathStep struct {
+	recursive bool     // Select from the node and all of its descendants
+	names     []string // Child names
+	indexes   []int    // Array indexes, which are counted from the end if negative
+	wildcard  bool     // Every child
+	slice     []*int   // Start, end and step of an array slice, each of which may be missing
+	filter    jsonPathExpression
+}
+
+type jsonPathParser struct {
+	path     string
+	position int
+}
+
+func compileJSONPath(path string) ([]jsonPathStep, error) {
+	parser := &jsonPathParser{path: strings.TrimSpace(path)}
+	if !parser.consume("$") {
+		return nil, fmt.Errorf("Received invalid JSONPath: %v (it should start with '$')", path)
+	}
+	steps, err := parser.steps()
+	if err == nil && parser.position < len(parser.path) {
+		err = parser.errorf("unexpected '%v'", string(parser.path[parser.position]))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return steps, nil
+}
+
+func (p *jsonPathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Received invalid JSONPath: %v (%v at position %d)", p.path, fmt.Sprintf(format, args...), p.position)
+}
+
+func (p *jsonPathParser) skipSpaces() {
+	for p.position < len(p.path) && p.path[p.position] == ' ' {
+		p.position++
+	}
+}
+
+func (p *jsonPathParser) consume(token string) bool {
+	if strings.HasPrefix(p.path[p.position:], token) {
+		p.position += len(token)
+		return true
+	}
+	return false
+}
+
+// steps parses steps until there are no more, which is the end of the path or of a path in a filter
+func (p *jsonPathParser) steps() ([]jsonPathStep, error) {
+	var steps []jsonPathStep
+	for p.position < len(p.path) {
+		var step jsonPathStep
+		switch {
+		case p.consume(".."):
+			step.recursive = true
+			if p.position < len(p.path) && p.path[p.position] == '[' {
+				p.position++
+				if err := p.bracket(&step); err != nil {
+					return nil, err
+				}
+			} else if err := p.dotName(&step); err != nil {
+				return nil, err
+			}
+		case p.consume("."):
+			if err := p.dotName(&step); err != nil {
+				return nil, err
+			}
+		case p.consume("["):
+			if err := p.bracket(&step); err != nil {
+				return nil, err
+			}
+		default:
+			return steps, nil
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (p *jsonPathParser) dotName(step *jsonPathStep) error {
+	if p.consume("*") {
+		step.wildcard = true
+		return nil
+	}
+	start := p.position
+	for p.position < len(p.path) && !strings.ContainsRune(".[ ()=!<>&|~", rune(p.path[p.position])) {
+		p.position++
+	}
+	if p.position == start {
+		return p.errorf("expected a name")
+	}
+	step.names = []string{p.path[start:p.position]}
+	return nil
+}
+
+// bracket parses what follows a '[', up to and including the ']'
+func (p *jsonPathParser) bracket(step *jsonPathStep) error {
+	p.skipSpaces()
+	switch {
+	case p.consume("*"):
+		step.wildcard = true
+	case p.consume("?"):
+		p.skipSpaces()
+		if !p.consume("(") {
+			return p.errorf("expected '(' after '?'")
+		}
+		filter, err := p.orExpression()
+		if err != nil {
+			return err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return p.errorf("expected ')' to end filter")
+		}
+		step.filter = filter
+	case p.position < len(p.path) && (p.path[p.position] == '\'' || p.path[p.position] == '"'):
+		for {
+			name, err := p.quoted()
+			if err != nil {
+				return err
+			}
+			step.names = append(step.names, name)
+			p.skipSpaces()
+			if !p.consume(",") {
+				break
+			}
+			p.skipSpaces()
+		}
+	default:
+		if err := p.indexes(step); err != nil {
+			return err
+		}
+	}
+	p.skipSpaces()
+	if !p.consume("]") {
+		return p.errorf("expected ']'")
+	}
+	return nil
+}
+
+func (p *jsonPathParser) indexes(step *jsonPathStep) error {
+	var parts []*int
+	for {
+		p.skipSpaces()
+		start := p.position
+		if p.position < len(p.path) && p.path[p.position] == '-' {
+			p.position++
+		}
+		for p.position < len(p.path) && p.path[p.position] >= '0' && p.path[p.position] <= '9' {
+			p.position++
+		}
+		var number *int
+		if p.position > start {
+			value, err := strconv.Atoi(p.path[start:p.position])
+			if err != nil {
+				return p.errorf("invalid index '%v'", p.path[start:p.position])
+			}
+			number = &value
+		}
+		parts = append(parts, number)
+		p.skipSpaces()
+		switch {
+		case p.consume(":"):
+			if step.indexes != nil || len(parts) == 3 {
+				return p.errorf("invalid slice")
+			}
+			continue
+		case p.consume(","):
+			if len(parts) > 1 || number == nil {
+				return p.errorf("invalid union")
+			}
+			step.indexes = append(step.indexes, *number)
+			parts = nil
+			continue
+		}
+		break
+	}
+	if len(parts) > 1 {
+		step.slice = parts
+		return nil
+	}
+	if parts[0] == nil {
+		return p.errorf("expected an index")
+	}
+	step.indexes = append(step.indexes, *parts[0])
+	return nil
+}
+
+func (p *jsonPathParser) quoted() (string, error) {
+	quote := p.path[p.position]
+	p.position++
+	var value strings.Builder
+	for p.position < len(p.path) {
+		c := p.path[p.position]
+		p.position++
+		switch {
+		case c == '\\' && p.position < len(p.path):
+			value.WriteByte(p.path[p.position])
+			p.position++
+		case c == quote:
+			return value.String(), nil
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func evaluateJSONPath(steps []jsonPathStep, root interface{}, node interface{}) []interface{} {
+	nodes := []interface{}{node}
+	for _, step := range steps {
+		var selected []interface{}
+		for _, current := range nodes {
+			if step.recursive {
+				for _, descendant := range jsonPathDescendants(current, nil) {
+					selected = append(selected, step.selectFrom(root, descendant)...)
+				}
+			} else {
+				selected = append(selected, step.selectFrom(root, current)...)
+			}
+		}
+		nodes = selected
+	}
+	return nodes
+}
+
+// jsonPathDescendants lists a node and everything in it, in document order
+func jsonPathDescendants(node interface{}, descendants []interface{}) []interface{} {
+	descendants = append(descendants, node)
+	for _, child := range jsonPathChildren(node) {
+		descendants = jsonPathDescendants(child, descendants)
+	}
+	return descendants
+}
+
+// jsonPathChildren lists the elements of an array, or the values of an object ordered by key
+func jsonPathChildren(node interface{}) []interface{} {
+	switch value := node.(type) {
+	case []interface{}:
+		return value
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		children := make([]interface{}, len(keys))
+		for i, key := range keys {
+			children[i] = value[key]
+		}
+		return children
+	}
+	return nil
+}
+
+func (s jsonPathStep) selectFrom(root interface{}, node interface{}) []interface{} {
+	switch {
+	case s.wildcard:
+		return jsonPathChildren(node)
+	case s.filter != nil:
+		var selected []interface{}
+		for _, child := range jsonPathChildren(node) {
+			if jsonPathTest(s.filter, root, child) {
+				selected = append(selected, child)
+			}
+		}
+		return selected
+	case s.names != nil:
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		var selected []interface{}
+		for _, name := range s.names {
+			if value, ok := object[name]; ok {
+				selected = append(selected, value)
+			}
+		}
+		return selected
+	}
+
+	array, ok := node.([]interface{})
+	if !ok {
+		return nil
+	}
+	var selected []interface{}
+	if s.slice != nil {
+		start, end, step := 0, len(array), 1
+		if len(s.slice) > 2 && s.slice[2] != nil {
+			step = *s.slice[2]
+		}
+		if step <= 0 {
+			return nil
+		}
+		if s.slice[0] != nil {
+			start = jsonPathIndex(*s.slice[0], len(array))
+		}
+		if s.slice[1] != nil {
+			end = jsonPathIndex(*s.slice[1], len(array))
+		}
+		for i := start; i < end && i < len(array); i += step {
+			selected = append(selected, array[i])
+		}
+		return selected
+	}
+	for _, index := range s.indexes {
+		if index < 0 {
+			index += len(array)
+		}
+		if index >= 0 && index < len(array) {
+			selected = append(selected, array[index])
+		}
+	}
+	return selected
+}
+
+func jsonPathIndex(index int, length int) int {
+	if index < 0 {
+		index += length
+	}
+	if index < 0 {
+		return 0
+	}
+	return index
+}
+
+// jsonPathExpression is part of a filter, evaluated against each node it filters
+type jsonPathExpression interface {
+	evaluate(root interface{}, node interface{}) interface{}
+}
+
+// jsonPathMissing is the value of a path in a filter that doesn't exist
+type jsonPathMissing struct{}
+
+type jsonPathLiteral struct{ value interface{} }
+
+type jsonPathQuery struct {
+	fromRoot bool
+	steps    []jsonPathStep
+}
+
+type jsonPathNot struct{ operand jsonPathExpression }
+
+type jsonPathBinary struct {
+	operator    string
+	left, right jsonPathExpression
+	pattern     *regexp.Regexp // For '=~' with a literal pattern
+}
+
+func (l jsonPathLiteral) evaluate(root interface{}, node interface{}) interface{} {
+	return l.value
+}
+
+func (q jsonPathQuery) evaluate(root interface{}, node interface{}) interface{} {
+	start := node
+	if q.fromRoot {
+		start = root
+	}
+	values := evaluateJSONPath(q.steps, root, start)
+	if len(values) == 0 {
+		return jsonPathMissing{}
+	}
+	return values[0]
+}
+
+func (n jsonPathNot) evaluate(root interface{}, node interface{}) interface{} {
+	return !jsonPathTest(n.operand, root, node)
+}
+
+func (b jsonPathBinary) evaluate(root interface{}, node interface{}) interface{} {
+	switch b.operator {
+	case "&&":
+		return jsonPathTest(b.left, root, node) && jsonPathTest(b.right, root, node)
+	case "||":
+		return jsonPathTest(b.left, root, node) || jsonPathTest(b.right, root, node)
+	}
+	left := b.left.evaluate(root, node)
+	right := b.right.evaluate(root, node)
+	if _, ok := left.(jsonPathMissing); ok {
+		return false
+	}
+	if _, ok := right.(jsonPathMissing); ok {
+		return false
+	}
+
+	switch b.operator {
+	case "==":
+		return jsonPathEqual(left, right)
+	case "!=":
+		return !jsonPathEqual(left, right)
+	case "=~":
+		text, ok := left.(string)
+		if !ok || b.pattern == nil {
+			return false
+		}
+		return b.pattern.MatchString(text)
+	}
+
+	leftNumber, leftIsNumber := numberValue(left)
+	rightNumber, rightIsNumber := numberValue(right)
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+	var comparison int
+	switch {
+	case leftIsNumber && rightIsNumber:
+		if leftNumber < rightNumber {
+			comparison = -1
+		} else if leftNumber > rightNumber {
+			comparison = 1
+		}
+	case leftIsString && rightIsString:
+		comparison = strings.Compare(leftString, rightString)
+	default:
+		return false
+	}
+	switch b.operator {
+	case "<":
+		return comparison < 0
+	case "<=":
+		return comparison <= 0
+	case ">":
+		return comparison > 0
+	}
+	return comparison >= 0
+}
+
+func jsonPathEqual(left interface{}, right interface{}) bool {
+	leftNumber, leftIsNumber := numberValue(left)
+	rightNumber, rightIsNumber := numberValue(right)
+	if leftIsNumber && rightIsNumber {
+		return leftNumber == rightNumber
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// jsonPathTest decides whether a node passes a filter. A path on its own checks the value exists,
+// whatever it is, so '[?(@.flag)]' selects nodes where flag is false too.
+func jsonPathTest(expression jsonPathExpression, root interface{}, node interface{}) bool {
+	if query, ok := expression.(jsonPathQuery); ok {
+		_, missing := query.evaluate(root, node).(jsonPathMissing)
+		return !missing
+	}
+	return jsonPathTruthy(expression.evaluate(root, node))
+}
+
+// jsonPathTruthy decides whether the result of a comparison or literal passes a filter
+func jsonPathTruthy(value interface{}) bool {
+	switch result := value.(type) {
+	case jsonPathMissing:
+		return false
+	case bool:
+		return result
+	}
+	return true
+}
+
+func (p *jsonPathParser) orExpression() (jsonPathExpression, error) {
+	left, err := p.andExpression()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpaces()
+		if !p.consume("||") {
+			return left, nil
+		}
+		right, err := p.andExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = jsonPathBinary{operator: "||", left: left, right: right}
+	}
+}
+
+func (p *jsonPathParser) andExpression() (jsonPathExpression, error) {
+	left, err := p.unaryExpression()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpaces()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		right, err := p.unaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = jsonPathBinary{operator: "&&", left: left, right: right}
+	}
+}
+
+func (p *jsonPathParser) unaryExpression() (jsonPathExpression, error) {
+	p.skipSpaces()
+	if p.consume("!") {
+		operand, err := p.unaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPathNot{operand: operand}, nil
+	}
+	if p.consume("(") {
+		expression, err := p.orExpression()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if !p.consume(")") {
+			return nil, p.errorf("expected ')'")
+		}
+		return expression, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	for _, operator := range []string{"==", "!=", "<=", ">=", "=~", "<", ">"} {
+		if !p.consume(operator) {
+			continue
+		}
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		comparison := jsonPathBinary{operator: operator, left: left, right: right}
+		if operator == "=~" {
+			literal, ok := right.(jsonPathLiteral)
+			pattern, isString := literal.value.(string)
+			if !ok || !isString {
+				return nil, p.errorf("expected a pattern after '=~'")
+			}
+			if comparison.pattern, err = regexp.Compile(pattern); err != nil {
+				return nil, p.errorf("invalid regular expression '%v'", pattern)
+			}
+		}
+		return comparison, nil
+	}
+	return left, nil
+}
+
+func (p *jsonPathParser) operand() (jsonPathExpression, error) {
+	p.skipSpaces()
+	if p.position >= len(p.path) {
+		return nil, p.errorf("unexpected end")
+	}
+	switch c := p.path[p.position]; {
+	case c == '@' || c == '$':
+		p.position++
+		steps, err := p.steps()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPathQuery{fromRoot: c == '$', steps: steps}, nil
+	case c == '\'' || c == '"':
+		value, err := p.quoted()
+		return jsonPathLiteral{value: value}, err
+	case c == '/':
+		// A regular expression in the /pattern/ form
+		end := strings.Index(p.path[p.position+1:], "/")
+		if end < 0 {
+			return nil, p.errorf("unterminated regular expression")
+		}
+		value := p.path[p.position+1 : p.position+1+end]
+		p.position += end + 2
+		return jsonPathLiteral{value: value}, nil
+	}
+	for _, keyword := range []struct {
+		name  string
+		value interface{}
+	}{{"true", true}, {"false", false}, {"null", nil}} {
+		if p.consume(keyword.name) {
+			return jsonPathLiteral{value: keyword.value}, nil
+		}
+	}
+
+	start := p.position
+	for p.position < len(p.path) && strings.ContainsRune("+-.0123456789eE", rune(p.path[p.position])) {
+		p.position++
+	}
+	number, err := strconv.ParseFloat(p.path[start:p.position], 64)
+	if err != nil {
+		p.position = start
+		return nil, p.errorf("expected a value")
+	}
+	return jsonPathLiteral{value: number}, nil
+}
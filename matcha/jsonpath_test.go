@@ -0,0 +1,99 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const jsonPathStore = `{
+	"store": {
+		"name": "Box office",
+		"items": [
+			{"name": "Stalls", "price": 45, "tags": ["view"]},
+			{"name": "Circle", "price": 30.5, "tags": []},
+			{"name": "Gallery", "price": 12, "discount": true},
+			{"name": "Programme", "price": 5}
+		],
+		"manager": {"name": "Sam"}
+	},
+	"limit": 20
+}`
+
+func TestQuery(t *testing.T) {
+
+	Convey("Given a JSON document", t, func() {
+
+		document := []byte(jsonPathStore)
+
+		Convey("When it is queried", func() {
+
+			Convey("It should select children, indexes, unions and slices", func() {
+				So(query(document, "$.store.name"), ShouldResemble, []interface{}{"Box office"})
+				So(query(document, "$['store']['items'][-1].name"), ShouldResemble, []interface{}{"Programme"})
+				So(query(document, "$.store.items[0,2].name"), ShouldResemble, []interface{}{"Stalls", "Gallery"})
+				So(query(document, "$.store.items[1:3].name"), ShouldResemble, []interface{}{"Circle", "Gallery"})
+				So(query(document, "$.store.items[::2].price"), ShouldResemble, []interface{}{45.0, 12.0})
+			})
+
+			Convey("It should select with wildcards and recursive descent", func() {
+				So(query(document, "$.store.items[*].price"), ShouldResemble, []interface{}{45.0, 30.5, 12.0, 5.0})
+				So(query(document, "$..name"), ShouldResemble, []interface{}{"Box office", "Stalls", "Circle", "Gallery", "Programme", "Sam"})
+				So(query(document, "$.store.manager.*"), ShouldResemble, []interface{}{"Sam"})
+			})
+
+			Convey("It should select with filters", func() {
+				So(query(document, "$.store.items[?(@.price < 20)].name"), ShouldResemble, []interface{}{"Gallery", "Programme"})
+				So(query(document, "$.store.items[?(@.discount)].name"), ShouldResemble, []interface{}{"Gallery"})
+				So(query(document, "$.store.items[?(@.price > $.limit && !(@.name == 'Circle'))].name"), ShouldResemble, []interface{}{"Stalls"})
+				So(query(document, "$.store.items[?(@.name =~ /^(S|P)/ || @.tags[0] == \"view\")].price"), ShouldResemble, []interface{}{45.0, 5.0})
+				So(query(document, "$..items[?(@.price >= 30.5)]..tags"), ShouldResemble, []interface{}{[]interface{}{"view"}, []interface{}{}})
+			})
+
+			Convey("It should select nodes where a filtered path exists, even when it is false", func() {
+				document := []byte(`[{"name": "a", "flag": false}, {"name": "b", "flag": true}, {"name": "c"}]`)
+				So(query(document, "$[?(@.flag)].name"), ShouldResemble, []interface{}{"a", "b"})
+				So(query(document, "$[?(!@.flag)].name"), ShouldResemble, []interface{}{"c"})
+				So(query(document, "$[?(@.flag == true)].name"), ShouldResemble, []interface{}{"b"})
+				So(query(document, "$[?(@.flag && @.name != 'b')].name"), ShouldResemble, []interface{}{"a"})
+			})
+
+			Convey("It should find nothing for paths that don't exist", func() {
+				So(query(document, "$.store.missing[0]"), ShouldBeNil)
+				So(query(document, "$.store.items[10]"), ShouldBeNil)
+			})
+
+		})
+
+		Convey("When a path is invalid", func() {
+
+			Convey("It should return an error", func() {
+				_, err := Query(document, "store.name")
+				So(err.Error(), ShouldEqual, "Received invalid JSONPath: store.name (it should start with '$')")
+				_, err = Query(document, "$.items[?(@.price < )]")
+				So(err.Error(), ShouldEqual, "Received invalid JSONPath: $.items[?(@.price < )] (expected a value at position 20)")
+				_, err = Query(document, "$.items[0")
+				So(err.Error(), ShouldEqual, "Received invalid JSONPath: $.items[0 (expected ']' at position 9)")
+			})
+
+		})
+
+	})
+
+	Convey("Given an XML document", t, func() {
+
+		document := []byte(`<order><item><price>5</price></item><item><price>7</price></item></order>`)
+
+		Convey("It should be queried in the same way", func() {
+			So(query(document, "$.order.item[*].price"), ShouldResemble, []interface{}{5.0, 7.0})
+		})
+
+	})
+
+}
+
+func query(document []byte, path string) []interface{} {
+	values, err := Query(document, path)
+	So(err, ShouldBeNil)
+	return values
+}
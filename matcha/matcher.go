@@ -2,11 +2,11 @@ package matcha
 
 import (
 	"fmt"
+	"math"
 	"reflect"
-	"regexp"
 	"strings"
+	"time"
 
-	snakecase "github.com/segmentio/go-snakecase"
 	. "github.com/smartystreets/goconvey/convey"
 )
 
@@ -14,24 +14,68 @@ import (
 type CapturedValues map[string][]interface{}
 
 type Matcher struct {
-	format         string // Should be 'json' or 'xml'
-	capturedValues CapturedValues
+	format          string // Should be 'json' or 'xml'
+	capturedValues  CapturedValues
+	fullPaths       bool            // Name fields in errors by their full path, e.g. 'data.results[2].date'
+	nullablePaths   map[string]bool // Full paths where a null is accepted whatever the expected type
+	pathCaptures    []pathCapture   // Values to capture by JSONPath, from Capture options
+	records         *CaptureRecords // Where to record captures with their context, from the RecordCaptures option
+	variables       CapturedValues  // Where '${name}' references are resolved, from the Variables option
+	store           *CaptureStore   // The store that capturedValues will be merged into, if one was given
+	storeSnapshot   CapturedValues  // The store's values, read when a variable is first resolved
+	workers         int             // How many elements of an array to match at once, from the Parallel option
+	maxErrors       int             // How many elements of an array can fail before it stops, from the MaxErrors option
+	aggregateErrors bool            // Whether to report errors that are the same for many elements once
+	path            []string        // Segments of the path to the value being matched, e.g. 'results', '[2]'
 }
 
 const (
 	success = "" // goconvey uses an empty string to signal success
 )
 
+var timeType = reflect.TypeOf(time.Time{})
+
 func TypeErrorString(fieldName string, expectedType string, actualType string) string {
 	return fmt.Sprintf("Expected '%v' to be: '%v' (but was: '%v')!", fieldName, expectedType, actualType)
 }
 
+// kindOf describes the kind of an actual value, which may be nil
+func kindOf(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return reflect.TypeOf(value).Kind().String()
+}
+
+// numberValue converts any of the number types a document can be decoded into to a float64
+func numberValue(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case float64:
+		return number, true
+	case int64:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	}
+	return 0, false
+}
+
+// fallbackFormats are formats whose field names are taken from the tag of another format if they
+// don't have their own, so that the same structs can be used for both
+var fallbackFormats = map[string]string{
+	"msgpack": "json",
+	"cbor":    "json",
+}
+
 func (m *Matcher) getFieldName(field reflect.StructField) string {
 	dataType := m.format
 	newFieldName, ok := field.Tag.Lookup(dataType)
+	if fallback, hasFallback := fallbackFormats[dataType]; !ok && hasFallback {
+		newFieldName, ok = field.Tag.Lookup(fallback)
+	}
 	if !ok {
 		// Get field name by looking at StructField name
-		newFieldName = snakecase.Snakecase(field.Name)
+		newFieldName = compiledField(field).snakeName
 	}
 	return newFieldName
 }
@@ -39,23 +83,22 @@ func (m *Matcher) getFieldName(field reflect.StructField) string {
 func (m *Matcher) shouldMatchPattern(actual interface{}, expectedField reflect.StructField) string {
 
 	// Check if we are expecting to match against a pattern for this field
-	pattern, ok := expectedField.Tag.Lookup("pattern")
-	if ok {
+	plan := compiledField(expectedField)
+	if pattern, ok := expectedField.Tag.Lookup("pattern"); ok {
 		// If so, check the expected field type is a string and the actual value is also a string
 		if expectedField.Type.Kind() != reflect.String {
-			return fmt.Sprintf("'pattern' tag cannot be used on non-string fields: %v", expectedField.Name)
+			return plan.patternError
 		}
 		actualString, isString := actual.(string)
 		if !isString {
 			return fmt.Sprintf("Expected a string value for field: %v but instead got %v", expectedField.Name, reflect.TypeOf(actual))
 		}
 
-		// If ok, then we try to match against the expected pattern
-		matched, err := regexp.MatchString(pattern, actualString)
-		if err != nil {
-			return fmt.Sprintf("Received invalid regular expression: %v", pattern)
+		// If ok, then we try to match against the compiled pattern
+		if plan.pattern == nil {
+			return plan.patternError
 		}
-		if !matched {
+		if !plan.pattern.MatchString(actualString) {
 			return fmt.Sprintf("%v: '%v' does not match expected pattern: %v", expectedField.Name, actualString, pattern)
 		}
 	}
@@ -65,7 +108,6 @@ func (m *Matcher) shouldMatchPattern(actual interface{}, expectedField reflect.S
 
 func (m *Matcher) shouldMatchExpectedArray(actual interface{}, expectedType reflect.Type, fieldName string) string {
 
-	var errorList []string
 	actualSlice, ok := actual.([]interface{})
 	if !ok {
 		// In XML, with the absence of a schema, it is impossible to distinguish between a single
@@ -80,24 +122,22 @@ func (m *Matcher) shouldMatchExpectedArray(actual interface{}, expectedType refl
 	// Get the expected type of each element in the array
 	expectedArrayElementType := expectedType.Elem()
 	// Compare each element in slice
-	for _, newActualField := range actualSlice {
-		// Array fields don't have names, so use something intuitive
-		newFieldName := fmt.Sprintf("%v array values", fieldName)
-		equal := m.shouldMatchExpectedField(newActualField, expectedArrayElementType, newFieldName)
-		if equal != success {
-			errorList = append(errorList, equal)
-		}
+	var results []string
+	if m.workers > 1 && len(actualSlice) > 1 {
+		results = m.shouldMatchExpectedElementsInParallel(actualSlice, expectedArrayElementType, fieldName)
+	} else {
+		results = m.shouldMatchExpectedElements(actualSlice, expectedArrayElementType, fieldName)
 	}
 
-	if errorList != nil {
+	if errorList := m.arrayErrors(results, len(actualSlice), fieldName); errorList != nil {
 		return strings.Join(errorList, "\n")
 	}
 	return success
 }
 
-func (m *Matcher) captureValue(expectedField reflect.StructField, value interface{}) {
+func (m *Matcher) captureValue(expectedField reflect.StructField, value interface{}, parent map[string]interface{}) {
 	// If we're not interested in capturing any values, just return
-	if m.capturedValues == nil {
+	if m.capturedValues == nil && m.records == nil {
 		return
 	}
 
@@ -106,27 +146,59 @@ func (m *Matcher) captureValue(expectedField reflect.StructField, value interfac
 		if captureKey == "" {
 			captureKey = m.getFieldName(expectedField)
 		}
-		m.capturedValues[captureKey] = append(m.capturedValues[captureKey], value)
+		if m.capturedValues != nil {
+			m.capturedValues[captureKey] = append(m.capturedValues[captureKey], value)
+		}
+		if m.records != nil {
+			*m.records = append(*m.records, m.captureRecord(captureKey, value, parent))
+		}
 	}
 }
 
-func (m *Matcher) shouldMatchExpectedStructField(actual map[string]interface{}, expectedField reflect.StructField) string {
+// enterPath and leavePath keep track of where in the document the matcher is
+func (m *Matcher) enterPath(segment string) {
+	m.path = append(m.path, segment)
+}
+
+func (m *Matcher) leavePath() {
+	m.path = m.path[:len(m.path)-1]
+}
+
+func (m *Matcher) shouldMatchExpectedStructField(actual map[string]interface{}, expectedField reflect.StructField, parentName string) string {
 
 	fieldName := m.getFieldName(expectedField)
 	expectedFieldType := expectedField.Type
 	actualField, ok := actual[fieldName]
+	m.enterPath("." + fieldName)
+	defer m.leavePath()
+	if m.fullPaths {
+		fieldName = parentName + "." + fieldName
+	}
 	if !ok {
 		return fmt.Sprintf("No field '%v' found in response", fieldName)
 	}
+	if actualField == nil && m.nullablePaths[fieldName] {
+		return success
+	}
 
-	m.captureValue(expectedField, actualField)
+	m.captureValue(expectedField, actualField, actual)
 
 	equal := m.shouldMatchPattern(actualField, expectedField)
 	if equal != success {
 		return equal
 	}
 
-	return m.shouldMatchExpectedField(actualField, expectedFieldType, fieldName)
+	equal = m.shouldMatchExpectedField(actualField, expectedFieldType, fieldName)
+	if equal != success {
+		return equal
+	}
+
+	equal = m.shouldMatchConstraints(actualField, expectedField)
+	if equal != success {
+		return equal
+	}
+
+	return m.shouldMatchVariables(actualField, expectedField)
 }
 
 func (m *Matcher) shouldMatchExpectedObject(actual interface{}, expectedType reflect.Type, fieldName string) string {
@@ -134,12 +206,12 @@ func (m *Matcher) shouldMatchExpectedObject(actual interface{}, expectedType ref
 	var errorList []string
 	actualMap, ok := actual.(map[string]interface{})
 	if !ok {
-		return fmt.Sprintf("Was expecting an object for field: %v, but got %v", fieldName, reflect.TypeOf(actual).Kind())
+		return fmt.Sprintf("Was expecting an object for field: %v, but got %v", fieldName, kindOf(actual))
 	}
 	for i := 0; i < expectedType.NumField(); i++ {
 
 		newField := expectedType.Field(i)
-		equal := m.shouldMatchExpectedStructField(actualMap, newField)
+		equal := m.shouldMatchExpectedStructField(actualMap, newField, fieldName)
 		if equal != success {
 			errorList = append(errorList, equal)
 		}
@@ -158,19 +230,38 @@ func (m *Matcher) shouldMatchExpectedField(actual interface{}, expectedType refl
 	switch expectedKind {
 	case reflect.String:
 		if equal := ShouldEqual(expectedType, actualType); equal != success {
-			return TypeErrorString(fieldName, expectedType.String(), actualType.String())
+			return TypeErrorString(fieldName, expectedType.String(), fmt.Sprintf("%v", actualType))
 		}
 	case reflect.Float64:
-		if equal := ShouldEqual(expectedType, actualType); equal != success {
-			return TypeErrorString(fieldName, expectedType.String(), actualType.String())
+		// Binary formats such as MessagePack and CBOR decode whole numbers as integers
+		if _, ok := numberValue(actual); !ok {
+			return TypeErrorString(fieldName, expectedType.String(), fmt.Sprintf("%v", actualType))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number, ok := numberValue(actual); !ok || number != math.Trunc(number) {
+			return TypeErrorString(fieldName, expectedType.String(), fmt.Sprintf("%v", actualType))
 		}
 	case reflect.Bool:
 		if equal := ShouldEqual(expectedType, actualType); equal != success {
-			return TypeErrorString(fieldName, expectedType.String(), actualType.String())
+			return TypeErrorString(fieldName, expectedType.String(), fmt.Sprintf("%v", actualType))
 		}
 	case reflect.Slice:
+		// Binary formats have byte strings, which are expected as a []byte
+		if expectedType.Elem().Kind() == reflect.Uint8 {
+			if _, ok := actual.([]byte); ok {
+				return success
+			}
+		}
 		return m.shouldMatchExpectedArray(actual, expectedType, fieldName)
 	case reflect.Struct:
+		// Datetimes are decoded as a time.Time by formats that have them, such as TOML
+		if expectedType == timeType {
+			if equal := ShouldEqual(expectedType, actualType); equal != success {
+				return TypeErrorString(fieldName, expectedType.String(), fmt.Sprintf("%v", actualType))
+			}
+			return success
+		}
 		// Type is a JSON object
 		return m.shouldMatchExpectedObject(actual, expectedType, fieldName)
 	default:
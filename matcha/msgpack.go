@@ -0,0 +1,202 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ShouldMatchExpectedMsgPack matches a MessagePack document. Integers are decoded as an int64 (or a
+// uint64 if they are too big), binary data as a []byte and timestamps as a time.Time. Field names
+// come from 'msgpack' tags, or from 'json' tags so that the same structs can be used for both.
+func ShouldMatchExpectedMsgPack(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) < 2 {
+		return fmt.Sprintf("ShouldMatchExpectedMsgPack expects three arguments: the actual MessagePack as a byte slice, the expected format as a Struct, and a map to hold captured values, optionally followed by Options")
+	}
+
+	actualMsgPack, ok := actual.([]byte)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be a byte slice")
+	}
+	actualResponse, err := decodeMsgPack(actualMsgPack)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to decode MessagePack: %v", err)
+	}
+	return shouldMatchBinaryDocument("msgpack", "MessagePack", actualResponse, expectedList)
+}
+
+func decodeMsgPack(data []byte) (interface{}, error) {
+	reader := &binaryReader{data: data}
+	value, err := reader.msgPackValue()
+	if err != nil {
+		return nil, err
+	}
+	if reader.offset != len(data) {
+		return nil, fmt.Errorf("unexpected data after the end of the document at byte %d", reader.offset)
+	}
+	return value, nil
+}
+
+func (r *binaryReader) msgPackValue() (interface{}, error) {
+	start := r.offset
+	format, err := r.uint(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case format <= 0x7f:
+		return int64(format), nil
+	case format >= 0xe0:
+		return int64(int8(format)), nil
+	case format >= 0x80 && format <= 0x8f:
+		return r.msgPackMap(format & 0x0f)
+	case format >= 0x90 && format <= 0x9f:
+		return r.msgPackArray(format & 0x0f)
+	case format >= 0xa0 && format <= 0xbf:
+		return r.msgPackString(format & 0x1f)
+	}
+
+	switch format {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		length, err := r.uint(1 << (format - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgPackBinary(length)
+	case 0xc7, 0xc8, 0xc9:
+		length, err := r.uint(1 << (format - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgPackExtension(length)
+	case 0xca:
+		bits, err := r.uint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := r.uint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		value, err := r.uint(1 << (format - 0xcc))
+		return unsignedValue(value), err
+	case 0xd0:
+		value, err := r.uint(1)
+		return int64(int8(value)), err
+	case 0xd1:
+		value, err := r.uint(2)
+		return int64(int16(value)), err
+	case 0xd2:
+		value, err := r.uint(4)
+		return int64(int32(value)), err
+	case 0xd3:
+		value, err := r.uint(8)
+		return int64(value), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return r.msgPackExtension(1 << (format - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		length, err := r.uint(1 << (format - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgPackString(length)
+	case 0xdc, 0xdd:
+		count, err := r.uint(2 << (format - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgPackArray(count)
+	case 0xde, 0xdf:
+		count, err := r.uint(2 << (format - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgPackMap(count)
+	}
+	return nil, fmt.Errorf("unknown format 0x%x at byte %d", format, start)
+}
+
+func (r *binaryReader) msgPackString(length uint64) (interface{}, error) {
+	value, err := r.next(length)
+	return string(value), err
+}
+
+func (r *binaryReader) msgPackBinary(length uint64) (interface{}, error) {
+	value, err := r.next(length)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, value...), nil
+}
+
+func (r *binaryReader) msgPackArray(count uint64) (interface{}, error) {
+	if err := r.checkCount(count); err != nil {
+		return nil, err
+	}
+	array := make([]interface{}, count)
+	for i := range array {
+		value, err := r.msgPackValue()
+		if err != nil {
+			return nil, err
+		}
+		array[i] = value
+	}
+	return array, nil
+}
+
+func (r *binaryReader) msgPackMap(count uint64) (interface{}, error) {
+	if err := r.checkCount(count); err != nil {
+		return nil, err
+	}
+	object := make(map[string]interface{}, count)
+	for i := uint64(0); i < count; i++ {
+		key, err := r.msgPackValue()
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.msgPackValue()
+		if err != nil {
+			return nil, err
+		}
+		object[mapKey(key)] = value
+	}
+	return object, nil
+}
+
+// msgPackExtension decodes timestamps, the only predefined extension type. Other extensions are
+// kept as binary data.
+func (r *binaryReader) msgPackExtension(length uint64) (interface{}, error) {
+	extensionType, err := r.uint(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := r.next(length)
+	if err != nil {
+		return nil, err
+	}
+	if int8(extensionType) != -1 {
+		return append([]byte{}, data...), nil
+	}
+
+	timestamp := &binaryReader{data: data}
+	switch length {
+	case 4:
+		seconds, _ := timestamp.uint(4)
+		return time.Unix(int64(seconds), 0).UTC(), nil
+	case 8:
+		value, _ := timestamp.uint(8)
+		return time.Unix(int64(value&(1<<34-1)), int64(value>>34)).UTC(), nil
+	case 12:
+		nanoseconds, _ := timestamp.uint(4)
+		seconds, _ := timestamp.uint(8)
+		return time.Unix(int64(seconds), int64(nanoseconds)).UTC(), nil
+	}
+	return nil, fmt.Errorf("invalid timestamp length %d", length)
+}
@@ -0,0 +1,119 @@
+package matcha
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedBinaryBooking struct {
+	ID       string    `json:"id" capture:""`
+	Seats    int       `json:"seats" min:"1"`
+	Price    float64   `json:"price"`
+	Ticket   []byte    `json:"ticket"`
+	Created  time.Time `json:"created"`
+	Ratings  []float64 `json:"ratings"`
+	ByNumber struct {
+		First string `msgpack:"1" cbor:"1"`
+	} `json:"by_number"`
+}
+
+// msgPackBooking is a MessagePack document with ints, floats, binary data, a timestamp and integer map keys
+var msgPackBooking = []byte{
+	0x87,
+	0xa2, 'i', 'd', 0xa3, 'b', '-', '1',
+	0xa5, 's', 'e', 'a', 't', 's', 0x02,
+	0xa5, 'p', 'r', 'i', 'c', 'e', 0xcb, 0x40, 0x29, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xa6, 't', 'i', 'c', 'k', 'e', 't', 0xc4, 0x02, 0xde, 0xad,
+	0xa7, 'c', 'r', 'e', 'a', 't', 'e', 'd', 0xd6, 0xff, 0x5a, 0x4b, 0x1c, 0x40,
+	0xa7, 'r', 'a', 't', 'i', 'n', 'g', 's', 0x93, 0x05, 0xd0, 0xfe, 0xca, 0x3f, 0xc0, 0x00, 0x00,
+	0xa9, 'b', 'y', '_', 'n', 'u', 'm', 'b', 'e', 'r', 0x81, 0x01, 0xa3, 'o', 'n', 'e',
+}
+
+func TestMsgPackDecoding(t *testing.T) {
+
+	Convey("Given a MessagePack document", t, func() {
+
+		Convey("When it is decoded", func() {
+
+			decoded, err := decodeMsgPack(msgPackBooking)
+
+			Convey("It should keep integers, binary data and timestamps apart", func() {
+				So(err, ShouldBeNil)
+				So(decoded, ShouldResemble, map[string]interface{}{
+					"id":        "b-1",
+					"seats":     int64(2),
+					"price":     12.5,
+					"ticket":    []byte{0xde, 0xad},
+					"created":   time.Date(2018, 1, 2, 5, 44, 32, 0, time.UTC),
+					"ratings":   []interface{}{int64(5), int64(-2), 1.5},
+					"by_number": map[string]interface{}{"1": "one"},
+				})
+			})
+
+		})
+
+		Convey("When it is cut short", func() {
+
+			_, err := decodeMsgPack(msgPackBooking[:20])
+
+			Convey("It should return an error", func() {
+				So(err.Error(), ShouldEqual, "unexpected end of data at byte 16")
+			})
+
+		})
+
+	})
+
+}
+
+func TestMsgPackMatching(t *testing.T) {
+
+	Convey("Given an expected format", t, func() {
+
+		var expected expectedBinaryBooking
+
+		Convey("When the document matches", func() {
+
+			Convey("It should return success", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedMsgPack(msgPackBooking, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["id"], ShouldResemble, []interface{}{"b-1"})
+			})
+
+			Convey("It should apply Options", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedMsgPack(msgPackBooking, expected, capturedValues, Capture("$.by_number.1", "number"))
+				So(success, ShouldEqual, "")
+				So(capturedValues["number"], ShouldResemble, []interface{}{"one"})
+			})
+
+		})
+
+		Convey("When the document doesn't match", func() {
+
+			// {"id": 7, "seats": 1.5}
+			document := []byte{0x82, 0xa2, 'i', 'd', 0x07, 0xa5, 's', 'e', 'a', 't', 's', 0xca, 0x3f, 0xc0, 0x00, 0x00}
+
+			Convey("It should show the document as JSON", func() {
+				failure := ShouldMatchExpectedMsgPack(document, expected, nil)
+				So(failure, ShouldStartWith, TypeErrorString("id", "string", "int64")+"\n"+TypeErrorString("seats", "int", "float64")+"\n")
+				So(failure, ShouldEndWith, "MessagePack data as JSON:\n{\n  \"id\": 7,\n  \"seats\": 1.5\n}")
+			})
+
+		})
+
+		Convey("When the document isn't MessagePack", func() {
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedMsgPack([]byte{0xc1}, expected, nil)
+				So(failure, ShouldEqual, "Was not possible to decode MessagePack: unknown format 0xc1 at byte 0")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,212 @@
+package matcha
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// MultipartExpectation describes the parts we expect in a multipart/form-data body
+type MultipartExpectation struct {
+	Fields interface{}       // The expected format of the parts that aren't files as a Struct with 'form' tags, or nil
+	Parts  []PartExpectation // Parts we expect, usually files
+	Strict bool              // Every part must be expected, either as a field or a part
+}
+
+// PartExpectation describes every part with the same name. Empty fields aren't checked.
+type PartExpectation struct {
+	Name        string
+	ContentType string      // A pattern the content type must match
+	Filename    string      // A pattern the filename must match
+	MinSize     int64       // The fewest bytes we expect
+	MaxSize     int64       // The most bytes we expect
+	Body        interface{} // The expected format of a JSON or XML part as a Struct
+}
+
+var (
+	jsonContentType = regexp.MustCompile(`^application/(.+\+)?json$`)
+	xmlContentType  = regexp.MustCompile(`^(application|text)/(.+\+)?xml$`)
+)
+
+type multipartPart struct {
+	name        string
+	filename    string
+	contentType string
+	body        []byte
+}
+
+// ShouldMatchMultipart matches a multipart/form-data body. The body can be an *http.Request, whose
+// Content-Type header gives the boundary, or a byte slice that starts with the boundary. Parts
+// with a JSON or XML content type are matched like JSON and XML responses.
+func ShouldMatchMultipart(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return fmt.Sprintf("ShouldMatchMultipart expects three arguments: the actual body as an *http.Request or byte slice, the expected parts as a MultipartExpectation, and a map to hold captured values")
+	}
+
+	var body []byte
+	var boundary string
+	switch actualBody := actual.(type) {
+	case *http.Request:
+		_, params, err := mime.ParseMediaType(actualBody.Header.Get("Content-Type"))
+		if err != nil || params["boundary"] == "" {
+			return fmt.Sprintf("Expected a multipart Content-Type with a boundary (but was: '%v')!", actualBody.Header.Get("Content-Type"))
+		}
+		boundary = params["boundary"]
+		if actualBody.Body != nil {
+			body, err = ioutil.ReadAll(actualBody.Body)
+			if err != nil {
+				return fmt.Sprintf("Was not possible to read the request body: %v", err)
+			}
+			// Put the body back so the request can still be handled
+			actualBody.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+	case []byte:
+		body = actualBody
+		firstLine := strings.SplitN(string(actualBody), "\n", 2)[0]
+		boundary = strings.TrimPrefix(strings.TrimSpace(firstLine), "--")
+	default:
+		return fmt.Sprintf("Expected first argument to be an *http.Request or byte slice")
+	}
+	expectation, ok := expectedList[0].(MultipartExpectation)
+	if !ok {
+		return fmt.Sprintf("Expected second argument to be a MultipartExpectation")
+	}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+
+	parts, err := readMultipart(body, boundary)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to read multipart body: %v", err)
+	}
+
+	var errorList []string
+	expectedNames := make(map[string]bool)
+	for _, partExpectation := range expectation.Parts {
+		expectedNames[partExpectation.Name] = true
+		found := false
+		for _, part := range parts {
+			if part.name != partExpectation.Name {
+				continue
+			}
+			found = true
+			if equal := partExpectation.shouldMatchPart(part, capturedValues); equal != success {
+				errorList = append(errorList, fmt.Sprintf("Part '%v': %v", part.name, equal))
+			}
+		}
+		if !found {
+			errorList = append(errorList, fmt.Sprintf("No part '%v' found in multipart body", partExpectation.Name))
+		}
+	}
+
+	// Parts that aren't files are form fields
+	fields := url.Values{}
+	for _, part := range parts {
+		if part.filename == "" && !expectedNames[part.name] {
+			fields.Add(part.name, string(part.body))
+		}
+	}
+	if expectation.Fields != nil {
+		if equal := ShouldMatchExpectedForm(fields, expectation.Fields, capturedValues); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if expectation.Strict {
+		matcher := Matcher{format: "form"}
+		if fieldsType := expectedTypeOf(expectation.Fields); fieldsType != nil && fieldsType.Kind() == reflect.Struct {
+			for i := 0; i < fieldsType.NumField(); i++ {
+				expectedNames[matcher.getFieldName(fieldsType.Field(i))] = true
+			}
+		}
+		for _, part := range parts {
+			if !expectedNames[part.name] {
+				errorList = append(errorList, fmt.Sprintf("Unexpected part '%v' found in multipart body", part.name))
+				expectedNames[part.name] = true
+			}
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+func readMultipart(body []byte, boundary string) ([]multipartPart, error) {
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	var parts []multipartPart
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		partBody, err := ioutil.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, multipartPart{
+			name:        part.FormName(),
+			filename:    part.FileName(),
+			contentType: part.Header.Get("Content-Type"),
+			body:        partBody,
+		})
+	}
+}
+
+func (e PartExpectation) shouldMatchPart(part multipartPart, capturedValues CapturedValues) string {
+	var errorList []string
+	if e.ContentType != "" {
+		if equal := shouldMatchValuePattern("content type", part.contentType, e.ContentType); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+	if e.Filename != "" {
+		if equal := shouldMatchValuePattern("filename", part.filename, e.Filename); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+	size := int64(len(part.body))
+	if e.MinSize > 0 && size < e.MinSize {
+		errorList = append(errorList, fmt.Sprintf("size %d is less than the minimum: %d", size, e.MinSize))
+	}
+	if e.MaxSize > 0 && size > e.MaxSize {
+		errorList = append(errorList, fmt.Sprintf("size %d is more than the maximum: %d", size, e.MaxSize))
+	}
+
+	if e.Body != nil {
+		mediaType, _, _ := mime.ParseMediaType(part.contentType)
+		var equal string
+		switch {
+		case jsonContentType.MatchString(mediaType):
+			equal = ShouldMatchExpectedJSONResponse(part.body, e.Body, capturedValues)
+		case xmlContentType.MatchString(mediaType):
+			equal = ShouldMatchExpectedXMLResponse(part.body, e.Body, capturedValues)
+		default:
+			equal = fmt.Sprintf("Can't match the body of a part with content type: '%v'", part.contentType)
+		}
+		if equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
@@ -0,0 +1,100 @@
+package matcha
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedUploadFields struct {
+	Title string   `form:"title"`
+	Tags  []string `form:"tag"`
+}
+
+type expectedUploadMetadata struct {
+	Width float64 `json:"width" capture:""`
+}
+
+func multipartUpload(metadataContentType string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("title", "Seating plan")
+	writer.WriteField("tag", "venue")
+	writer.WriteField("tag", "map")
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="metadata"`)
+	header.Set("Content-Type", metadataContentType)
+	part, _ := writer.CreatePart(header)
+	part.Write([]byte(`{"width": 800}`))
+	header = textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="plan"; filename="plan.png"`)
+	header.Set("Content-Type", "image/png")
+	part, _ = writer.CreatePart(header)
+	part.Write(make([]byte, 2048))
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+func TestMultipartMatching(t *testing.T) {
+
+	Convey("Given the parts we expect in a multipart body", t, func() {
+
+		expected := MultipartExpectation{
+			Fields: expectedUploadFields{},
+			Parts: []PartExpectation{
+				{Name: "metadata", ContentType: "json", Body: expectedUploadMetadata{}},
+				{Name: "plan", ContentType: "^image/", Filename: `\.png$`, MinSize: 1, MaxSize: 4096},
+			},
+			Strict: true,
+		}
+
+		Convey("When a request has the expected parts", func() {
+
+			body, contentType := multipartUpload("application/json")
+			request, _ := http.NewRequest("POST", "/uploads", body)
+			request.Header.Set("Content-Type", contentType)
+
+			Convey("It should return success and leave the body to be read again", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchMultipart(request, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["width"], ShouldResemble, []interface{}{800.0})
+
+				success = ShouldMatchExpectedRequest(request, RequestExpectation{Body: expected, Format: "multipart"}, nil)
+				So(success, ShouldEqual, "")
+			})
+
+			Convey("It should accept the fields of a compiled plan in strict mode", func() {
+				expected.Fields = MustCompile(expectedUploadFields{})
+				success := ShouldMatchMultipart(request, expected, nil)
+				So(success, ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When parts don't match", func() {
+
+			body, _ := multipartUpload("text/plain")
+			expected.Parts[1].MaxSize = 1024
+			expected.Parts = append(expected.Parts, PartExpectation{Name: "thumbnail"})
+			expected.Fields = nil
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchMultipart(body.Bytes(), expected, nil)
+				So(failure, ShouldEqual, "Part 'metadata': content type: 'text/plain' does not match expected pattern: json\n"+
+					"Can't match the body of a part with content type: 'text/plain'\n"+
+					"Part 'plan': size 2048 is more than the maximum: 1024\n"+
+					"No part 'thumbnail' found in multipart body\n"+
+					"Unexpected part 'title' found in multipart body\n"+
+					"Unexpected part 'tag' found in multipart body")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,434 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/clbanning/mxj"
+)
+
+// OpenAPIDocument is an OpenAPI 3 document that responses can be checked against. It is read from a
+// local JSON or YAML file, and references to other local files are read as they are needed.
+type OpenAPIDocument struct {
+	path     string
+	mutex    sync.Mutex
+	files    map[string]map[string]interface{} // Every file read so far, by path
+	patterns sync.Map                          // Compiled 'pattern' keywords, by pattern
+}
+
+// openAPINode is a part of an OpenAPI document along with the file it came from, which references in
+// it are relative to
+type openAPINode struct {
+	value map[string]interface{}
+	file  string
+}
+
+var openAPIPathParameter = regexp.MustCompile(`\{[^}/]+\}`)
+
+// httpMethods are the keys of a path item that are operations
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ReadOpenAPI reads an OpenAPI 3 document from a JSON or YAML file
+func ReadOpenAPI(path string) (*OpenAPIDocument, error) {
+	document := &OpenAPIDocument{path: filepath.Clean(path), files: make(map[string]map[string]interface{})}
+	root, err := document.file(document.path)
+	if err != nil {
+		return nil, err
+	}
+	if version, _ := root["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("'%v' is not an OpenAPI 3 document", path)
+	}
+	return document, nil
+}
+
+// file returns a file of the document, reading it the first time
+func (d *OpenAPIDocument) file(path string) (map[string]interface{}, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if file, ok := d.files[path]; ok {
+		return file, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &decoded)
+	} else {
+		decoded, err = parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Was not possible to read '%v': %v", path, err)
+	}
+	file, ok := decoded.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Was expecting an object in '%v', but got %v", path, kindOf(decoded))
+	}
+	d.files[path] = file
+	return file, nil
+}
+
+// resolve follows the $ref of a node, if it has one, to the node it refers to
+func (d *OpenAPIDocument) resolve(node openAPINode) (openAPINode, error) {
+	for hops := 0; ; hops++ {
+		ref, ok := node.value["$ref"].(string)
+		if !ok {
+			return node, nil
+		}
+		if hops == 32 {
+			return node, fmt.Errorf("Too many references to follow from: %v", ref)
+		}
+
+		location, pointer := ref, ""
+		if hash := strings.Index(ref, "#"); hash >= 0 {
+			location, pointer = ref[:hash], ref[hash+1:]
+		}
+		file := node.file
+		if location != "" {
+			if strings.Contains(location, "://") {
+				return node, fmt.Errorf("Only references to local files are supported: %v", ref)
+			}
+			file = filepath.Join(filepath.Dir(node.file), filepath.FromSlash(location))
+		}
+		root, err := d.file(file)
+		if err != nil {
+			return node, err
+		}
+
+		var target interface{} = root
+		if pointer != "" {
+			for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+				if unescaped, err := url.PathUnescape(token); err == nil {
+					token = unescaped
+				}
+				token = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+				object, ok := target.(map[string]interface{})
+				if !ok {
+					return node, fmt.Errorf("No '%v' found for reference: %v", token, ref)
+				}
+				if target, ok = object[token]; !ok {
+					return node, fmt.Errorf("No '%v' found for reference: %v", token, ref)
+				}
+			}
+		}
+		object, ok := target.(map[string]interface{})
+		if !ok {
+			return node, fmt.Errorf("Was expecting an object for reference: %v, but got %v", ref, kindOf(target))
+		}
+		node = openAPINode{value: object, file: file}
+	}
+}
+
+// child returns a property of a node that is an object, resolving any reference
+func (d *OpenAPIDocument) child(node openAPINode, key string) (openAPINode, bool, error) {
+	value, ok := node.value[key].(map[string]interface{})
+	if !ok {
+		return openAPINode{}, false, nil
+	}
+	resolved, err := d.resolve(openAPINode{value: value, file: node.file})
+	return resolved, true, err
+}
+
+// operation finds an operation by its operationId, or by a method and path such as 'GET /bookings/12',
+// where the path is either one of the document's path templates or matches one
+func (d *OpenAPIDocument) operation(name string) (openAPINode, error) {
+	root, err := d.file(d.path)
+	if err != nil {
+		return openAPINode{}, err
+	}
+	paths, _ := root["paths"].(map[string]interface{})
+	templates := make([]string, 0, len(paths))
+	for template := range paths {
+		templates = append(templates, template)
+	}
+	// Templates with fewer parameters are more specific, so are tried first
+	sort.Slice(templates, func(i, j int) bool {
+		countI := len(openAPIPathParameter.FindAllString(templates[i], -1))
+		countJ := len(openAPIPathParameter.FindAllString(templates[j], -1))
+		if countI != countJ {
+			return countI < countJ
+		}
+		return templates[i] < templates[j]
+	})
+
+	method, path := "", ""
+	if space := strings.Index(name, " "); space >= 0 {
+		method, path = strings.ToLower(name[:space]), strings.TrimSpace(name[space+1:])
+	}
+
+	var candidates []openAPINode
+	for _, template := range templates {
+		pathItem, ok, err := d.child(openAPINode{value: paths, file: d.path}, template)
+		if err != nil {
+			return openAPINode{}, err
+		}
+		if !ok {
+			continue
+		}
+		for _, itemMethod := range httpMethods {
+			operation, ok, err := d.child(pathItem, itemMethod)
+			if err != nil {
+				return openAPINode{}, err
+			}
+			if !ok {
+				continue
+			}
+			if method == "" {
+				if operation.value["operationId"] == name {
+					return operation, nil
+				}
+			} else if method == itemMethod {
+				if template == path {
+					return operation, nil
+				}
+				if openAPIPathMatches(template, path) {
+					candidates = append(candidates, operation)
+				}
+			}
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0], nil
+	}
+	return openAPINode{}, fmt.Errorf("No operation '%v' found in the OpenAPI document", name)
+}
+
+func openAPIPathMatches(template string, path string) bool {
+	parts := openAPIPathParameter.Split(template, -1)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matched, _ := regexp.MatchString("^"+strings.Join(parts, "[^/]+")+"$", path)
+	return matched
+}
+
+// response finds the response an operation describes for a status: the one for the status itself,
+// then one for its class, such as '4XX', then the default
+func (d *OpenAPIDocument) response(operation openAPINode, status int) (openAPINode, error) {
+	responses, ok, err := d.child(operation, "responses")
+	if err != nil {
+		return openAPINode{}, err
+	}
+	if ok {
+		class := fmt.Sprintf("%dXX", status/100)
+		for _, key := range []string{strconv.Itoa(status), class, strings.ToLower(class), "default"} {
+			response, ok, err := d.child(responses, key)
+			if err != nil || ok {
+				return response, err
+			}
+		}
+	}
+	return openAPINode{}, fmt.Errorf("No response for status %v found for operation: %v", status, operation.value["operationId"])
+}
+
+// ShouldMatchOpenAPIResponse checks a response against an operation of an OpenAPI 3 document. The
+// operation is given by its operationId or by a method and path, e.g. 'GET /bookings/{booking_id}' or
+// 'GET /bookings/12'. The response can be an *http.Response or *httptest.ResponseRecorder, or a body as
+// a byte slice followed by its status and, optionally, its headers.
+//
+//	spec, err := matcha.ReadOpenAPI("openapi.yaml")
+//	So(recorder, matcha.ShouldMatchOpenAPIResponse, spec, "getBooking")
+//	So(body, matcha.ShouldMatchOpenAPIResponse, spec, "GET /bookings/12", 200, headers)
+func ShouldMatchOpenAPIResponse(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	_, isBody := actual.([]byte)
+	if len(expectedList) < 2 || (isBody && len(expectedList) < 3) || len(expectedList) > 4 {
+		return fmt.Sprintf("ShouldMatchOpenAPIResponse expects the actual response, an OpenAPI document and an operation, followed by the status and optionally the headers if the response is a byte slice")
+	}
+
+	document, ok := expectedList[0].(*OpenAPIDocument)
+	if !ok || document == nil {
+		return fmt.Sprintf("Expected second argument to be an *OpenAPIDocument")
+	}
+	operationName, ok := expectedList[1].(string)
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be an operationId or a method and path as a string")
+	}
+
+	var body []byte
+	var status int
+	headers := make(http.Header)
+	switch response := actual.(type) {
+	case []byte:
+		body = response
+		if status, ok = expectedList[2].(int); !ok {
+			return fmt.Sprintf("Expected fourth argument to be a status as an int")
+		}
+		if len(expectedList) > 3 && expectedList[3] != nil {
+			if headers, ok = expectedList[3].(http.Header); !ok {
+				return fmt.Sprintf("Expected fifth argument to be an http.Header or nil")
+			}
+		}
+	case *httptest.ResponseRecorder:
+		body, status, headers = response.Body.Bytes(), response.Code, response.Header()
+	case *http.Response:
+		var err error
+		if body, err = ioutil.ReadAll(response.Body); err != nil {
+			return fmt.Sprintf("Was not possible to read the response body: %v", err)
+		}
+		response.Body.Close()
+		response.Body = ioutil.NopCloser(bytes.NewReader(body))
+		status, headers = response.StatusCode, response.Header
+	default:
+		return fmt.Sprintf("Expected first argument to be an *http.Response, an *httptest.ResponseRecorder or a byte slice")
+	}
+	if !isBody && len(expectedList) > 2 {
+		return fmt.Sprintf("ShouldMatchOpenAPIResponse only expects a status and headers when the response is a byte slice")
+	}
+
+	operation, err := document.operation(operationName)
+	if err != nil {
+		return err.Error()
+	}
+	response, err := document.response(operation, status)
+	if err != nil {
+		return err.Error()
+	}
+
+	errorList := document.shouldMatchHeaders(response, headers)
+	if equal := document.shouldMatchContent(response, headers.Get("Content-Type"), body); equal != success {
+		errorList = append(errorList, equal)
+	}
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// shouldMatchHeaders checks the headers a response describes, converting their values to the type of
+// their schema
+func (d *OpenAPIDocument) shouldMatchHeaders(response openAPINode, headers http.Header) []string {
+	var errorList []string
+	headerObjects, ok, err := d.child(response, "headers")
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if !ok {
+		return nil
+	}
+
+	validator := openAPIValidator{document: d, format: "header"}
+	names := make([]string, 0, len(headerObjects.value))
+	for name := range headerObjects.value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		header, _, err := d.child(headerObjects, name)
+		if err != nil {
+			errorList = append(errorList, err.Error())
+			continue
+		}
+		values, present := headers[http.CanonicalHeaderKey(name)]
+		if !present {
+			if required, _ := header.value["required"].(bool); required {
+				errorList = append(errorList, fmt.Sprintf("No header '%v' found in response", name))
+			}
+			continue
+		}
+		if schema, ok := header.value["schema"].(map[string]interface{}); ok && len(values) > 0 {
+			errorList = append(errorList, validator.validate(values[0], openAPINode{value: schema, file: header.file}, "header "+name)...)
+		}
+	}
+	return errorList
+}
+
+// shouldMatchContent decodes the body by its media type and checks it against the media type's schema
+func (d *OpenAPIDocument) shouldMatchContent(response openAPINode, contentType string, body []byte) string {
+	content, ok, err := d.child(response, "content")
+	if err != nil {
+		return err.Error()
+	}
+	if !ok || len(content.value) == 0 {
+		return success
+	}
+
+	mediaTypes := make([]string, 0, len(content.value))
+	for mediaType := range content.value {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+	sort.Strings(mediaTypes)
+
+	actualType := ""
+	if contentType != "" {
+		if actualType, _, err = mime.ParseMediaType(contentType); err != nil {
+			return fmt.Sprintf("Received invalid Content-Type: %v", contentType)
+		}
+	}
+	mediaTypeName := ""
+	switch {
+	case actualType == "" && len(mediaTypes) == 1:
+		mediaTypeName = mediaTypes[0]
+	case actualType != "":
+		for _, candidate := range []string{actualType, strings.SplitN(actualType, "/", 2)[0] + "/*", "*/*"} {
+			if _, ok := content.value[candidate]; ok {
+				mediaTypeName = candidate
+				break
+			}
+		}
+	}
+	if mediaTypeName == "" {
+		return fmt.Sprintf("Content type '%v' is not one of the response's media types: %v", actualType, strings.Join(mediaTypes, ", "))
+	}
+	if actualType == "" {
+		actualType = mediaTypeName
+	}
+
+	mediaType, _, err := d.child(content, mediaTypeName)
+	if err != nil {
+		return err.Error()
+	}
+	schema, ok := mediaType.value["schema"].(map[string]interface{})
+	if !ok {
+		return success
+	}
+
+	validator := openAPIValidator{document: d}
+	var actualBody interface{}
+	switch {
+	case jsonContentType.MatchString(actualType):
+		validator.format = "json"
+		if err := json.Unmarshal(body, &actualBody); err != nil {
+			return fmt.Sprintf("Was not possible to unmarshal JSON into a Go struct. JSON data:\n%v", string(body))
+		}
+	case xmlContentType.MatchString(actualType):
+		validator.format = "xml"
+		actualXML, err := mxj.NewMapXml(body)
+		if err != nil {
+			return fmt.Sprintf("Was not possible to unmarshal XML into a Go struct. XML data:\n%v", string(body))
+		}
+		// The root element is named by the document rather than the schema
+		for _, root := range actualXML {
+			actualBody = validator.xmlProperty(root, schema, mediaType.file)
+		}
+	case strings.HasPrefix(actualType, "text/"):
+		validator.format = "text"
+		actualBody = string(body)
+	default:
+		return fmt.Sprintf("Can't check a body of type: %v", actualType)
+	}
+
+	errorList := validator.validate(actualBody, openAPINode{value: schema, file: mediaType.file}, "body")
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
@@ -0,0 +1,652 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// GenerateOptions describe the Go file GenerateExpected writes
+type GenerateOptions struct {
+	Package string // The package the file is in
+	Source  string // Where the OpenAPI document was read from, for the comment at the top of the file
+}
+
+// generatedResponse is a response schema that an expected struct is generated for
+type generatedResponse struct {
+	name        string // The name of the expected struct
+	description string // Which response it is, e.g. 'the 200 response of getBooking'
+	schema      openAPINode
+}
+
+// namedSchema is a schema referred to by a $ref, which is declared as a type of its own
+type namedSchema struct {
+	name   string
+	schema openAPINode
+}
+
+type expectedGenerator struct {
+	document *OpenAPIDocument
+	names    map[string]string // Type names of referenced schemas, by where they are
+	used     map[string]bool   // Type names that have been taken
+	pending  []namedSchema     // Referenced schemas still to be declared
+}
+
+// formatPatterns are patterns for the string formats the matcher has no tag for
+var formatPatterns = map[string]string{
+	"date-time": `^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})$`,
+	"date":      `^[0-9]{4}-[0-9]{2}-[0-9]{2}$`,
+	"uuid":      `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+}
+
+// formatExamples are examples of the string formats
+var formatExamples = map[string]string{
+	"date-time": "2024-01-01T00:00:00Z",
+	"date":      "2024-01-01",
+	"uuid":      "00000000-0000-4000-8000-000000000000",
+	"email":     "user@example.com",
+	"uri":       "https://example.com",
+}
+
+// initialisms are written in capitals in Go identifiers
+var initialisms = map[string]bool{"id": true, "url": true, "uri": true, "http": true, "api": true, "json": true, "xml": true, "uuid": true}
+
+// GenerateExpected writes Go source declaring an expected struct for the JSON body of every response in an
+// OpenAPI document, named after its operation and status, e.g. GetBooking200Response. Schemas that are
+// referred to by a $ref are declared as types of their own. Fields have 'json' tags, and 'pattern',
+// 'min', 'max' and 'enum' tags from the keywords of their schema. The matcher expects every field of a
+// struct to be in the document, so optional properties are left out and listed in a comment.
+func GenerateExpected(spec *OpenAPIDocument, opts GenerateOptions) ([]byte, error) {
+	responses, err := spec.generatedResponses()
+	if err != nil {
+		return nil, err
+	}
+	generator := &expectedGenerator{document: spec, names: make(map[string]string), used: make(map[string]bool)}
+	for _, response := range responses {
+		generator.used[response.name] = true
+	}
+
+	var source bytes.Buffer
+	fmt.Fprintf(&source, "// Code generated by matcha-gen from %v. DO NOT EDIT.\n\npackage %v\n", opts.Source, opts.Package)
+	for _, response := range responses {
+		if _, ok := response.schema.value["$ref"]; ok {
+			name, reason, err := generator.goType(response.schema)
+			if err != nil {
+				return nil, err
+			}
+			if reason == "" {
+				fmt.Fprintf(&source, "\n// %v is %v\ntype %v = %v\n", response.name, response.description, response.name, name)
+				continue
+			}
+		}
+		body, reason, err := generator.typeBody(response.schema)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			fmt.Fprintf(&source, "\n// %v can't be generated for %v, as its schema has %v\n", response.name, response.description, reason)
+			continue
+		}
+		fmt.Fprintf(&source, "\n// %v is %v\ntype %v %v\n", response.name, response.description, response.name, body)
+	}
+
+	for len(generator.pending) > 0 {
+		named := generator.pending[0]
+		generator.pending = generator.pending[1:]
+		// Only schemas that can be declared are given names
+		body, _, err := generator.typeBody(named.schema)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&source, "\n// %v is the %v schema\ntype %v %v\n", named.name, named.name, named.name, body)
+	}
+
+	formatted, err := format.Source(source.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Was not possible to format the generated code: %v", err)
+	}
+	return formatted, nil
+}
+
+// GenerateExamples makes an example JSON payload for every response GenerateExpected writes a struct
+// for, by the name of the struct. Examples are taken from the 'example' keywords of schemas if they have
+// them, and otherwise made up to satisfy the schema.
+func GenerateExamples(spec *OpenAPIDocument) (map[string][]byte, error) {
+	responses, err := spec.generatedResponses()
+	if err != nil {
+		return nil, err
+	}
+	examples := make(map[string][]byte)
+	for _, response := range responses {
+		example, err := spec.example(response.schema, 0)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.MarshalIndent(example, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		examples[response.name] = append(data, '\n')
+	}
+	return examples, nil
+}
+
+// generatedResponses finds the schemas of every response with a JSON body, in the order of their paths
+func (d *OpenAPIDocument) generatedResponses() ([]generatedResponse, error) {
+	root, err := d.file(d.path)
+	if err != nil {
+		return nil, err
+	}
+	paths := openAPINode{file: d.path}
+	paths.value, _ = root["paths"].(map[string]interface{})
+
+	var responses []generatedResponse
+	for _, template := range sortedObjectKeys(paths.value) {
+		pathItem, _, err := d.child(paths, template)
+		if err != nil {
+			return nil, err
+		}
+		for _, method := range httpMethods {
+			operation, ok, err := d.child(pathItem, method)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			operationName, _ := operation.value["operationId"].(string)
+			if operationName == "" {
+				operationName = method + " " + template
+			}
+			statuses, _, err := d.child(operation, "responses")
+			if err != nil {
+				return nil, err
+			}
+			for _, status := range sortedObjectKeys(statuses.value) {
+				response, _, err := d.child(statuses, status)
+				if err != nil {
+					return nil, err
+				}
+				content, _, err := d.child(response, "content")
+				if err != nil {
+					return nil, err
+				}
+				for _, mediaTypeName := range sortedObjectKeys(content.value) {
+					if !jsonContentType.MatchString(mediaTypeName) {
+						continue
+					}
+					mediaType, _, err := d.child(content, mediaTypeName)
+					if err != nil {
+						return nil, err
+					}
+					schema, ok := mediaType.value["schema"].(map[string]interface{})
+					if !ok {
+						continue
+					}
+					responses = append(responses, generatedResponse{
+						name:        goIdentifier(operationName) + statusName(status) + "Response",
+						description: fmt.Sprintf("the %v response of %v", status, operationName),
+						schema:      openAPINode{value: schema, file: mediaType.file},
+					})
+					break
+				}
+			}
+		}
+	}
+	return responses, nil
+}
+
+// statusName is how a response's status is written in the name of its struct, e.g. '200', '4XX' or 'Default'
+func statusName(status string) string {
+	if strings.EqualFold(status, "default") {
+		return "Default"
+	}
+	return strings.ToUpper(status)
+}
+
+// sortedObjectKeys returns the keys of a decoded object in order
+func sortedObjectKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// goIdentifier turns a name from a document into an exported Go identifier, e.g. 'booking_id' into 'BookingID'
+func goIdentifier(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var identifier strings.Builder
+	for _, word := range words {
+		if initialisms[strings.ToLower(word)] {
+			identifier.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		identifier.WriteString(string(runes))
+	}
+	if identifier.Len() == 0 || unicode.IsDigit([]rune(identifier.String())[0]) {
+		return "Field" + identifier.String()
+	}
+	return identifier.String()
+}
+
+// goType returns the Go type for a schema, or the reason there can't be one
+func (g *expectedGenerator) goType(schema openAPINode) (string, string, error) {
+	ref, isRef := schema.value["$ref"].(string)
+	if !isRef {
+		return g.typeBody(schema)
+	}
+	resolved, err := g.document.resolve(schema)
+	if err != nil {
+		return "", "", err
+	}
+	pointer := ref[strings.Index(ref, "#")+1:]
+	key := resolved.file + "#" + pointer
+	if name, ok := g.names[key]; ok {
+		return name, "", nil
+	}
+
+	// The name is taken before the body is worked out, so that schemas that refer to themselves use it.
+	// Schemas that can't be declared as a type are written in full where they are used.
+	base := goIdentifier(pointer[strings.LastIndex(pointer, "/")+1:])
+	name := base
+	for i := 2; g.used[name]; i++ {
+		name = fmt.Sprintf("%v%d", base, i)
+	}
+	g.used[name] = true
+	g.names[key] = name
+	if _, reason, err := g.typeBody(resolved); err != nil || reason != "" {
+		delete(g.used, name)
+		delete(g.names, key)
+		return "", reason, err
+	}
+	g.pending = append(g.pending, namedSchema{name: name, schema: resolved})
+	return name, "", nil
+}
+
+// typeBody returns the Go type a schema describes, without using its name if it has one
+func (g *expectedGenerator) typeBody(schema openAPINode) (string, string, error) {
+	schema, err := g.document.resolve(schema)
+	if err != nil {
+		return "", "", err
+	}
+	if isNullable(schema) {
+		return "", "nullable", nil
+	}
+	for _, keyword := range []string{"oneOf", "anyOf"} {
+		if _, ok := schema.value[keyword]; ok {
+			return "", keyword, nil
+		}
+	}
+	if _, ok := schema.value["allOf"]; ok {
+		return g.structType(schema)
+	}
+
+	var types []string
+	for schemaType := range schemaTypes(schema) {
+		if schemaType != "null" {
+			types = append(types, schemaType)
+		}
+	}
+	if len(types) == 0 && schema.value["properties"] != nil {
+		types = []string{"object"}
+	}
+	if len(types) != 1 {
+		return "", "no single type", nil
+	}
+
+	switch types[0] {
+	case "string":
+		return "string", "", nil
+	case "number":
+		return "float64", "", nil
+	case "integer":
+		return "int", "", nil
+	case "boolean":
+		return "bool", "", nil
+	case "array":
+		items, ok := schema.value["items"].(map[string]interface{})
+		if !ok {
+			return "", "an array without items", nil
+		}
+		itemType, reason, err := g.goType(openAPINode{value: items, file: schema.file})
+		if reason != "" || err != nil {
+			return "", reason, err
+		}
+		return "[]" + itemType, "", nil
+	case "object":
+		return g.structType(schema)
+	}
+	return "", fmt.Sprintf("the unknown type '%v'", types[0]), nil
+}
+
+// objectProperties collects the properties of an object schema, and of the schemas in its allOf
+func (d *OpenAPIDocument) objectProperties(schema openAPINode, properties map[string]openAPINode, required map[string]bool) error {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return err
+	}
+	allOf, _ := schema.value["allOf"].([]interface{})
+	for _, item := range allOf {
+		if subschema, ok := item.(map[string]interface{}); ok {
+			if err := d.objectProperties(openAPINode{value: subschema, file: schema.file}, properties, required); err != nil {
+				return err
+			}
+		}
+	}
+	if ownProperties, ok := schema.value["properties"].(map[string]interface{}); ok {
+		for name, property := range ownProperties {
+			if propertySchema, ok := property.(map[string]interface{}); ok {
+				properties[name] = openAPINode{value: propertySchema, file: schema.file}
+			}
+		}
+	}
+	if names, ok := schema.value["required"].([]interface{}); ok {
+		for _, name := range names {
+			if name, ok := name.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (g *expectedGenerator) structType(schema openAPINode) (string, string, error) {
+	properties := make(map[string]openAPINode)
+	required := make(map[string]bool)
+	if err := g.document.objectProperties(schema, properties, required); err != nil {
+		return "", "", err
+	}
+
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields, optional, nullable, unsupported []string
+	usedNames := make(map[string]bool)
+	for _, name := range names {
+		if !required[name] {
+			optional = append(optional, name)
+			continue
+		}
+		property, err := g.document.resolve(properties[name])
+		if err != nil {
+			return "", "", err
+		}
+		if isNullable(property) {
+			nullable = append(nullable, name)
+			continue
+		}
+		fieldType, reason, err := g.goType(properties[name])
+		if err != nil {
+			return "", "", err
+		}
+		if reason != "" {
+			unsupported = append(unsupported, fmt.Sprintf("%v (%v)", name, reason))
+			continue
+		}
+		fieldName := goIdentifier(name)
+		for i := 2; usedNames[fieldName]; i++ {
+			fieldName = fmt.Sprintf("%v%d", goIdentifier(name), i)
+		}
+		usedNames[fieldName] = true
+		tags, err := g.document.fieldTags(name, properties[name], fieldType)
+		if err != nil {
+			return "", "", err
+		}
+		fields = append(fields, fmt.Sprintf("%v %v %v", fieldName, fieldType, tags))
+	}
+
+	var body strings.Builder
+	body.WriteString("struct {\n")
+	if optional != nil {
+		fmt.Fprintf(&body, "// Optional properties aren't checked: %v\n", strings.Join(optional, ", "))
+	}
+	if nullable != nil {
+		fmt.Fprintf(&body, "// Nullable properties aren't checked: %v\n", strings.Join(nullable, ", "))
+	}
+	if unsupported != nil {
+		fmt.Fprintf(&body, "// Properties whose schemas can't be checked: %v\n", strings.Join(unsupported, ", "))
+	}
+	for _, field := range fields {
+		body.WriteString(field + "\n")
+	}
+	body.WriteString("}")
+	return body.String(), "", nil
+}
+
+// fieldTags writes the struct tags for a property
+func (d *OpenAPIDocument) fieldTags(name string, property openAPINode, fieldType string) (string, error) {
+	property, err := d.resolve(property)
+	if err != nil {
+		return "", err
+	}
+	tags := []string{fmt.Sprintf("json:%v", strconv.Quote(name))}
+
+	if fieldType == "string" {
+		pattern, ok := property.value["pattern"].(string)
+		if !ok {
+			format, _ := property.value["format"].(string)
+			pattern, ok = formatPatterns[format]
+		}
+		if ok {
+			tags = append(tags, fmt.Sprintf("pattern:%v", strconv.Quote(pattern)))
+		}
+	}
+
+	minimum, maximum := "minimum", "maximum"
+	switch {
+	case fieldType == "string":
+		minimum, maximum = "minLength", "maxLength"
+	case strings.HasPrefix(fieldType, "[]"):
+		minimum, maximum = "minItems", "maxItems"
+	}
+	for tag, keyword := range map[string]string{"min": minimum, "max": maximum} {
+		if limit, ok := numberValue(property.value[keyword]); ok {
+			tags = append(tags, fmt.Sprintf("%v:%q", tag, strconv.FormatFloat(limit, 'f', -1, 64)))
+		}
+	}
+
+	if enum, ok := property.value["enum"].([]interface{}); ok {
+		values := make([]string, 0, len(enum))
+		for _, value := range enum {
+			if value != nil {
+				values = append(values, fmt.Sprintf("%v", value))
+			}
+		}
+		if joined := strings.Join(values, "|"); len(values) > 0 && strings.Count(joined, "|") == len(values)-1 {
+			tags = append(tags, fmt.Sprintf("enum:%v", strconv.Quote(joined)))
+		}
+	}
+
+	sort.SliceStable(tags[1:], func(i, j int) bool { return tags[i+1] < tags[j+1] })
+	tag := strings.Join(tags, " ")
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag), nil
+	}
+	return "`" + tag + "`", nil
+}
+
+// maxExampleDepth is how deep examples go before leaving out what they can, so that schemas that refer
+// to themselves have an end
+const maxExampleDepth = 8
+
+// example makes up a value that satisfies a schema
+func (d *OpenAPIDocument) example(schema openAPINode, depth int) (interface{}, error) {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return nil, err
+	}
+	if example, ok := schema.value["example"]; ok {
+		return example, nil
+	}
+	if enum, ok := schema.value["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0], nil
+	}
+	if alternatives, ok := schema.value["oneOf"].([]interface{}); ok {
+		return d.alternativeExample(schema, alternatives, depth)
+	}
+	if alternatives, ok := schema.value["anyOf"].([]interface{}); ok {
+		return d.alternativeExample(schema, alternatives, depth)
+	}
+
+	types := schemaTypes(schema)
+	if _, ok := schema.value["allOf"]; ok || schema.value["properties"] != nil {
+		types["object"] = true
+	}
+	switch {
+	case types["object"] && depth > maxExampleDepth:
+		// Past the depth only required properties are made, so these most likely refer back to the schema
+		if isNullable(schema) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("Can't make an example of a schema in %v, as its required properties refer to it too deeply", schema.file)
+	case types["object"]:
+		return d.objectExample(schema, depth)
+	case types["array"]:
+		count := 1
+		if minItems, ok := numberValue(schema.value["minItems"]); ok {
+			count = int(minItems)
+		} else if depth >= maxExampleDepth {
+			count = 0
+		}
+		items := make([]interface{}, 0, count)
+		if itemSchema, ok := schema.value["items"].(map[string]interface{}); ok {
+			for i := 0; i < count; i++ {
+				item, err := d.example(openAPINode{value: itemSchema, file: schema.file}, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	case types["string"]:
+		return exampleString(schema)
+	case types["integer"]:
+		return exampleNumber(schema, true), nil
+	case types["number"]:
+		return exampleNumber(schema, false), nil
+	case types["boolean"]:
+		return true, nil
+	}
+	return nil, nil
+}
+
+// alternativeExample makes an example of the first schema of a oneOf or anyOf, or of the first one its
+// discriminator maps to, with the discriminating property set
+func (d *OpenAPIDocument) alternativeExample(schema openAPINode, alternatives []interface{}, depth int) (interface{}, error) {
+	var chosen map[string]interface{}
+	value := ""
+	if discriminator, ok := schema.value["discriminator"].(map[string]interface{}); ok {
+		if mapping, ok := discriminator["mapping"].(map[string]interface{}); ok && len(mapping) > 0 {
+			value = sortedObjectKeys(mapping)[0]
+			ref, _ := mapping[value].(string)
+			if !strings.ContainsAny(ref, "#/.") {
+				ref = "#/components/schemas/" + ref
+			}
+			chosen = map[string]interface{}{"$ref": ref}
+		}
+	}
+	if chosen == nil {
+		if len(alternatives) == 0 {
+			return nil, nil
+		}
+		chosen, _ = alternatives[0].(map[string]interface{})
+		if ref, ok := chosen["$ref"].(string); ok {
+			value = ref[strings.LastIndex(ref, "/")+1:]
+		}
+	}
+
+	example, err := d.example(openAPINode{value: chosen, file: schema.file}, depth)
+	if err != nil {
+		return nil, err
+	}
+	if discriminator, ok := schema.value["discriminator"].(map[string]interface{}); ok && value != "" {
+		if object, ok := example.(map[string]interface{}); ok {
+			if propertyName, ok := discriminator["propertyName"].(string); ok {
+				object[propertyName] = value
+			}
+		}
+	}
+	return example, nil
+}
+
+func (d *OpenAPIDocument) objectExample(schema openAPINode, depth int) (interface{}, error) {
+	properties := make(map[string]openAPINode)
+	required := make(map[string]bool)
+	if err := d.objectProperties(schema, properties, required); err != nil {
+		return nil, err
+	}
+	object := make(map[string]interface{})
+	for name, property := range properties {
+		if !required[name] && depth >= maxExampleDepth {
+			continue
+		}
+		example, err := d.example(property, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		object[name] = example
+	}
+	return object, nil
+}
+
+func exampleString(schema openAPINode) (string, error) {
+	if pattern, ok := schema.value["pattern"].(string); ok {
+		return patternExample(pattern)
+	}
+	format, _ := schema.value["format"].(string)
+	example, ok := formatExamples[format]
+	if !ok {
+		example = "string"
+	}
+	if minLength, ok := numberValue(schema.value["minLength"]); ok {
+		for len(example) < int(minLength) {
+			example += "x"
+		}
+	}
+	if maxLength, ok := numberValue(schema.value["maxLength"]); ok && len(example) > int(maxLength) {
+		example = example[:int(maxLength)]
+	}
+	return example, nil
+}
+
+func exampleNumber(schema openAPINode, integer bool) float64 {
+	step := 0.5
+	if integer {
+		step = 1
+	}
+	value := 1.0
+	if minimum, ok := numberValue(schema.value["minimum"]); ok {
+		value = minimum
+		if exclusive, _ := schema.value["exclusiveMinimum"].(bool); exclusive {
+			value += step
+		}
+	}
+	if exclusiveMinimum, ok := numberValue(schema.value["exclusiveMinimum"]); ok && value <= exclusiveMinimum {
+		value = exclusiveMinimum + step
+	}
+	if maximum, ok := numberValue(schema.value["maximum"]); ok && value > maximum {
+		value = maximum
+		if exclusive, _ := schema.value["exclusiveMaximum"].(bool); exclusive {
+			value -= step
+		}
+	}
+	if exclusiveMaximum, ok := numberValue(schema.value["exclusiveMaximum"]); ok && value >= exclusiveMaximum {
+		value = exclusiveMaximum - step
+	}
+	return value
+}
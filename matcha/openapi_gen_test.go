@@ -0,0 +1,159 @@
+package matcha
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestGenerateExpected(t *testing.T) {
+
+	spec, err := ReadOpenAPI("testdata/openapi/bookings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Given an OpenAPI document", t, func() {
+
+		source, err := GenerateExpected(spec, GenerateOptions{Package: "api", Source: "bookings.yaml"})
+		code := string(source)
+
+		Convey("It should write valid Go", func() {
+			So(err, ShouldBeNil)
+			_, err := parser.ParseFile(token.NewFileSet(), "expected.go", source, 0)
+			So(err, ShouldBeNil)
+			So(code, ShouldStartWith, "// Code generated by matcha-gen from bookings.yaml. DO NOT EDIT.\n\npackage api\n")
+		})
+
+		Convey("It should name a struct for each response after its operation and status", func() {
+			So(code, ShouldContainSubstring, "type ListBookings200Response []Booking\n")
+			So(code, ShouldContainSubstring, "type GetBooking200Response = Booking\n")
+			So(code, ShouldContainSubstring, "// GetBooking4XXResponse is the 4XX response of getBooking\ntype GetBooking4XXResponse struct {")
+		})
+
+		Convey("It should declare referenced schemas once, with tags from their keywords", func() {
+			So(code, ShouldContainSubstring, "type Booking struct {\n"+
+				"\t// Optional properties aren't checked: created, note, total\n"+
+				"\tID     string `json:\"id\" pattern:\"^b-[0-9]+$\"`\n"+
+				"\tSeats  []int  `json:\"seats\" min:\"1\"`\n"+
+				"\tStatus string `json:\"status\" enum:\"open|closed\"`\n"+
+				"}")
+		})
+
+		Convey("It should say which properties can't be checked", func() {
+			So(code, ShouldContainSubstring, "// Properties whose schemas can't be checked: method (oneOf)")
+		})
+
+	})
+
+	Convey("Given a schema that refers to itself", t, func() {
+
+		spec, err := ReadOpenAPI("testdata/openapi/venues.yaml")
+		So(err, ShouldBeNil)
+		source, err := GenerateExpected(spec, GenerateOptions{Package: "api", Source: "venues.yaml"})
+
+		Convey("It should use the type's own name inside it", func() {
+			So(err, ShouldBeNil)
+			_, err := parser.ParseFile(token.NewFileSet(), "expected.go", source, 0)
+			So(err, ShouldBeNil)
+			So(string(source), ShouldContainSubstring, "type GetVenue200Response = Venue\n")
+			So(string(source), ShouldContainSubstring, "Sections []Venue `json:\"sections\"`\n")
+		})
+
+		Convey("It should leave out required nullable properties and list them", func() {
+			So(string(source), ShouldContainSubstring, "type Venue struct {\n"+
+				"\t// Optional properties aren't checked: manager\n"+
+				"\t// Nullable properties aren't checked: capacity, parent\n"+
+				"\tName     string  `json:\"name\"`\n"+
+				"\tSections []Venue `json:\"sections\"`\n"+
+				"}")
+		})
+
+	})
+
+	Convey("Given names from a document", t, func() {
+
+		Convey("They should become exported Go identifiers", func() {
+			So(goIdentifier("booking_id"), ShouldEqual, "BookingID")
+			So(goIdentifier("getBooking"), ShouldEqual, "GetBooking")
+			So(goIdentifier("get /bookings/{booking_id}"), ShouldEqual, "GetBookingsBookingID")
+			So(goIdentifier("2fa-code"), ShouldEqual, "Field2faCode")
+		})
+
+	})
+
+}
+
+func TestGenerateExamples(t *testing.T) {
+
+	spec, err := ReadOpenAPI("testdata/openapi/bookings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Given an OpenAPI document", t, func() {
+
+		examples, err := GenerateExamples(spec)
+
+		Convey("It should make an example for each response", func() {
+			So(err, ShouldBeNil)
+			So(examples, ShouldContainKey, "ListBookings200Response")
+			So(examples, ShouldContainKey, "GetBooking200Response")
+			So(examples, ShouldContainKey, "GetBooking4XXResponse")
+			So(examples, ShouldContainKey, "GetPayment200Response")
+		})
+
+		Convey("Each example should satisfy its schema", func() {
+			jsonHeaders := http.Header{"Content-Type": {"application/json"}}
+			So(ShouldMatchOpenAPIResponse(examples["ListBookings200Response"], spec, "listBookings", 200, jsonHeaders), ShouldEqual, "")
+			So(ShouldMatchOpenAPIResponse(examples["GetBooking200Response"], spec, "getBooking", 200, http.Header{"X-Rate-Limit": {"1"}}), ShouldEqual, "")
+			So(ShouldMatchOpenAPIResponse(examples["GetBooking4XXResponse"], spec, "getBooking", 404), ShouldEqual, "")
+		})
+
+		Convey("It should set the discriminator of a oneOf", func() {
+			var payment map[string]interface{}
+			So(json.Unmarshal(examples["GetPayment200Response"], &payment), ShouldBeNil)
+			So(payment["method"], ShouldResemble, map[string]interface{}{"kind": "card", "last4": "stri"})
+		})
+
+	})
+
+	Convey("Given a schema whose required properties refer to it", t, func() {
+
+		spec, err := ReadOpenAPI("testdata/openapi/venues.yaml")
+		So(err, ShouldBeNil)
+
+		Convey("It should end with null where the schema is nullable", func() {
+			examples, err := GenerateExamples(spec)
+			So(err, ShouldBeNil)
+			So(ShouldMatchOpenAPIResponse(examples["GetVenue200Response"], spec, "getVenue", 200), ShouldEqual, "")
+			So(string(examples["GetVenue200Response"]), ShouldContainSubstring, `"parent": null`)
+		})
+
+		Convey("It should return an error where it isn't", func() {
+			loop := openAPINode{value: map[string]interface{}{"$ref": "#/components/schemas/Loop"}, file: spec.path}
+			_, err := spec.example(loop, 0)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Can't make an example of a schema in "+spec.path+", as its required properties refer to it too deeply")
+		})
+
+	})
+
+	Convey("Given patterns", t, func() {
+
+		Convey("It should make strings that match them", func() {
+			for _, pattern := range []string{`^b-[0-9]+$`, `^[A-Z]{3}(-[a-z]{2,4})?$`, `^(GBP|EUR)\d{2}[^/]x*$`, `\w+@\w+\.com`} {
+				example, err := patternExample(pattern)
+				So(err, ShouldBeNil)
+				So(regexp.MustCompile(pattern).MatchString(example), ShouldBeTrue)
+			}
+		})
+
+	})
+
+}
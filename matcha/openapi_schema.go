@@ -0,0 +1,380 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// openAPIValidator checks values against OpenAPI schemas, with the same errors as Matcher. Values in
+// headers and XML documents are all strings, so they are converted to the type of their schema first.
+type openAPIValidator struct {
+	document *OpenAPIDocument
+	format   string // 'json', 'xml', 'text' or 'header'
+}
+
+// validate returns the errors found checking a value against a schema, naming values by their full path
+func (v *openAPIValidator) validate(value interface{}, schema openAPINode, path string) []string {
+	schema, err := v.document.resolve(schema)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	// A nullable schema accepts null whatever its subschemas say, e.g. 'nullable: true' with an 'allOf'
+	// of a single $ref, which is how OpenAPI 3.0 makes a referenced schema nullable
+	types := schemaTypes(schema)
+	if value == nil && isNullable(schema) {
+		return nil
+	}
+
+	var errorList []string
+	for _, subschema := range v.subschemas(schema, "allOf") {
+		errorList = append(errorList, v.validate(value, subschema, path)...)
+	}
+	if alternatives := v.subschemas(schema, "anyOf"); alternatives != nil {
+		if matched, closest := v.matchAlternatives(value, alternatives, path); matched == 0 {
+			errorList = append(errorList, fmt.Sprintf("%v matches none of the anyOf schemas", path))
+			errorList = append(errorList, closest...)
+		}
+	}
+	if alternatives := v.subschemas(schema, "oneOf"); alternatives != nil {
+		errorList = append(errorList, v.validateOneOf(value, schema, alternatives, path)...)
+	}
+
+	if value == nil && len(types) == 0 {
+		return errorList
+	}
+	value = v.convert(value, types)
+	if len(types) > 0 && !types[openAPITypeOf(value, types)] {
+		return append(errorList, openAPITypeError(value, types, path))
+	}
+
+	switch actualValue := value.(type) {
+	case string:
+		errorList = append(errorList, v.validateString(actualValue, schema, path)...)
+	case float64:
+		errorList = append(errorList, validateNumber(actualValue, schema, path)...)
+	case []interface{}:
+		errorList = append(errorList, v.validateArray(actualValue, schema, path)...)
+	case map[string]interface{}:
+		errorList = append(errorList, v.validateObject(actualValue, schema, path)...)
+	}
+	if enum, ok := schema.value["enum"].([]interface{}); ok {
+		errorList = append(errorList, validateEnum(value, enum, path)...)
+	}
+	return errorList
+}
+
+func (v *openAPIValidator) subschemas(schema openAPINode, keyword string) []openAPINode {
+	list, ok := schema.value[keyword].([]interface{})
+	if !ok {
+		return nil
+	}
+	subschemas := make([]openAPINode, 0, len(list))
+	for _, item := range list {
+		if object, ok := item.(map[string]interface{}); ok {
+			subschemas = append(subschemas, openAPINode{value: object, file: schema.file})
+		}
+	}
+	return subschemas
+}
+
+// matchAlternatives counts the schemas a value matches, and returns the errors from the schema it came
+// closest to matching
+func (v *openAPIValidator) matchAlternatives(value interface{}, alternatives []openAPINode, path string) (int, []string) {
+	matched := 0
+	var closest []string
+	for i, alternative := range alternatives {
+		errorList := v.validate(value, alternative, path)
+		if errorList == nil {
+			matched++
+		} else if i == 0 || len(errorList) < len(closest) {
+			closest = errorList
+		}
+	}
+	return matched, closest
+}
+
+// validateOneOf checks a value matches exactly one schema, or the one its discriminator picks
+func (v *openAPIValidator) validateOneOf(value interface{}, schema openAPINode, alternatives []openAPINode, path string) []string {
+	if discriminator, ok := schema.value["discriminator"].(map[string]interface{}); ok {
+		propertyName, _ := discriminator["propertyName"].(string)
+		object, _ := value.(map[string]interface{})
+		if name, ok := object[propertyName].(string); ok {
+			ref := "#/components/schemas/" + name
+			if mapping, ok := discriminator["mapping"].(map[string]interface{}); ok {
+				if mapped, ok := mapping[name].(string); ok {
+					ref = mapped
+					if !strings.ContainsAny(ref, "#/.") {
+						ref = "#/components/schemas/" + ref
+					}
+				}
+			}
+			return v.validate(value, openAPINode{value: map[string]interface{}{"$ref": ref}, file: schema.file}, path)
+		}
+	}
+
+	matched, closest := v.matchAlternatives(value, alternatives, path)
+	switch {
+	case matched == 0:
+		return append([]string{fmt.Sprintf("%v matches none of the oneOf schemas", path)}, closest...)
+	case matched > 1:
+		return []string{fmt.Sprintf("%v matches %d of the oneOf schemas, but should match exactly one", path, matched)}
+	}
+	return nil
+}
+
+// schemaTypes returns the types a schema allows, which can be a single type or, from OpenAPI 3.1, a list
+func schemaTypes(schema openAPINode) map[string]bool {
+	types := make(map[string]bool)
+	switch schemaType := schema.value["type"].(type) {
+	case string:
+		types[schemaType] = true
+	case []interface{}:
+		for _, item := range schemaType {
+			if name, ok := item.(string); ok {
+				types[name] = true
+			}
+		}
+	}
+	return types
+}
+
+// isNullable reports whether a schema accepts null, with the 'nullable' of OpenAPI 3.0 or the 'null'
+// type of 3.1
+func isNullable(schema openAPINode) bool {
+	nullable, _ := schema.value["nullable"].(bool)
+	return nullable || schemaTypes(schema)["null"]
+}
+
+// convert turns the strings in headers and XML documents into the type their schema expects
+func (v *openAPIValidator) convert(value interface{}, types map[string]bool) interface{} {
+	text, ok := value.(string)
+	if !ok || (v.format != "xml" && v.format != "header") || types["string"] {
+		return value
+	}
+	switch {
+	case types["number"] || types["integer"]:
+		if number, err := strconv.ParseFloat(strings.TrimSpace(text), 64); err == nil {
+			return number
+		}
+	case types["boolean"]:
+		if boolean, err := strconv.ParseBool(strings.TrimSpace(text)); err == nil {
+			return boolean
+		}
+	case types["object"]:
+		// Empty XML elements are decoded as empty strings
+		if strings.TrimSpace(text) == "" && v.format == "xml" {
+			return map[string]interface{}{}
+		}
+	}
+	return value
+}
+
+// openAPITypeOf names the type of a value, calling whole numbers integers if the schema expects them
+func openAPITypeOf(value interface{}, types map[string]bool) string {
+	switch actualValue := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if types["integer"] && actualValue == math.Trunc(actualValue) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return kindOf(value)
+}
+
+func openAPITypeError(value interface{}, types map[string]bool, path string) string {
+	names := make([]string, 0, len(types))
+	for name := range types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	switch {
+	case types["array"] && len(types) == 1:
+		return fmt.Sprintf("Was expecting an array for field: %v", path)
+	case types["object"] && len(types) == 1:
+		return fmt.Sprintf("Was expecting an object for field: %v, but got %v", path, kindOf(value))
+	}
+	return TypeErrorString(path, strings.Join(names, " or "), kindOf(value))
+}
+
+func (v *openAPIValidator) validateString(value string, schema openAPINode, path string) []string {
+	var errorList []string
+	length := float64(utf8.RuneCountInString(value))
+	errorList = append(errorList, validateLimits(length, fmt.Sprintf("length %v", length), schema, "minLength", "maxLength", path)...)
+
+	if pattern, ok := schema.value["pattern"].(string); ok {
+		compiled, err := v.document.compilePattern(pattern)
+		if err != nil {
+			errorList = append(errorList, fmt.Sprintf("Received invalid regular expression: %v", pattern))
+		} else if !compiled.MatchString(value) {
+			errorList = append(errorList, fmt.Sprintf("%v: '%v' does not match expected pattern: %v", path, value, pattern))
+		}
+	}
+
+	switch schema.value["format"] {
+	case "date-time":
+		if _, err := decodeTime(value); err != nil {
+			errorList = append(errorList, fmt.Sprintf("%v: %v", path, err))
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			errorList = append(errorList, fmt.Sprintf("%v: '%v' isn't a date", path, value))
+		}
+	}
+	return errorList
+}
+
+func (d *OpenAPIDocument) compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := d.patterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	d.patterns.Store(pattern, compiled)
+	return compiled, nil
+}
+
+func validateNumber(value float64, schema openAPINode, path string) []string {
+	// A bound made exclusive by a boolean is only checked as exclusive, so it isn't reported twice
+	minimum, maximum := "minimum", "maximum"
+	if exclusive, _ := schema.value["exclusiveMinimum"].(bool); exclusive {
+		minimum = ""
+	}
+	if exclusive, _ := schema.value["exclusiveMaximum"].(bool); exclusive {
+		maximum = ""
+	}
+	errorList := validateLimits(value, fmt.Sprintf("%v", value), schema, minimum, maximum, path)
+
+	// OpenAPI 3.0 makes the limits exclusive with a boolean, and 3.1 gives the exclusive limit as a number
+	for _, keywords := range [][2]string{{"exclusiveMinimum", "minimum"}, {"exclusiveMaximum", "maximum"}} {
+		keyword := keywords[0]
+		limit, ok := numberValue(schema.value[keyword])
+		if exclusive, _ := schema.value[keyword].(bool); exclusive {
+			limit, ok = numberValue(schema.value[keywords[1]])
+		}
+		if !ok {
+			continue
+		}
+		if keyword == "exclusiveMinimum" && value <= limit {
+			errorList = append(errorList, fmt.Sprintf("%v: %v is not more than the exclusive minimum: %v", path, value, limit))
+		}
+		if keyword == "exclusiveMaximum" && value >= limit {
+			errorList = append(errorList, fmt.Sprintf("%v: %v is not less than the exclusive maximum: %v", path, value, limit))
+		}
+	}
+	return errorList
+}
+
+// validateLimits checks a measurement against a minimum and maximum keyword, with the same errors as
+// the 'min' and 'max' tags
+func validateLimits(measured float64, description string, schema openAPINode, minimum string, maximum string, path string) []string {
+	var errorList []string
+	if limit, ok := numberValue(schema.value[minimum]); ok && measured < limit {
+		errorList = append(errorList, fmt.Sprintf("%v: %v is less than the minimum: %v", path, description, limit))
+	}
+	if limit, ok := numberValue(schema.value[maximum]); ok && measured > limit {
+		errorList = append(errorList, fmt.Sprintf("%v: %v is more than the maximum: %v", path, description, limit))
+	}
+	return errorList
+}
+
+func validateEnum(value interface{}, enum []interface{}, path string) []string {
+	allowed := make([]string, len(enum))
+	for i, item := range enum {
+		if jsonPathEqual(value, item) {
+			return nil
+		}
+		allowed[i] = fmt.Sprintf("%v", item)
+	}
+	return []string{fmt.Sprintf("%v: '%v' is not one of: %v", path, value, strings.Join(allowed, ", "))}
+}
+
+func (v *openAPIValidator) validateArray(value []interface{}, schema openAPINode, path string) []string {
+	length := float64(len(value))
+	errorList := validateLimits(length, fmt.Sprintf("length %v", length), schema, "minItems", "maxItems", path)
+	if items, ok := schema.value["items"].(map[string]interface{}); ok {
+		for i, item := range value {
+			errorList = append(errorList, v.validate(item, openAPINode{value: items, file: schema.file}, fmt.Sprintf("%v[%d]", path, i))...)
+		}
+	}
+	return errorList
+}
+
+func (v *openAPIValidator) validateObject(value map[string]interface{}, schema openAPINode, path string) []string {
+	var errorList []string
+	properties, _ := schema.value["properties"].(map[string]interface{})
+
+	if required, ok := schema.value["required"].([]interface{}); ok {
+		for _, item := range required {
+			name, _ := item.(string)
+			if _, ok := value[name]; !ok {
+				errorList = append(errorList, fmt.Sprintf("No field '%v.%v' found in response", path, name))
+			}
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		// XML attributes are decoded with a '-' in front of their name
+		if v.format == "xml" && strings.HasPrefix(name, "-") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		propertyPath := path + "." + name
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			errorList = append(errorList, v.validate(v.xmlProperty(value[name], property, schema.file), openAPINode{value: property, file: schema.file}, propertyPath)...)
+			continue
+		}
+		switch additional := schema.value["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				errorList = append(errorList, fmt.Sprintf("Unexpected field '%v' found in response", propertyPath))
+			}
+		case map[string]interface{}:
+			errorList = append(errorList, v.validate(value[name], openAPINode{value: additional, file: schema.file}, propertyPath)...)
+		}
+	}
+	return errorList
+}
+
+// xmlProperty unwraps the elements of arrays in XML documents. Without a schema an XML array with one
+// element can't be told apart from a single element, and arrays can be wrapped in an element of their own.
+func (v *openAPIValidator) xmlProperty(value interface{}, property map[string]interface{}, file string) interface{} {
+	if v.format != "xml" {
+		return value
+	}
+	resolved, err := v.document.resolve(openAPINode{value: property, file: file})
+	if err != nil || !schemaTypes(resolved)["array"] {
+		return value
+	}
+	if wrapper, ok := value.(map[string]interface{}); ok && len(wrapper) == 1 {
+		for _, wrapped := range wrapper {
+			value = wrapped
+		}
+	}
+	if _, ok := value.([]interface{}); !ok {
+		value = []interface{}{value}
+	}
+	return value
+}
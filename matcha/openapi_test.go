@@ -0,0 +1,243 @@
+package matcha
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const openAPIBooking = `{"id": "b-12", "status": "open", "seats": [1, 2], "total": 20.5, "note": null, "created": "2024-03-01T10:00:00Z"}`
+
+func openAPIRecorder(status int, contentType string, body string, headers map[string]string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	recorder.Header().Set("Content-Type", contentType)
+	for name, value := range headers {
+		recorder.Header().Set(name, value)
+	}
+	recorder.WriteHeader(status)
+	recorder.Body.WriteString(body)
+	return recorder
+}
+
+func TestReadOpenAPI(t *testing.T) {
+
+	Convey("Given an OpenAPI file", t, func() {
+
+		Convey("When it is a valid OpenAPI 3 document", func() {
+
+			spec, err := ReadOpenAPI("testdata/openapi/bookings.yaml")
+
+			Convey("It should be read", func() {
+				So(err, ShouldBeNil)
+				So(spec, ShouldNotBeNil)
+			})
+
+		})
+
+		Convey("When it is not an OpenAPI 3 document", func() {
+
+			directory, _ := ioutil.TempDir("", "openapi")
+			path := filepath.Join(directory, "swagger.json")
+			ioutil.WriteFile(path, []byte(`{"swagger": "2.0"}`), 0644)
+			_, err := ReadOpenAPI(path)
+
+			Convey("It should return an error", func() {
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEndWith, "is not an OpenAPI 3 document")
+			})
+
+		})
+
+		Convey("When it doesn't exist", func() {
+
+			_, err := ReadOpenAPI("testdata/openapi/missing.yaml")
+
+			Convey("It should return an error", func() {
+				So(err, ShouldNotBeNil)
+			})
+
+		})
+
+	})
+
+}
+
+func TestShouldMatchOpenAPIResponse(t *testing.T) {
+
+	spec, err := ReadOpenAPI("testdata/openapi/bookings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Convey("Given a response that matches its operation", t, func() {
+
+		recorder := openAPIRecorder(200, "application/json; charset=utf-8", openAPIBooking, map[string]string{"X-Rate-Limit": "100"})
+
+		Convey("It should match by operationId", func() {
+			So(ShouldMatchOpenAPIResponse(recorder, spec, "getBooking"), ShouldEqual, "")
+		})
+
+		Convey("It should match by method and path template", func() {
+			So(ShouldMatchOpenAPIResponse(recorder, spec, "GET /bookings/{booking_id}"), ShouldEqual, "")
+		})
+
+		Convey("It should match by method and path", func() {
+			So(ShouldMatchOpenAPIResponse(recorder, spec, "get /bookings/b-12"), ShouldEqual, "")
+		})
+
+		Convey("It should prefer a path without parameters", func() {
+			So(ShouldMatchOpenAPIResponse(recorder, spec, "GET /bookings/latest"), ShouldEqual, "")
+		})
+
+		Convey("It should match an *http.Response and leave its body to be read again", func() {
+			response := recorder.Result()
+			So(ShouldMatchOpenAPIResponse(response, spec, "getBooking"), ShouldEqual, "")
+			body, _ := ioutil.ReadAll(response.Body)
+			So(string(body), ShouldEqual, openAPIBooking)
+		})
+
+		Convey("It should match a body given with its status and headers", func() {
+			headers := http.Header{"X-Rate-Limit": {"5"}}
+			So(ShouldMatchOpenAPIResponse([]byte(openAPIBooking), spec, "getBooking", 200, headers), ShouldEqual, "")
+		})
+
+	})
+
+	Convey("Given a response that doesn't match its schema", t, func() {
+
+		body := `{"id": "x-1", "status": "lost", "seats": [1.5], "total": 0, "note": 4, "created": "yesterday"}`
+		recorder := openAPIRecorder(200, "application/json", body, map[string]string{"X-Rate-Limit": "0"})
+		failure := ShouldMatchOpenAPIResponse(recorder, spec, "getBooking")
+
+		Convey("It should describe each mismatch the way the matcher does", func() {
+			So(failure, ShouldContainSubstring, "header X-Rate-Limit: 0 is less than the minimum: 1")
+			So(failure, ShouldContainSubstring, "body.id: 'x-1' does not match expected pattern: ^b-[0-9]+$")
+			So(failure, ShouldContainSubstring, "body.status: 'lost' is not one of: open, closed")
+			So(failure, ShouldContainSubstring, "Expected 'body.seats[0]' to be: 'integer' (but was: 'float64')!")
+			So(failure, ShouldContainSubstring, "body.total: 0 is not more than the exclusive minimum: 0")
+			So(failure, ShouldContainSubstring, "Expected 'body.note' to be: 'string' (but was: 'float64')!")
+			So(failure, ShouldContainSubstring, "body.created: 'yesterday' isn't an RFC 3339 datetime")
+		})
+
+	})
+
+	Convey("Given a value outside a limit made exclusive by a boolean", t, func() {
+
+		body := strings.Replace(openAPIBooking, `"total": 20.5`, `"total": -1`, 1)
+		failure := ShouldMatchOpenAPIResponse(openAPIRecorder(200, "application/json", body, map[string]string{"X-Rate-Limit": "5"}), spec, "getBooking")
+
+		Convey("It should only report the exclusive limit", func() {
+			So(failure, ShouldEqual, "body.total: -1 is not more than the exclusive minimum: 0")
+			schema := openAPINode{value: map[string]interface{}{"maximum": 10.0, "exclusiveMaximum": true}}
+			So(validateNumber(11, schema, "total"), ShouldResemble, []string{"total: 11 is not less than the exclusive maximum: 10"})
+		})
+
+	})
+
+	Convey("Given a response missing required values", t, func() {
+
+		recorder := openAPIRecorder(200, "application/json", `{"status": "open", "seats": []}`, nil)
+		failure := ShouldMatchOpenAPIResponse(recorder, spec, "getBooking")
+
+		Convey("It should say what is missing", func() {
+			So(failure, ShouldContainSubstring, "No header 'X-Rate-Limit' found in response")
+			So(failure, ShouldContainSubstring, "No field 'body.id' found in response")
+			So(failure, ShouldContainSubstring, "body.seats: length 0 is less than the minimum: 1")
+		})
+
+	})
+
+	Convey("Given an error response", t, func() {
+
+		Convey("It should use the response for its status class from the components", func() {
+			So(ShouldMatchOpenAPIResponse(openAPIRecorder(404, "application/json", `{"message": "Not found"}`, nil), spec, "getBooking"), ShouldEqual, "")
+			So(ShouldMatchOpenAPIResponse(openAPIRecorder(404, "application/json", `{"message": "Not found", "code": 4}`, nil), spec, "getBooking"),
+				ShouldEqual, "Unexpected field 'body.code' found in response")
+		})
+
+		Convey("It should fail if the operation has no response for the status", func() {
+			So(ShouldMatchOpenAPIResponse(openAPIRecorder(500, "application/json", `{}`, nil), spec, "getBooking"),
+				ShouldEqual, "No response for status 500 found for operation: getBooking")
+		})
+
+	})
+
+	Convey("Given a schema in another local file", t, func() {
+
+		Convey("It should follow the reference and pick the oneOf schema from the discriminator", func() {
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "card", "last4": "1234"}, "amount": 12}`), spec, "getPayment", 200), ShouldEqual, "")
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "voucher"}}`), spec, "getPayment", 200),
+				ShouldEqual, "No field 'body.method.code' found in response")
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "card", "last4": "12"}}`), spec, "getPayment", 200),
+				ShouldEqual, "body.method.last4: length 2 is less than the minimum: 4")
+		})
+
+		Convey("It should check a value matches exactly one oneOf schema", func() {
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "card", "last4": "1234"}, "amount": 5}`), spec, "getPayment", 200),
+				ShouldEqual, "body.amount matches 2 of the oneOf schemas, but should match exactly one")
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "card", "last4": "1234"}, "amount": 12.5}`), spec, "getPayment", 200),
+				ShouldEqual, "body.amount matches none of the oneOf schemas\nExpected 'body.amount' to be: 'integer' (but was: 'float64')!")
+		})
+
+	})
+
+	Convey("Given a nullable property whose schema is an allOf of a $ref", t, func() {
+
+		Convey("It should accept null", func() {
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "card", "last4": "1234"}, "refund": null}`), spec, "getPayment", 200), ShouldEqual, "")
+		})
+
+		Convey("It should still check a value against the referenced schema", func() {
+			So(ShouldMatchOpenAPIResponse([]byte(`{"method": {"kind": "card", "last4": "1234"}, "refund": {"kind": "card"}}`), spec, "getPayment", 200),
+				ShouldEqual, "No field 'body.refund.last4' found in response")
+		})
+
+	})
+
+	Convey("Given an XML response", t, func() {
+
+		body := `<bookings><booking><id>b-1</id><status>open</status><seats>4</seats></booking>` +
+			`<booking><id>b-2</id><status>closed</status><seats>5</seats><seats>x</seats></booking></bookings>`
+		failure := ShouldMatchOpenAPIResponse(openAPIRecorder(200, "application/xml", body, nil), spec, "listBookings")
+
+		Convey("It should convert values to the types of their schemas", func() {
+			So(failure, ShouldEqual, "Expected 'body[1].seats[1]' to be: 'integer' (but was: 'string')!")
+		})
+
+	})
+
+	Convey("Given a response with a content type the operation doesn't have", t, func() {
+
+		failure := ShouldMatchOpenAPIResponse(openAPIRecorder(200, "text/csv", "id\nb-1", nil), spec, "listBookings")
+
+		Convey("It should list the media types it does have", func() {
+			So(failure, ShouldEqual, "Content type 'text/csv' is not one of the response's media types: application/json, application/xml")
+		})
+
+	})
+
+	Convey("Given a response that has no content", t, func() {
+
+		Convey("It should only check the status", func() {
+			So(ShouldMatchOpenAPIResponse(openAPIRecorder(204, "", "", nil), spec, "getLatestBooking"), ShouldEqual, "")
+		})
+
+	})
+
+	Convey("Given an operation that doesn't exist", t, func() {
+
+		Convey("It should return an error string", func() {
+			So(ShouldMatchOpenAPIResponse(bytes.NewBufferString(""), spec, "getBooking"), ShouldStartWith, "Expected first argument")
+			So(ShouldMatchOpenAPIResponse([]byte("{}"), spec, "deleteBooking", 200), ShouldEqual, "No operation 'deleteBooking' found in the OpenAPI document")
+			So(ShouldMatchOpenAPIResponse([]byte("{}"), spec, "getBooking"), ShouldStartWith, "ShouldMatchOpenAPIResponse expects")
+		})
+
+	})
+
+}
@@ -0,0 +1,58 @@
+package matcha
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Option changes how an assertion matches a document. Options are passed after the map to hold
+// captured values, e.g.
+//
+//	So(response, ShouldMatchExpectedJSONResponse, expected{}, capturedValues, Capture("$.items[*].price", "prices"))
+type Option func(*Matcher)
+
+type pathCapture struct {
+	path string
+	key  string
+}
+
+// Capture captures every value at a JSONPath under a key, whether or not it is in the expected struct
+func Capture(path string, key string) Option {
+	return func(m *Matcher) {
+		m.pathCaptures = append(m.pathCaptures, pathCapture{path: path, key: key})
+	}
+}
+
+// applyOptions applies the options that follow the captured values in an assertion's arguments
+func (m *Matcher) applyOptions(options []interface{}) string {
+	for i, option := range options {
+		apply, ok := option.(Option)
+		if !ok {
+			return fmt.Sprintf("Expected argument %d to be an Option (but was: %T)!", i+4, option)
+		}
+		apply(m)
+	}
+	return success
+}
+
+// capturePaths captures the values at the paths of Capture options
+func (m *Matcher) capturePaths(actual interface{}) string {
+	var errorList []string
+	for _, capture := range m.pathCaptures {
+		steps, err := compileJSONPath(capture.path)
+		if err != nil {
+			errorList = append(errorList, err.Error())
+			continue
+		}
+		if m.capturedValues == nil {
+			continue
+		}
+		for _, value := range evaluateJSONPath(steps, actual, actual) {
+			m.capturedValues[capture.key] = append(m.capturedValues[capture.key], value)
+		}
+	}
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
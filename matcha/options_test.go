@@ -0,0 +1,57 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedStoreName struct {
+	Store struct {
+		Name string `json:"name"`
+	} `json:"store"`
+}
+
+func TestCaptureOption(t *testing.T) {
+
+	Convey("Given a document with values that aren't in the expected struct", t, func() {
+
+		document := []byte(jsonPathStore)
+
+		Convey("When they are captured by JSONPath", func() {
+
+			capturedValues := make(CapturedValues)
+			success := ShouldMatchExpectedJSONResponse(document, expectedStoreName{}, capturedValues,
+				Capture("$.store.items[?(@.price < 40)].price", "prices"),
+				Capture("$.store.manager.name", "manager"),
+			)
+
+			Convey("It should capture them in the same call", func() {
+				So(success, ShouldEqual, "")
+				So(capturedValues["prices"], ShouldResemble, []interface{}{30.5, 12.0, 5.0})
+				So(capturedValues["manager"], ShouldResemble, []interface{}{"Sam"})
+			})
+
+		})
+
+		Convey("When a path is invalid", func() {
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedXMLResponse([]byte("<store/>"), expectedStoreName{}, nil, Capture("$.[", "x"))
+				So(failure, ShouldEqual, "Received invalid JSONPath: $.[ (expected a name at position 2)")
+			})
+
+		})
+
+		Convey("When an argument isn't an Option", func() {
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedJSONResponse(document, expectedStoreName{}, nil, "$.store")
+				So(failure, ShouldEqual, "Expected argument 4 to be an Option (but was: string)!")
+			})
+
+		})
+
+	})
+
+}
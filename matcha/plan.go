@@ -0,0 +1,184 @@
+package matcha
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	snakecase "github.com/segmentio/go-snakecase"
+)
+
+// Plan is an expected type whose struct tags have been checked and compiled in advance. It can be
+// passed to any assertion in place of the expected struct, and shared between goroutines.
+type Plan struct {
+	expectedType reflect.Type
+	err          error
+}
+
+var (
+	plans      sync.Map // Compiled plans, by the expected type
+	fieldPlans sync.Map // Compiled fields, by fieldKey
+)
+
+// fieldKey identifies a field by everything its plan depends on
+type fieldKey struct {
+	name string
+	tag  reflect.StructTag
+	typ  reflect.Type
+}
+
+// fieldPlan holds the parts of a field's tags that would otherwise be worked out on every match
+type fieldPlan struct {
+	snakeName    string         // The field name to use when there is no tag for the format
+	pattern      *regexp.Regexp // The compiled 'pattern' tag, or nil
+	patternError string         // Why the 'pattern' tag can't be used
+	limits       []fieldLimit   // The 'min' and 'max' tags
+	limitError   string         // Why a 'min' or 'max' tag can't be used
+	enum         []string       // The values in the 'enum' tag, or nil
+	valueTags    bool           // Whether the field has tags that need its whole value
+}
+
+type fieldLimit struct {
+	constraint string // 'min' or 'max'
+	tag        string
+	value      float64
+}
+
+// Compile checks the tags of an expected struct, and of every struct it contains, and compiles them
+// into a plan. Plans are cached, so compiling the same type again is cheap. Invalid patterns and
+// constraints are returned as an error here instead of when a document is matched.
+//
+//	var bookingPlan = matcha.MustCompile(expectedBooking{})
+//	So(response, matcha.ShouldMatchExpectedJSONResponse, bookingPlan, capturedValues)
+func Compile(expected interface{}) (*Plan, error) {
+	expectedType := reflect.TypeOf(expected)
+	if expectedType == nil {
+		return nil, errors.New("Can't compile a plan for nil")
+	}
+	if cached, ok := plans.Load(expectedType); ok {
+		plan := cached.(*Plan)
+		if plan.err != nil {
+			return nil, plan.err
+		}
+		return plan, nil
+	}
+
+	var errorList []string
+	compileType(expectedType, make(map[reflect.Type]bool), &errorList)
+	plan := &Plan{expectedType: expectedType}
+	if errorList != nil {
+		plan.err = errors.New(strings.Join(errorList, "\n"))
+	}
+	plans.Store(expectedType, plan)
+	return Compile(expected)
+}
+
+// MustCompile is like Compile but panics if the expected struct has invalid tags
+func MustCompile(expected interface{}) *Plan {
+	plan, err := Compile(expected)
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
+// expectedTypeOf returns the type an expected argument describes, which may be given as a Plan
+func expectedTypeOf(expected interface{}) reflect.Type {
+	if plan, ok := expected.(*Plan); ok {
+		return plan.expectedType
+	}
+	return reflect.TypeOf(expected)
+}
+
+func compileType(expectedType reflect.Type, seen map[reflect.Type]bool, errorList *[]string) {
+	if seen[expectedType] {
+		return
+	}
+	seen[expectedType] = true
+
+	switch expectedType.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Ptr, reflect.Map:
+		compileType(expectedType.Elem(), seen, errorList)
+	case reflect.Struct:
+		if expectedType == timeType {
+			return
+		}
+		for i := 0; i < expectedType.NumField(); i++ {
+			field := expectedType.Field(i)
+			plan := compiledField(field)
+			for _, fieldError := range []string{plan.patternError, plan.limitError} {
+				if fieldError != "" {
+					*errorList = append(*errorList, fieldError)
+				}
+			}
+			*errorList = append(*errorList, literalErrors(field)...)
+			compileType(field.Type, seen, errorList)
+		}
+	}
+}
+
+// literalErrors checks the values in 'equals' and 'in' tags that aren't variables
+func literalErrors(field reflect.StructField) []string {
+	var errorList []string
+	if tag, ok := field.Tag.Lookup("equals"); ok && !variablePattern.MatchString(tag) {
+		if _, err := literalValue("equals", tag, field); err != nil {
+			errorList = append(errorList, err.Error())
+		}
+	}
+	if tag, ok := field.Tag.Lookup("in"); ok {
+		for _, part := range strings.Split(tag, "|") {
+			if variablePattern.MatchString(part) {
+				continue
+			}
+			if _, err := literalValue("in", part, field); err != nil {
+				errorList = append(errorList, err.Error())
+			}
+		}
+	}
+	return errorList
+}
+
+// compiledField returns the plan for a field, compiling it the first time the field is seen
+func compiledField(field reflect.StructField) *fieldPlan {
+	key := fieldKey{name: field.Name, tag: field.Tag, typ: field.Type}
+	if cached, ok := fieldPlans.Load(key); ok {
+		return cached.(*fieldPlan)
+	}
+
+	plan := &fieldPlan{snakeName: snakecase.Snakecase(field.Name)}
+	if pattern, ok := field.Tag.Lookup("pattern"); ok {
+		if field.Type.Kind() != reflect.String {
+			plan.patternError = fmt.Sprintf("'pattern' tag cannot be used on non-string fields: %v", field.Name)
+		} else if compiled, err := regexp.Compile(pattern); err != nil {
+			plan.patternError = fmt.Sprintf("Received invalid regular expression: %v", pattern)
+		} else {
+			plan.pattern = compiled
+		}
+	}
+	for _, constraint := range []string{"min", "max"} {
+		limitTag, ok := field.Tag.Lookup(constraint)
+		if !ok {
+			continue
+		}
+		limit, err := strconv.ParseFloat(limitTag, 64)
+		if err != nil && plan.limitError == "" {
+			plan.limitError = fmt.Sprintf("Received invalid '%v' constraint: %v", constraint, limitTag)
+		}
+		plan.limits = append(plan.limits, fieldLimit{constraint: constraint, tag: limitTag, value: limit})
+	}
+	if enumTag, ok := field.Tag.Lookup("enum"); ok {
+		plan.enum = strings.Split(enumTag, "|")
+	}
+	for _, tag := range []string{"capture", "pattern", "min", "max", "enum", "equals", "in"} {
+		if _, ok := field.Tag.Lookup(tag); ok {
+			plan.valueTags = true
+		}
+	}
+
+	cached, _ := fieldPlans.LoadOrStore(key, plan)
+	return cached.(*fieldPlan)
+}
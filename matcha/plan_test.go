@@ -0,0 +1,128 @@
+package matcha
+
+import (
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedPlanItem struct {
+	Code  string  `json:"code" pattern:"^[A-Z]{3}$"`
+	Price float64 `json:"price" min:"0"`
+}
+
+type expectedPlanOrder struct {
+	OrderID string             `json:"order_id" pattern:"^o-[0-9]+$" capture:"order_id"`
+	Status  string             `json:"status" enum:"open|closed"`
+	Items   []expectedPlanItem `json:"items" min:"1"`
+}
+
+type invalidPlanItem struct {
+	Code  string `json:"code" pattern:"^[A-Z"`
+	Count int    `json:"count" max:"lots" equals:"one"`
+}
+
+type invalidPlanOrder struct {
+	Items  []invalidPlanItem `json:"items"`
+	Total  float64           `json:"total" pattern:"[0-9]+" in:"1|two|${total}"`
+	Parent *invalidPlanOrder `json:"parent"`
+}
+
+func TestCompile(t *testing.T) {
+
+	Convey("Given an expected struct with valid tags", t, func() {
+
+		plan, err := Compile(expectedPlanOrder{})
+
+		Convey("It should compile a plan", func() {
+			So(err, ShouldBeNil)
+			So(plan, ShouldNotBeNil)
+		})
+
+		Convey("It should return the same plan when compiled again", func() {
+			again, _ := Compile(expectedPlanOrder{})
+			So(again, ShouldEqual, plan)
+		})
+
+		Convey("The plan should be usable in place of the expected struct", func() {
+			capturedValues := make(CapturedValues)
+			document := []byte(`{"order_id": "o-12", "status": "open", "items": [{"code": "ABC", "price": 2.5}]}`)
+			So(ShouldMatchExpectedJSONResponse(document, plan, capturedValues), ShouldEqual, "")
+			So(capturedValues["order_id"], ShouldResemble, []interface{}{"o-12"})
+
+			document = []byte(`{"order_id": "o-12", "status": "lost", "items": [{"code": "abc", "price": -1}]}`)
+			failure := ShouldMatchExpectedJSONResponse(document, plan, nil)
+			So(failure, ShouldContainSubstring, "Status: 'lost' is not one of: open, closed")
+			So(failure, ShouldContainSubstring, "Code: 'abc' does not match expected pattern: ^[A-Z]{3}$")
+			So(failure, ShouldContainSubstring, "Price: -1 is less than the minimum: 0")
+		})
+
+		Convey("The plan should be usable by other formats", func() {
+			xmlPlan := MustCompile(expectedXMLString{})
+			So(ShouldMatchExpectedXMLResponse([]byte("<string_field>some string</string_field>"), xmlPlan, nil), ShouldEqual, "")
+		})
+
+		Convey("The plan should be safe to share between goroutines", func() {
+			var wait sync.WaitGroup
+			results := make([]string, 20)
+			for i := range results {
+				wait.Add(1)
+				go func(i int) {
+					defer wait.Done()
+					results[i] = ShouldMatchExpectedJSONResponse([]byte(`{"order_id": "o-1", "status": "open", "items": [{"code": "ABC", "price": 1}]}`), plan, nil)
+				}(i)
+			}
+			wait.Wait()
+			for _, result := range results {
+				So(result, ShouldEqual, "")
+			}
+		})
+
+	})
+
+	Convey("Given an expected struct with invalid tags", t, func() {
+
+		plan, err := Compile(invalidPlanOrder{})
+
+		Convey("It should return every problem as an error", func() {
+			So(plan, ShouldBeNil)
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldContainSubstring, "Received invalid regular expression: ^[A-Z")
+			So(err.Error(), ShouldContainSubstring, "Received invalid 'max' constraint: lots")
+			So(err.Error(), ShouldContainSubstring, "Received invalid 'equals' value for int field Count: one")
+			So(err.Error(), ShouldContainSubstring, "'pattern' tag cannot be used on non-string fields: Total")
+			So(err.Error(), ShouldContainSubstring, "Received invalid 'in' value for float64 field Total: two")
+			So(err.Error(), ShouldNotContainSubstring, "${total}")
+		})
+
+		Convey("It should return the error when compiled again", func() {
+			_, again := Compile(invalidPlanOrder{})
+			So(again, ShouldResemble, err)
+		})
+
+		Convey("MustCompile should panic", func() {
+			So(func() { MustCompile(invalidPlanOrder{}) }, ShouldPanic)
+		})
+
+	})
+
+	Convey("Given nil", t, func() {
+
+		Convey("It should return an error", func() {
+			_, err := Compile(nil)
+			So(err, ShouldNotBeNil)
+		})
+
+	})
+
+}
+
+func BenchmarkShouldMatchExpectedJSONResponse(b *testing.B) {
+	document := []byte(`{"order_id": "o-12", "status": "open", "items": [{"code": "ABC", "price": 2.5}, {"code": "DEF", "price": 4}]}`)
+	plan := MustCompile(expectedPlanOrder{})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ShouldMatchExpectedJSONResponse(document, plan, nil)
+	}
+}
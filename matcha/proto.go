@@ -0,0 +1,278 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ProtoMessage is a message type that protobuf messages can be decoded as
+type ProtoMessage struct {
+	types       *ProtoTypes
+	messageType *protoMessageType
+}
+
+// ShouldMatchExpectedProto decodes a protobuf message with a type from ReadProtoDescriptors and matches
+// it like a JSON document, with field names from 'proto' tags or the snake case of the field name.
+//
+// Fields that can be missing, such as messages, oneof members and optional fields, are only in the
+// decoded message if they were set, while other fields have their default value if they weren't.
+// Maps are decoded as objects, enums as the name of their value and google.protobuf.Timestamp
+// messages as a time.Time.
+func ShouldMatchExpectedProto(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 3 {
+		return fmt.Sprintf("ShouldMatchExpectedProto expects four arguments: the actual protobuf message as a byte slice, the expected format as a Struct, a map to hold captured values, and the message type as a *ProtoMessage")
+	}
+
+	actualProto, ok := actual.([]byte)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be a byte slice")
+	}
+	message, ok := expectedList[2].(*ProtoMessage)
+	if !ok || message == nil {
+		return fmt.Sprintf("Expected fourth argument to be a *ProtoMessage")
+	}
+	actualResponse, err := message.Decode(actualProto)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to decode protobuf message: %v", err)
+	}
+	return shouldMatchBinaryDocument("proto", "Protobuf", actualResponse, expectedList[:2])
+}
+
+// Decode decodes a message into the same maps and slices that JSON documents are decoded into
+func (p *ProtoMessage) Decode(data []byte) (interface{}, error) {
+	message := make(map[string]interface{})
+	if err := p.types.decode(data, p.messageType, message); err != nil {
+		return nil, err
+	}
+	return p.types.finish(p.messageType, message), nil
+}
+
+func (t *ProtoTypes) decode(data []byte, messageType *protoMessageType, message map[string]interface{}) error {
+	wireFields, err := readProtoFields(data)
+	if err != nil {
+		return err
+	}
+	for _, wireField := range wireFields {
+		field, ok := messageType.fields[wireField.number]
+		if !ok {
+			// Unknown fields are skipped
+			continue
+		}
+		if err := t.decodeField(messageType, field, wireField, message); err != nil {
+			return fmt.Errorf("field '%v': %v", field.name, err)
+		}
+	}
+	return nil
+}
+
+func (t *ProtoTypes) decodeField(messageType *protoMessageType, field *protoField, wireField protoWireField, message map[string]interface{}) error {
+	if field.fieldType == protoTypeMessage || field.fieldType == protoTypeGroup {
+		fieldType, ok := t.messages[field.typeName]
+		if !ok {
+			return fmt.Errorf("unknown message type '%v'", field.typeName)
+		}
+		if wireField.wireType != protoWireBytes && wireField.wireType != protoWireStartGroup {
+			return fmt.Errorf("unexpected wire type %d", wireField.wireType)
+		}
+
+		if field.label == protoLabelRepeated && fieldType.mapEntry {
+			return t.decodeMapEntry(field, fieldType, wireField.data, message)
+		}
+		if field.label == protoLabelRepeated {
+			value := make(map[string]interface{})
+			if err := t.decode(wireField.data, fieldType, value); err != nil {
+				return err
+			}
+			values, _ := message[field.name].([]interface{})
+			message[field.name] = append(values, value)
+			return nil
+		}
+
+		// A message that is set more than once is merged
+		clearOneof(messageType, field, message)
+		value, ok := message[field.name].(map[string]interface{})
+		if !ok {
+			value = make(map[string]interface{})
+		}
+		message[field.name] = value
+		return t.decode(wireField.data, fieldType, value)
+	}
+
+	if field.label != protoLabelRepeated {
+		value, err := t.protoScalar(field, wireField.wireType, wireField.value, wireField.data)
+		if err != nil {
+			return err
+		}
+		clearOneof(messageType, field, message)
+		message[field.name] = value
+		return nil
+	}
+
+	values, _ := message[field.name].([]interface{})
+	if wireField.wireType == protoWireBytes && protoWireTypeOf(field.fieldType) != protoWireBytes {
+		// Packed numbers
+		reader := &binaryReader{data: wireField.data}
+		for reader.offset < len(wireField.data) {
+			var number uint64
+			var err error
+			switch protoWireTypeOf(field.fieldType) {
+			case protoWireFixed64:
+				number, err = reader.fixed(8)
+			case protoWireFixed32:
+				number, err = reader.fixed(4)
+			default:
+				number, err = reader.varint()
+			}
+			if err != nil {
+				return err
+			}
+			value, _ := t.protoScalar(field, protoWireTypeOf(field.fieldType), number, nil)
+			values = append(values, value)
+		}
+	} else {
+		value, err := t.protoScalar(field, wireField.wireType, wireField.value, wireField.data)
+		if err != nil {
+			return err
+		}
+		values = append(values, value)
+	}
+	message[field.name] = values
+	return nil
+}
+
+func (t *ProtoTypes) decodeMapEntry(field *protoField, entryType *protoMessageType, data []byte, message map[string]interface{}) error {
+	entry := make(map[string]interface{})
+	if err := t.decode(data, entryType, entry); err != nil {
+		return err
+	}
+	entry = t.finish(entryType, entry).(map[string]interface{})
+	values, ok := message[field.name].(map[string]interface{})
+	if !ok {
+		values = make(map[string]interface{})
+		message[field.name] = values
+	}
+	values[mapKey(entry["key"])] = entry["value"]
+	return nil
+}
+
+// clearOneof removes the other fields in the same oneof, as only the last one set is kept
+func clearOneof(messageType *protoMessageType, field *protoField, message map[string]interface{}) {
+	if field.oneof < 0 {
+		return
+	}
+	for _, other := range messageType.fields {
+		if other != field && other.oneof == field.oneof {
+			delete(message, other.name)
+		}
+	}
+}
+
+// finish fills in the default value of fields that weren't set and converts well-known types
+func (t *ProtoTypes) finish(messageType *protoMessageType, message map[string]interface{}) interface{} {
+	for _, field := range messageType.fields {
+		value, ok := message[field.name]
+		if !ok {
+			if !field.hasPresence || messageType.mapEntry {
+				message[field.name] = t.protoDefault(field)
+			}
+			continue
+		}
+		fieldType, isMessage := t.messages[field.typeName]
+		if !isMessage || fieldType.mapEntry {
+			continue
+		}
+		switch fieldValue := value.(type) {
+		case map[string]interface{}:
+			message[field.name] = t.finish(fieldType, fieldValue)
+		case []interface{}:
+			for i, element := range fieldValue {
+				fieldValue[i] = t.finish(fieldType, element.(map[string]interface{}))
+			}
+		}
+	}
+
+	if messageType.name == ".google.protobuf.Timestamp" {
+		seconds, _ := message["seconds"].(int64)
+		nanoseconds, _ := message["nanos"].(int64)
+		return time.Unix(seconds, nanoseconds).UTC()
+	}
+	return message
+}
+
+func (t *ProtoTypes) protoDefault(field *protoField) interface{} {
+	if field.label == protoLabelRepeated {
+		if fieldType, ok := t.messages[field.typeName]; ok && fieldType.mapEntry {
+			return map[string]interface{}{}
+		}
+		return []interface{}{}
+	}
+	switch field.fieldType {
+	case protoTypeDouble, protoTypeFloat:
+		return 0.0
+	case protoTypeBool:
+		return false
+	case protoTypeString:
+		return ""
+	case protoTypeBytes:
+		return []byte{}
+	case protoTypeEnum:
+		if name, ok := t.enums[field.typeName][0]; ok {
+			return name
+		}
+	case protoTypeMessage, protoTypeGroup:
+		return map[string]interface{}{}
+	}
+	return int64(0)
+}
+
+func (t *ProtoTypes) protoScalar(field *protoField, wireType uint64, value uint64, data []byte) (interface{}, error) {
+	if expectedWireType := protoWireTypeOf(field.fieldType); wireType != expectedWireType {
+		return nil, fmt.Errorf("unexpected wire type %d (expected %d)", wireType, expectedWireType)
+	}
+	switch field.fieldType {
+	case protoTypeDouble:
+		return math.Float64frombits(value), nil
+	case protoTypeFloat:
+		return float64(math.Float32frombits(uint32(value))), nil
+	case protoTypeInt64, protoTypeSfixed64:
+		return int64(value), nil
+	case protoTypeUint64, protoTypeFixed64:
+		return unsignedValue(value), nil
+	case protoTypeInt32, protoTypeSfixed32:
+		return int64(int32(value)), nil
+	case protoTypeUint32, protoTypeFixed32:
+		return int64(uint32(value)), nil
+	case protoTypeSint32, protoTypeSint64:
+		return int64(value>>1) ^ -int64(value&1), nil
+	case protoTypeBool:
+		return value != 0, nil
+	case protoTypeString:
+		return string(data), nil
+	case protoTypeBytes:
+		return append([]byte{}, data...), nil
+	case protoTypeEnum:
+		number := int64(int32(value))
+		if name, ok := t.enums[field.typeName][number]; ok {
+			return name, nil
+		}
+		return number, nil
+	}
+	return nil, fmt.Errorf("unknown field type %d", field.fieldType)
+}
+
+func protoWireTypeOf(fieldType uint64) uint64 {
+	switch fieldType {
+	case protoTypeDouble, protoTypeFixed64, protoTypeSfixed64:
+		return protoWireFixed64
+	case protoTypeFloat, protoTypeFixed32, protoTypeSfixed32:
+		return protoWireFixed32
+	case protoTypeString, protoTypeBytes, protoTypeMessage:
+		return protoWireBytes
+	case protoTypeGroup:
+		return protoWireStartGroup
+	}
+	return protoWireVarint
+}
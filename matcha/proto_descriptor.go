@@ -0,0 +1,327 @@
+package matcha
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Field labels and types from descriptor.proto
+const (
+	protoLabelRepeated = 3
+
+	protoTypeDouble   = 1
+	protoTypeFloat    = 2
+	protoTypeInt64    = 3
+	protoTypeUint64   = 4
+	protoTypeInt32    = 5
+	protoTypeFixed64  = 6
+	protoTypeFixed32  = 7
+	protoTypeBool     = 8
+	protoTypeString   = 9
+	protoTypeGroup    = 10
+	protoTypeMessage  = 11
+	protoTypeBytes    = 12
+	protoTypeUint32   = 13
+	protoTypeEnum     = 14
+	protoTypeSfixed32 = 15
+	protoTypeSfixed64 = 16
+	protoTypeSint32   = 17
+	protoTypeSint64   = 18
+)
+
+// Wire types
+const (
+	protoWireVarint     = 0
+	protoWireFixed64    = 1
+	protoWireBytes      = 2
+	protoWireStartGroup = 3
+	protoWireEndGroup   = 4
+	protoWireFixed32    = 5
+)
+
+// ProtoTypes are the message and enum types read from a serialized FileDescriptorSet, such as the
+// output of 'protoc --descriptor_set_out'. Messages can be decoded with them without generated code.
+type ProtoTypes struct {
+	messages map[string]*protoMessageType
+	enums    map[string]map[int64]string
+}
+
+type protoMessageType struct {
+	name     string // Fully qualified, with a leading '.'
+	fields   map[uint64]*protoField
+	mapEntry bool
+}
+
+type protoField struct {
+	name        string
+	number      uint64
+	label       uint64
+	fieldType   uint64
+	typeName    string
+	oneof       int  // Index of the oneof the field is in, or -1
+	hasPresence bool // Whether the field can be missing, rather than having a default value
+}
+
+// protoWireField is a field as it is encoded, before its type is known
+type protoWireField struct {
+	number   uint64
+	wireType uint64
+	value    uint64 // Varint, fixed32 and fixed64 values
+	data     []byte // Length-delimited values and the contents of groups
+}
+
+// ReadProtoDescriptors reads the types from a serialized FileDescriptorSet
+func ReadProtoDescriptors(data []byte) (*ProtoTypes, error) {
+	types := &ProtoTypes{
+		messages: make(map[string]*protoMessageType),
+		enums:    make(map[string]map[int64]string),
+	}
+	files, err := readProtoFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("Was not possible to read FileDescriptorSet: %v", err)
+	}
+	for _, file := range files {
+		if file.number == 1 && file.wireType == protoWireBytes {
+			if err := types.addFile(file.data); err != nil {
+				return nil, fmt.Errorf("Was not possible to read FileDescriptorSet: %v", err)
+			}
+		}
+	}
+	return types, nil
+}
+
+// Message finds a message type by its fully qualified name, e.g. 'booking.v1.Booking'
+func (t *ProtoTypes) Message(name string) (*ProtoMessage, error) {
+	messageType, ok := t.messages["."+strings.TrimPrefix(name, ".")]
+	if !ok {
+		return nil, fmt.Errorf("No message type '%v' found in descriptors", name)
+	}
+	return &ProtoMessage{types: t, messageType: messageType}, nil
+}
+
+func (t *ProtoTypes) addFile(data []byte) error {
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return err
+	}
+	prefix, syntax := "", "proto2"
+	for _, field := range fields {
+		switch field.number {
+		case 2:
+			prefix = "." + string(field.data)
+		case 12:
+			syntax = string(field.data)
+		}
+	}
+	for _, field := range fields {
+		switch field.number {
+		case 4:
+			err = t.addMessage(prefix, field.data, syntax)
+		case 5:
+			err = t.addEnum(prefix, field.data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *ProtoTypes) addMessage(prefix string, data []byte, syntax string) error {
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return err
+	}
+	message := &protoMessageType{fields: make(map[uint64]*protoField)}
+	for _, field := range fields {
+		switch field.number {
+		case 1:
+			message.name = prefix + "." + string(field.data)
+		case 2:
+			messageField, err := readProtoField(field.data)
+			if err != nil {
+				return err
+			}
+			messageField.hasPresence = messageField.label != protoLabelRepeated &&
+				(syntax != "proto3" || messageField.oneof >= 0 ||
+					messageField.fieldType == protoTypeMessage || messageField.fieldType == protoTypeGroup)
+			message.fields[messageField.number] = messageField
+		case 7:
+			options, err := readProtoFields(field.data)
+			if err != nil {
+				return err
+			}
+			for _, option := range options {
+				if option.number == 7 {
+					message.mapEntry = option.value != 0
+				}
+			}
+		}
+	}
+	t.messages[message.name] = message
+
+	for _, field := range fields {
+		switch field.number {
+		case 3:
+			err = t.addMessage(message.name, field.data, syntax)
+		case 4:
+			err = t.addEnum(message.name, field.data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readProtoField(data []byte) (*protoField, error) {
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return nil, err
+	}
+	field := &protoField{oneof: -1}
+	for _, wireField := range fields {
+		switch wireField.number {
+		case 1:
+			field.name = string(wireField.data)
+		case 3:
+			field.number = wireField.value
+		case 4:
+			field.label = wireField.value
+		case 5:
+			field.fieldType = wireField.value
+		case 6:
+			field.typeName = string(wireField.data)
+		case 9:
+			field.oneof = int(wireField.value)
+		}
+	}
+	return field, nil
+}
+
+func (t *ProtoTypes) addEnum(prefix string, data []byte) error {
+	fields, err := readProtoFields(data)
+	if err != nil {
+		return err
+	}
+	name := ""
+	values := make(map[int64]string)
+	for _, field := range fields {
+		switch field.number {
+		case 1:
+			name = prefix + "." + string(field.data)
+		case 2:
+			valueFields, err := readProtoFields(field.data)
+			if err != nil {
+				return err
+			}
+			valueName, number := "", int64(0)
+			for _, valueField := range valueFields {
+				switch valueField.number {
+				case 1:
+					valueName = string(valueField.data)
+				case 2:
+					number = int64(int32(valueField.value))
+				}
+			}
+			// Aliases share a number, and the first name is used
+			if _, ok := values[number]; !ok {
+				values[number] = valueName
+			}
+		}
+	}
+	t.enums[name] = values
+	return nil
+}
+
+func readProtoFields(data []byte) ([]protoWireField, error) {
+	reader := &binaryReader{data: data}
+	var fields []protoWireField
+	for reader.offset < len(data) {
+		field, err := reader.protoWireField()
+		if err != nil {
+			return nil, err
+		}
+		if field.wireType == protoWireEndGroup {
+			return nil, fmt.Errorf("unexpected end of group %d", field.number)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func (r *binaryReader) varint() (uint64, error) {
+	var value uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b, err := r.uint(1)
+		if err != nil {
+			return 0, err
+		}
+		value |= (b & 0x7f) << shift
+		if b < 0x80 {
+			return value, nil
+		}
+	}
+	return 0, fmt.Errorf("varint is too long at byte %d", r.offset)
+}
+
+// fixed reads a little-endian number, unlike the big-endian numbers of other binary formats
+func (r *binaryReader) fixed(size int) (uint64, error) {
+	value, err := r.next(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	if size == 4 {
+		return uint64(binary.LittleEndian.Uint32(value)), nil
+	}
+	return binary.LittleEndian.Uint64(value), nil
+}
+
+func (r *binaryReader) protoWireField() (protoWireField, error) {
+	start := r.offset
+	key, err := r.varint()
+	if err != nil {
+		return protoWireField{}, err
+	}
+	field := protoWireField{number: key >> 3, wireType: key & 7}
+	if field.number == 0 {
+		return field, fmt.Errorf("invalid field number 0 at byte %d", start)
+	}
+
+	switch field.wireType {
+	case protoWireVarint:
+		field.value, err = r.varint()
+	case protoWireFixed64:
+		field.value, err = r.fixed(8)
+	case protoWireFixed32:
+		field.value, err = r.fixed(4)
+	case protoWireBytes:
+		var length uint64
+		length, err = r.varint()
+		if err == nil {
+			field.data, err = r.next(length)
+		}
+	case protoWireStartGroup:
+		groupStart := r.offset
+		for {
+			groupEnd := r.offset
+			var groupField protoWireField
+			groupField, err = r.protoWireField()
+			if err != nil {
+				break
+			}
+			if groupField.wireType == protoWireEndGroup {
+				if groupField.number != field.number {
+					err = fmt.Errorf("group %d ended by group %d at byte %d", field.number, groupField.number, groupEnd)
+				}
+				field.data = r.data[groupStart:groupEnd]
+				break
+			}
+		}
+	case protoWireEndGroup:
+	default:
+		err = fmt.Errorf("invalid wire type %d at byte %d", field.wireType, start)
+	}
+	return field, err
+}
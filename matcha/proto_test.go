@@ -0,0 +1,252 @@
+package matcha
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// Helpers to encode descriptors and messages, since there is no generated code
+
+func protoVarint(value uint64) []byte {
+	var encoded []byte
+	for value >= 0x80 {
+		encoded = append(encoded, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(encoded, byte(value))
+}
+
+func protoInt(number uint64, value uint64) []byte {
+	return append(protoVarint(number<<3|protoWireVarint), protoVarint(value)...)
+}
+
+func protoBytes(number uint64, parts ...[]byte) []byte {
+	var data []byte
+	for _, part := range parts {
+		data = append(data, part...)
+	}
+	encoded := append(protoVarint(number<<3|protoWireBytes), protoVarint(uint64(len(data)))...)
+	return append(encoded, data...)
+}
+
+func protoString(number uint64, value string) []byte {
+	return protoBytes(number, []byte(value))
+}
+
+func protoFieldDescriptor(name string, number uint64, label uint64, fieldType uint64, options ...[]byte) []byte {
+	parts := [][]byte{protoString(1, name), protoInt(3, number), protoInt(4, label), protoInt(5, fieldType)}
+	return protoBytes(2, append(parts, options...)...)
+}
+
+var protoDescriptorSet = protoBytes(1,
+	protoString(1, "google/protobuf/timestamp.proto"),
+	protoString(2, "google.protobuf"),
+	protoBytes(4,
+		protoString(1, "Timestamp"),
+		protoFieldDescriptor("seconds", 1, 1, protoTypeInt64),
+		protoFieldDescriptor("nanos", 2, 1, protoTypeInt32),
+	),
+	protoString(12, "proto3"),
+)
+
+var bookingDescriptorSet = append(protoDescriptorSet, protoBytes(1,
+	protoString(1, "booking.proto"),
+	protoString(2, "booking"),
+	protoBytes(4,
+		protoString(1, "Booking"),
+		protoFieldDescriptor("id", 1, 1, protoTypeString),
+		protoFieldDescriptor("seats", 2, 1, protoTypeInt32),
+		protoFieldDescriptor("tags", 3, 3, protoTypeString),
+		protoFieldDescriptor("prices", 4, 3, protoTypeMessage, protoString(6, ".booking.Booking.PricesEntry")),
+		protoFieldDescriptor("card", 5, 1, protoTypeString, protoInt(9, 0)),
+		protoFieldDescriptor("voucher", 6, 1, protoTypeBool, protoInt(9, 0)),
+		protoFieldDescriptor("customer", 7, 1, protoTypeMessage, protoString(6, ".booking.Booking.Customer")),
+		protoFieldDescriptor("note", 8, 1, protoTypeString, protoInt(9, 1), protoInt(17, 1)),
+		protoFieldDescriptor("status", 9, 1, protoTypeEnum, protoString(6, ".booking.Booking.Status")),
+		protoFieldDescriptor("deltas", 10, 3, protoTypeSint32),
+		protoFieldDescriptor("ticket", 11, 1, protoTypeBytes),
+		protoFieldDescriptor("total", 12, 1, protoTypeDouble),
+		protoFieldDescriptor("created", 13, 1, protoTypeMessage, protoString(6, ".google.protobuf.Timestamp")),
+		protoBytes(3,
+			protoString(1, "Customer"),
+			protoFieldDescriptor("name", 1, 1, protoTypeString),
+		),
+		protoBytes(3,
+			protoString(1, "PricesEntry"),
+			protoFieldDescriptor("key", 1, 1, protoTypeString),
+			protoFieldDescriptor("value", 2, 1, protoTypeInt64),
+			protoBytes(7, protoInt(7, 1)),
+		),
+		protoBytes(4,
+			protoString(1, "Status"),
+			protoBytes(2, protoString(1, "PENDING"), protoInt(2, 0)),
+			protoBytes(2, protoString(1, "CONFIRMED"), protoInt(2, 1)),
+		),
+		protoBytes(8, protoString(1, "payment")),
+		protoBytes(8, protoString(1, "_note")),
+	),
+	protoString(12, "proto3"),
+)...)
+
+func protoBooking() []byte {
+	total := make([]byte, 8)
+	binary.LittleEndian.PutUint64(total, math.Float64bits(12.5))
+	var booking []byte
+	for _, field := range [][]byte{
+		protoString(1, "b-1"),
+		protoInt(2, 2),
+		protoString(3, "new"),
+		protoString(3, "vip"),
+		protoBytes(4, protoString(1, "adult"), protoInt(2, 1500)),
+		protoBytes(4, protoString(1, "child")),
+		protoString(5, "visa"),
+		protoInt(6, 1),
+		protoBytes(7, protoString(1, "Ann")),
+		protoInt(9, 1),
+		protoBytes(10, protoVarint(3), protoVarint(4)),
+		append(protoVarint(12<<3|protoWireFixed64), total...),
+		protoBytes(13, protoInt(1, 1514871872), protoInt(2, 5)),
+		protoInt(99, 1),
+	} {
+		booking = append(booking, field...)
+	}
+	return booking
+}
+
+type expectedProtoBooking struct {
+	ID     string   `capture:""`
+	Seats  int      `min:"1"`
+	Tags   []string `proto:"tags"`
+	Prices struct {
+		Adult int `proto:"adult"`
+		Child int `proto:"child"`
+	}
+	Voucher  bool
+	Customer struct {
+		Name string
+	}
+	Status  string `enum:"PENDING|CONFIRMED"`
+	Deltas  []int
+	Ticket  []byte
+	Total   float64
+	Created time.Time
+}
+
+type expectedProtoPayment struct {
+	Card string
+	Note string
+}
+
+func TestProtoDecoding(t *testing.T) {
+
+	Convey("Given a FileDescriptorSet", t, func() {
+
+		types, err := ReadProtoDescriptors(bookingDescriptorSet)
+		So(err, ShouldBeNil)
+
+		Convey("When a message is decoded", func() {
+
+			message, err := types.Message("booking.Booking")
+			So(err, ShouldBeNil)
+			decoded, err := message.Decode(protoBooking())
+
+			Convey("It should respect presence, repeated fields, maps and oneofs", func() {
+				So(err, ShouldBeNil)
+				So(decoded, ShouldResemble, map[string]interface{}{
+					"id":       "b-1",
+					"seats":    int64(2),
+					"tags":     []interface{}{"new", "vip"},
+					"prices":   map[string]interface{}{"adult": int64(1500), "child": int64(0)},
+					"voucher":  true,
+					"customer": map[string]interface{}{"name": "Ann"},
+					"status":   "CONFIRMED",
+					"deltas":   []interface{}{int64(-2), int64(2)},
+					"ticket":   []byte{},
+					"total":    12.5,
+					"created":  time.Date(2018, 1, 2, 5, 44, 32, 5, time.UTC),
+				})
+			})
+
+		})
+
+		Convey("When an empty message is decoded", func() {
+
+			message, _ := types.Message("booking.Booking")
+			decoded, err := message.Decode(nil)
+
+			Convey("It should have the default value of fields without presence", func() {
+				So(err, ShouldBeNil)
+				So(decoded, ShouldResemble, map[string]interface{}{
+					"id":     "",
+					"seats":  int64(0),
+					"tags":   []interface{}{},
+					"prices": map[string]interface{}{},
+					"status": "PENDING",
+					"deltas": []interface{}{},
+					"ticket": []byte{},
+					"total":  0.0,
+				})
+			})
+
+		})
+
+		Convey("When a message type doesn't exist", func() {
+
+			_, err := types.Message("booking.Missing")
+
+			Convey("It should return an error", func() {
+				So(err.Error(), ShouldEqual, "No message type 'booking.Missing' found in descriptors")
+			})
+
+		})
+
+		Convey("When a field has the wrong wire type", func() {
+
+			message, _ := types.Message("booking.Booking")
+			_, err := message.Decode(protoInt(1, 5))
+
+			Convey("It should return an error", func() {
+				So(err.Error(), ShouldEqual, "field 'id': unexpected wire type 0 (expected 2)")
+			})
+
+		})
+
+	})
+
+}
+
+func TestProtoMatching(t *testing.T) {
+
+	Convey("Given an expected format and a message type", t, func() {
+
+		types, _ := ReadProtoDescriptors(bookingDescriptorSet)
+		message, _ := types.Message("booking.Booking")
+
+		Convey("When the message matches", func() {
+
+			Convey("It should return success", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedProto(protoBooking(), expectedProtoBooking{}, capturedValues, message)
+				So(success, ShouldEqual, "")
+				So(capturedValues["id"], ShouldResemble, []interface{}{"b-1"})
+			})
+
+		})
+
+		Convey("When fields with presence weren't set", func() {
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedProto(protoBooking(), expectedProtoPayment{}, nil, message)
+				So(failure, ShouldStartWith, "No field 'card' found in response\nNo field 'note' found in response\n")
+				So(failure, ShouldContainSubstring, "\nProtobuf data as JSON:\n{\n")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,225 @@
+package matcha
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RequestExpectation describes a request we are expecting a client to send
+type RequestExpectation struct {
+	Method  string            // Leave empty to accept any method
+	Path    string            // Path template, e.g. '/bookings/{booking_id}'. Leave empty to accept any path
+	Query   map[string]string // Query parameters we expect, mapped to a pattern their value should match
+	Headers map[string]string // Headers we expect, mapped to a pattern their value should match
+	Body    interface{}       // The expected body format as a Struct, or nil if we don't care about the body
+	Format  string            // Should be 'json', 'xml', 'form' or 'multipart', defaults to 'json'. Multipart bodies are expected as a MultipartExpectation
+}
+
+// RecordedRequest is a request received by a RequestRecorder along with the result of matching it
+type RecordedRequest struct {
+	Request        *http.Request
+	Body           []byte
+	Result         string // Empty if the request matched the expectation
+	CapturedValues CapturedValues
+}
+
+// RequestRecorder is an http.Handler middleware that records and validates incoming requests
+type RequestRecorder struct {
+	expectation RequestExpectation
+	next        http.Handler
+
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// NewRequestRecorder returns a RequestRecorder that checks every request against the expectation
+// before passing it on to next. If next is nil then an empty 200 response is written.
+func NewRequestRecorder(expectation RequestExpectation, next http.Handler) *RequestRecorder {
+	return &RequestRecorder{expectation: expectation, next: next}
+}
+
+func (rr *RequestRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	capturedValues := make(CapturedValues)
+	result := ShouldMatchExpectedRequest(r, rr.expectation, capturedValues)
+
+	// ShouldMatchExpectedRequest leaves a fresh copy of the body in place so we can read it again
+	var body []byte
+	if r.Body != nil {
+		body, _ = ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	rr.mu.Lock()
+	rr.requests = append(rr.requests, RecordedRequest{Request: r, Body: body, Result: result, CapturedValues: capturedValues})
+	rr.mu.Unlock()
+
+	if rr.next != nil {
+		rr.next.ServeHTTP(w, r)
+	}
+}
+
+// Requests returns every request received so far, in the order they arrived
+func (rr *RequestRecorder) Requests() []RecordedRequest {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+	requests := make([]RecordedRequest, len(rr.requests))
+	copy(requests, rr.requests)
+	return requests
+}
+
+// Failures returns the result of every request that didn't match the expectation
+func (rr *RequestRecorder) Failures() []string {
+	var failures []string
+	for _, request := range rr.Requests() {
+		if request.Result != success {
+			failures = append(failures, request.Result)
+		}
+	}
+	return failures
+}
+
+// ShouldMatchExpectedRequest checks an *http.Request against a RequestExpectation. Path parameters
+// and any fields in the body with a 'capture' tag are captured.
+func ShouldMatchExpectedRequest(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return fmt.Sprintf("ShouldMatchExpectedRequest expects three arguments: the actual request as an *http.Request, the expected request as a RequestExpectation, and a map to hold captured values")
+	}
+
+	actualRequest, ok := actual.(*http.Request)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be an *http.Request")
+	}
+	expectation, ok := expectedList[0].(RequestExpectation)
+	if !ok {
+		return fmt.Sprintf("Expected second argument to be a RequestExpectation")
+	}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+
+	var errorList []string
+	if expectation.Method != "" && !strings.EqualFold(expectation.Method, actualRequest.Method) {
+		errorList = append(errorList, fmt.Sprintf("Expected request method to be: '%v' (but was: '%v')!", expectation.Method, actualRequest.Method))
+	}
+	if expectation.Path != "" {
+		if equal := shouldMatchPathTemplate(actualRequest.URL.Path, expectation.Path, capturedValues); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	query := actualRequest.URL.Query()
+	for _, name := range sortedKeys(expectation.Query) {
+		pattern := expectation.Query[name]
+		if _, ok := query[name]; !ok {
+			errorList = append(errorList, fmt.Sprintf("No query parameter '%v' found in request", name))
+			continue
+		}
+		if equal := shouldMatchValuePattern(name, query.Get(name), pattern); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+	for _, name := range sortedKeys(expectation.Headers) {
+		pattern := expectation.Headers[name]
+		if _, ok := actualRequest.Header[http.CanonicalHeaderKey(name)]; !ok {
+			errorList = append(errorList, fmt.Sprintf("No header '%v' found in request", name))
+			continue
+		}
+		if equal := shouldMatchValuePattern(name, actualRequest.Header.Get(name), pattern); equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if expectation.Body != nil {
+		var body []byte
+		if actualRequest.Body != nil {
+			var err error
+			body, err = ioutil.ReadAll(actualRequest.Body)
+			if err != nil {
+				return fmt.Sprintf("Was not possible to read the request body: %v", err)
+			}
+			// Put the body back so the request can still be handled
+			actualRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		var equal string
+		switch expectation.Format {
+		case "", "json":
+			equal = ShouldMatchExpectedJSONResponse(body, expectation.Body, capturedValues)
+		case "xml":
+			equal = ShouldMatchExpectedXMLResponse(body, expectation.Body, capturedValues)
+		case "form":
+			equal = ShouldMatchExpectedForm(body, expectation.Body, capturedValues)
+		case "multipart":
+			equal = ShouldMatchMultipart(actualRequest, expectation.Body, capturedValues)
+		default:
+			equal = fmt.Sprintf("Unknown request body format: %v", expectation.Format)
+		}
+		if equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// shouldMatchPathTemplate checks a path against a template such as '/bookings/{booking_id}',
+// capturing the value of each parameter under its name
+func shouldMatchPathTemplate(path string, template string, capturedValues CapturedValues) string {
+	actualSegments := strings.Split(strings.Trim(path, "/"), "/")
+	templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+	if len(actualSegments) != len(templateSegments) {
+		return fmt.Sprintf("Expected request path to match: '%v' (but was: '%v')!", template, path)
+	}
+
+	params := make(map[string]string)
+	for i, segment := range templateSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") && actualSegments[i] != "" {
+			params[strings.Trim(segment, "{}")] = actualSegments[i]
+			continue
+		}
+		if segment != actualSegments[i] {
+			return fmt.Sprintf("Expected request path to match: '%v' (but was: '%v')!", template, path)
+		}
+	}
+
+	// Only capture once we know the whole path matched
+	if capturedValues != nil {
+		for name, value := range params {
+			capturedValues[name] = append(capturedValues[name], value)
+		}
+	}
+	return success
+}
+
+func shouldMatchValuePattern(name string, value string, pattern string) string {
+	matched, err := regexp.MatchString(pattern, value)
+	if err != nil {
+		return fmt.Sprintf("Received invalid regular expression: %v", pattern)
+	}
+	if !matched {
+		return fmt.Sprintf("%v: '%v' does not match expected pattern: %v", name, value, pattern)
+	}
+	return success
+}
+
+// sortedKeys is used so that errors are always reported in the same order
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
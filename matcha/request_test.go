@@ -0,0 +1,133 @@
+package matcha
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedBookingRequest struct {
+	Customer string  `json:"customer" capture:""`
+	Seats    float64 `json:"seats"`
+}
+
+func TestRequestMatching(t *testing.T) {
+
+	Convey("Given an expected request", t, func() {
+
+		expected := RequestExpectation{
+			Method:  "POST",
+			Path:    "/events/{event_id}/bookings",
+			Query:   map[string]string{"lang": "^[a-z]{2}$"},
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    expectedBookingRequest{},
+		}
+
+		Convey("When the request matches", func() {
+
+			request := httptest.NewRequest("POST", "/events/25/bookings?lang=en", strings.NewReader(`{"customer": "Jane", "seats": 2}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			Convey("It should return success and capture the path parameters and body", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedRequest(request, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["event_id"][0], ShouldEqual, "25")
+				So(capturedValues["customer"][0], ShouldEqual, "Jane")
+			})
+
+		})
+
+		Convey("When the method is different", func() {
+
+			request := httptest.NewRequest("PUT", "/events/25/bookings?lang=en", strings.NewReader(`{"customer": "Jane", "seats": 2}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedRequest(request, expected, nil)
+				So(success, ShouldStartWith, "Expected request method to be: 'POST' (but was: 'PUT')!")
+			})
+
+		})
+
+		Convey("When the path doesn't match the template", func() {
+
+			request := httptest.NewRequest("POST", "/events/bookings?lang=en", strings.NewReader(`{"customer": "Jane", "seats": 2}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedRequest(request, expected, nil)
+				So(success, ShouldStartWith, "Expected request path to match: '/events/{event_id}/bookings' (but was: '/events/bookings')!")
+			})
+
+		})
+
+		Convey("When query parameters and headers are missing or wrong", func() {
+
+			request := httptest.NewRequest("POST", "/events/25/bookings?lang=english", strings.NewReader(`{"customer": "Jane", "seats": 2}`))
+
+			Convey("It should return several errors", func() {
+				success := ShouldMatchExpectedRequest(request, expected, nil)
+				So(success, ShouldEqual, "lang: 'english' does not match expected pattern: ^[a-z]{2}$\nNo header 'Content-Type' found in request")
+			})
+
+		})
+
+		Convey("When the body has a different structure", func() {
+
+			request := httptest.NewRequest("POST", "/events/25/bookings?lang=en", strings.NewReader(`{"customer": "Jane"}`))
+			request.Header.Set("Content-Type", "application/json")
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedRequest(request, expected, nil)
+				So(success, ShouldStartWith, "No field 'seats' found in response")
+			})
+
+		})
+
+	})
+
+}
+
+func TestRequestRecorder(t *testing.T) {
+
+	Convey("Given a request recorder in front of a stub handler", t, func() {
+
+		expected := RequestExpectation{Method: "POST", Path: "/bookings", Body: expectedBookingRequest{}}
+		stub := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+		recorder := NewRequestRecorder(expected, stub)
+		server := httptest.NewServer(recorder)
+		defer server.Close()
+
+		Convey("When requests are sent to it", func() {
+
+			_, err := http.Post(server.URL+"/bookings", "application/json", strings.NewReader(`{"customer": "Jane", "seats": 2}`))
+			So(err, ShouldBeNil)
+			response, err := http.Post(server.URL+"/bookings", "application/json", strings.NewReader(`{"customer": "John"}`))
+			So(err, ShouldBeNil)
+
+			Convey("It should record them and pass them on", func() {
+				So(response.StatusCode, ShouldEqual, http.StatusCreated)
+				requests := recorder.Requests()
+				So(len(requests), ShouldEqual, 2)
+				So(requests[0].Result, ShouldEqual, "")
+				So(requests[0].CapturedValues["customer"][0], ShouldEqual, "Jane")
+				So(string(requests[1].Body), ShouldEqual, `{"customer": "John"}`)
+			})
+
+			Convey("It should report the requests that didn't match", func() {
+				failures := recorder.Failures()
+				So(len(failures), ShouldEqual, 1)
+				So(failures[0], ShouldStartWith, "No field 'seats' found in response")
+			})
+
+		})
+
+	})
+
+}
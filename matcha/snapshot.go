@@ -0,0 +1,176 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/clbanning/mxj"
+)
+
+const (
+	snapshotDir = "testdata"
+	maskedValue = "<volatile>"
+)
+
+// UpdateSnapshots makes Snapshot write the current document instead of comparing it. Snapshots are
+// also updated when tests are run with -update, or when the MATCHA_UPDATE_SNAPSHOTS environment
+// variable is set to true.
+var UpdateSnapshots = false
+
+func init() {
+	// The flag is only added to test binaries. Packages that want it for their own golden files can
+	// read it with flag.Lookup rather than defining it again.
+	if isTestBinary(os.Args[0]) && flag.Lookup("update") == nil {
+		flag.Bool("update", false, "rewrite matcha snapshot files in testdata")
+	}
+}
+
+func isTestBinary(program string) bool {
+	program = strings.TrimSuffix(filepath.Base(program), ".exe")
+	return strings.HasSuffix(program, ".test")
+}
+
+func shouldUpdateSnapshots() bool {
+	if UpdateSnapshots {
+		return true
+	}
+	if update, err := strconv.ParseBool(os.Getenv("MATCHA_UPDATE_SNAPSHOTS")); err == nil && update {
+		return true
+	}
+	updateFlag := flag.Lookup("update")
+	return updateFlag != nil && updateFlag.Value.String() == "true"
+}
+
+// Snapshot compares a JSON or XML document against the copy stored under testdata/ with the given
+// name. Run the tests with -update, or set MATCHA_UPDATE_SNAPSHOTS=true, to write the current
+// document instead.
+//
+// Fields with a 'pattern' or 'capture' tag in any of the volatile structs are masked before the
+// document is stored or compared, so values such as timestamps don't break the snapshot.
+func Snapshot(t testing.TB, name string, body []byte, volatile ...interface{}) {
+	t.Helper()
+
+	format := "json"
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		format = "xml"
+	}
+	actual, err := normaliseSnapshot(body, format, volatile)
+	if err != nil {
+		t.Fatalf("Was not possible to normalise snapshot %v: %v", name, err)
+		return
+	}
+
+	path := filepath.Join(snapshotDir, filepath.FromSlash(name)+"."+format)
+	if shouldUpdateSnapshots() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Was not possible to create snapshot directory: %v", err)
+			return
+		}
+		if err := ioutil.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("Was not possible to write snapshot %v: %v", path, err)
+		}
+		return
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Was not possible to read snapshot %v, run the tests with -update to create it: %v", path, err)
+		return
+	}
+	if bytes.Equal(expected, actual) {
+		return
+	}
+
+	expectedTree, err := decodeSnapshot(expected, format)
+	if err != nil {
+		t.Fatalf("Was not possible to decode snapshot %v: %v", path, err)
+		return
+	}
+	actualTree, _ := decodeSnapshot(actual, format)
+	differences, _ := Diff(expectedTree, actualTree, DiffOptions{})
+	if differences == nil {
+		// Only the layout of the stored file is different
+		return
+	}
+	t.Errorf("Snapshot %v doesn't match (run the tests with -update to rewrite it):\n%v", path, formatDifferences(differences))
+}
+
+// normaliseSnapshot decodes the document, masks volatile fields and encodes it again with
+// sorted keys and consistent indentation
+func normaliseSnapshot(body []byte, format string, volatile []interface{}) ([]byte, error) {
+	tree, err := decodeSnapshot(body, format)
+	if err != nil {
+		return nil, err
+	}
+
+	matcher := Matcher{format: format}
+	for _, expected := range volatile {
+		tree = matcher.maskVolatile(tree, expectedTypeOf(expected))
+	}
+
+	if format == "xml" {
+		treeMap, _ := tree.(map[string]interface{})
+		normalised, err := mxj.Map(treeMap).XmlIndent("", "  ")
+		return append(normalised, '\n'), err
+	}
+	var normalised bytes.Buffer
+	encoder := json.NewEncoder(&normalised)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(tree)
+	return normalised.Bytes(), err
+}
+
+func decodeSnapshot(body []byte, format string) (interface{}, error) {
+	if format == "xml" {
+		tree, err := mxj.NewMapXml(body, true)
+		return map[string]interface{}(tree), err
+	}
+	var tree interface{}
+	err := json.Unmarshal(body, &tree)
+	return tree, err
+}
+
+// maskVolatile walks the document alongside the expected type, replacing the value of any field
+// with a 'pattern' or 'capture' tag
+func (m *Matcher) maskVolatile(actual interface{}, expectedType reflect.Type) interface{} {
+	switch expectedType.Kind() {
+	case reflect.Slice:
+		actualSlice, ok := actual.([]interface{})
+		if !ok {
+			return m.maskVolatile(actual, expectedType.Elem())
+		}
+		for i, element := range actualSlice {
+			actualSlice[i] = m.maskVolatile(element, expectedType.Elem())
+		}
+	case reflect.Struct:
+		actualMap, ok := actual.(map[string]interface{})
+		if !ok {
+			return actual
+		}
+		for i := 0; i < expectedType.NumField(); i++ {
+			field := expectedType.Field(i)
+			fieldName := m.getFieldName(field)
+			value, ok := actualMap[fieldName]
+			if !ok {
+				continue
+			}
+			_, hasPattern := field.Tag.Lookup("pattern")
+			_, hasCapture := field.Tag.Lookup("capture")
+			if hasPattern || hasCapture {
+				actualMap[fieldName] = maskedValue
+			} else {
+				actualMap[fieldName] = m.maskVolatile(value, field.Type)
+			}
+		}
+	}
+	return actual
+}
@@ -0,0 +1,128 @@
+package matcha
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+// fakeT records failures instead of failing the real test
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+type expectedVolatileBooking struct {
+	Booking struct {
+		Created string `pattern:"^[0-9]{4}-"`
+		Seats   []struct {
+			Reference string `capture:""`
+		}
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+
+	Convey("Given a stored snapshot", t, func() {
+
+		fake := &fakeT{TB: t}
+
+		Convey("When the document is the same apart from layout and volatile fields", func() {
+
+			body := []byte(`{"booking": {"seats": [{"reference": "X1", "row": "A"}], "created": "2017-05-01T10:00:00Z", "customer": "Jane"}}`)
+
+			Convey("It should pass", func() {
+				Snapshot(fake, "booking", body, expectedVolatileBooking{})
+				So(fake.failures, ShouldBeEmpty)
+			})
+
+		})
+
+		Convey("When the document has changed", func() {
+
+			body := []byte(`{"booking": {"seats": [{"reference": "X1", "row": "B"}], "created": "2017-05-01T10:00:00Z", "status": "paid"}}`)
+
+			Convey("It should report the differences", func() {
+				Snapshot(fake, "booking", body, expectedVolatileBooking{})
+				So(len(fake.failures), ShouldEqual, 1)
+				So(fake.failures[0], ShouldEndWith, "- booking.customer: \"Jane\"\n~ booking.seats[0].row: \"A\" => \"B\"\n+ booking.status: \"paid\"")
+			})
+
+		})
+
+		Convey("When the snapshot doesn't exist", func() {
+
+			Convey("It should ask for it to be created", func() {
+				Snapshot(fake, "missing", []byte(`{}`))
+				So(len(fake.failures), ShouldEqual, 1)
+				So(fake.failures[0], ShouldContainSubstring, "run the tests with -update to create it")
+			})
+
+		})
+
+	})
+
+	for _, update := range []struct {
+		description string
+		enable      func()
+	}{
+		{"the update flag", func() { flag.Set("update", "true") }},
+		{"UpdateSnapshots", func() { UpdateSnapshots = true }},
+		{"MATCHA_UPDATE_SNAPSHOTS", func() { os.Setenv("MATCHA_UPDATE_SNAPSHOTS", "true") }},
+	} {
+
+		Convey("Given "+update.description+" is set", t, func() {
+
+			dir, err := ioutil.TempDir("", "matcha")
+			So(err, ShouldBeNil)
+			workingDir, _ := os.Getwd()
+			So(os.Chdir(dir), ShouldBeNil)
+			update.enable()
+
+			Reset(func() {
+				UpdateSnapshots = false
+				flag.Set("update", "false")
+				os.Unsetenv("MATCHA_UPDATE_SNAPSHOTS")
+				os.Chdir(workingDir)
+				os.RemoveAll(dir)
+			})
+
+			Convey("When taking an XML snapshot", func() {
+
+				Snapshot(t, "nested/booking", []byte(`<booking><seats>2</seats><customer>Jane</customer></booking>`))
+
+				Convey("It should write a normalised document", func() {
+					written, err := ioutil.ReadFile(filepath.Join(dir, "testdata", "nested", "booking.xml"))
+					So(err, ShouldBeNil)
+					So(string(written), ShouldEqual, "<booking>\n  <customer>Jane</customer>\n  <seats>2</seats>\n</booking>\n")
+				})
+
+			})
+
+		})
+
+	}
+
+	Convey("The update flag should only be added to test binaries", t, func() {
+		So(flag.Lookup("update"), ShouldNotBeNil)
+		So(isTestBinary("/tmp/go-build1/b001/matcha.test"), ShouldBeTrue)
+		So(isTestBinary("matcha.test.exe"), ShouldBeTrue)
+		So(isTestBinary("/usr/local/bin/matcha-gen"), ShouldBeFalse)
+	})
+
+}
@@ -0,0 +1,239 @@
+package matcha
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EventStreamExpectation describes the events we expect from a Server-Sent Events stream
+type EventStreamExpectation struct {
+	Events  map[string]interface{} // Event names mapped to the expected format of their JSON data as a Struct. Other events aren't checked
+	Order   []string               // Event names that must first arrive in this order, other events may arrive in between
+	Counts  map[string]int         // Event names mapped to exactly how many of them we expect
+	Timeout time.Duration          // Stop reading after this long and check the events received so far, 0 to read until the stream ends
+}
+
+// Event is a single event from a Server-Sent Events stream
+type Event struct {
+	ID    string
+	Event string // 'message' if the event has no name
+	Data  string
+}
+
+// ShouldMatchEventStream reads a Server-Sent Events stream and matches the JSON data of each event
+// against the struct expected for its name. Captured values from every event go into the same map.
+func ShouldMatchEventStream(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return fmt.Sprintf("ShouldMatchEventStream expects three arguments: the actual stream as an io.Reader, the expected events as an EventStreamExpectation, and a map to hold captured values")
+	}
+
+	reader, ok := actual.(io.Reader)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be an io.Reader")
+	}
+	expectation, ok := expectedList[0].(EventStreamExpectation)
+	if !ok {
+		return fmt.Sprintf("Expected second argument to be an EventStreamExpectation")
+	}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+
+	events, err := readEvents(reader, expectation.Timeout)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to read the event stream: %v", err)
+	}
+
+	var errorList []string
+	counts := make(map[string]int)
+	matcher := Matcher{format: "json", capturedValues: capturedValues, store: store}
+	for _, event := range events {
+		counts[event.Event]++
+		expected, ok := expectation.Events[event.Event]
+		if !ok {
+			continue
+		}
+		if equal := matcher.shouldMatchEvent(event, expectedTypeOf(expected)); equal != success {
+			errorList = append(errorList, fmt.Sprintf("Event '%v' (id: '%v'): %v", event.Event, event.ID, equal))
+		}
+	}
+
+	if equal := shouldMatchEventOrder(events, expectation.Order); equal != success {
+		errorList = append(errorList, equal)
+	}
+	var names []string
+	for name := range expectation.Counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if counts[name] != expectation.Counts[name] {
+			errorList = append(errorList, fmt.Sprintf("Expected %d '%v' events (but got: %d)!", expectation.Counts[name], name, counts[name]))
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+func (m *Matcher) shouldMatchEvent(event Event, expectedType reflect.Type) string {
+	var actualData interface{}
+	if err := json.Unmarshal([]byte(event.Data), &actualData); err != nil {
+		return fmt.Sprintf("Was not possible to unmarshal JSON into a Go struct. JSON data:\n%v", event.Data)
+	}
+	result := m.shouldMatchExpectedField(actualData, expectedType, "Result")
+	if result != success {
+		if differences := m.describeDifferences(actualData, expectedType); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
+	}
+	return result
+}
+
+// shouldMatchEventOrder checks the events named in order first arrived in that order
+func shouldMatchEventOrder(events []Event, order []string) string {
+	next := 0
+	for _, event := range events {
+		if next < len(order) && event.Event == order[next] {
+			next++
+		}
+	}
+	if next == len(order) {
+		return success
+	}
+	if next == 0 {
+		return fmt.Sprintf("Expected events in the order: %v (but '%v' never arrived)!", strings.Join(order, ", "), order[0])
+	}
+	return fmt.Sprintf("Expected events in the order: %v (but '%v' never arrived after '%v')!", strings.Join(order, ", "), order[next], order[next-1])
+}
+
+// readEvents reads every event from the stream, stopping early if the timeout passes. Streams that
+// can be closed are closed on timeout so that the read in progress is interrupted.
+func readEvents(reader io.Reader, timeout time.Duration) ([]Event, error) {
+	eventChannel := make(chan Event)
+	errorChannel := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		errorChannel <- parseEventStream(reader, func(event Event) bool {
+			select {
+			case eventChannel <- event:
+				return true
+			case <-done:
+				return false
+			}
+		})
+	}()
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(timeout)
+	}
+	var events []Event
+	for {
+		select {
+		case event := <-eventChannel:
+			events = append(events, event)
+		case err := <-errorChannel:
+			return events, err
+		case <-timer:
+			if closer, ok := reader.(io.Closer); ok {
+				closer.Close()
+			}
+			return events, nil
+		}
+	}
+}
+
+// parseEventStream parses events as described by the Server-Sent Events specification, passing each
+// one to dispatch until it returns false
+func parseEventStream(reader io.Reader, dispatch func(Event) bool) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	scanner.Split(scanEventStreamLines)
+
+	var data []string
+	event := Event{}
+	lastID := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// A blank line dispatches the event, as long as it has some data
+			if data != nil {
+				event.ID = lastID
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				event.Data = strings.Join(data, "\n")
+				if !dispatch(event) {
+					return nil
+				}
+			}
+			data = nil
+			event = Event{}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// Comment
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				lastID = value
+			}
+		}
+	}
+	// An event that isn't followed by a blank line is discarded
+	return scanner.Err()
+}
+
+// scanEventStreamLines splits lines ending in '\r\n', '\n' or '\r'
+func scanEventStreamLines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		// Need more data to know whether the '\r' is followed by '\n'
+		return 0, nil, nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
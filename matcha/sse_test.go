@@ -0,0 +1,124 @@
+package matcha
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedSeatEvent struct {
+	Seat      string `json:"seat" capture:"seats"`
+	Available bool   `json:"available"`
+}
+
+type expectedSnapshotEvent struct {
+	Total float64 `json:"total"`
+}
+
+// stalledReader sends some data and then blocks until it is closed, like a live event stream
+type stalledReader struct {
+	data   io.Reader
+	closed chan struct{}
+}
+
+func (r *stalledReader) Read(p []byte) (int, error) {
+	if n, err := r.data.Read(p); err != io.EOF {
+		return n, err
+	}
+	<-r.closed
+	return 0, io.EOF
+}
+
+func (r *stalledReader) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestEventStreamParsing(t *testing.T) {
+
+	Convey("Given an event stream", t, func() {
+
+		stream := ": keep-alive\r\nevent: seat\r\nid: 1\r\ndata: {\"seat\":\r\ndata:  \"A1\"}\r\n\r\ndata: no name\n\nretry: 1000\nid: 2\n\n\rdata: one\rdata: two\r\revent: unfinished\ndata: lost"
+
+		Convey("When it is parsed", func() {
+
+			var events []Event
+			err := parseEventStream(strings.NewReader(stream), func(event Event) bool {
+				events = append(events, event)
+				return true
+			})
+
+			Convey("It should follow the specification", func() {
+				So(err, ShouldBeNil)
+				So(events, ShouldResemble, []Event{
+					{ID: "1", Event: "seat", Data: "{\"seat\":\n \"A1\"}"},
+					{ID: "1", Event: "message", Data: "no name"},
+					{ID: "2", Event: "message", Data: "one\ntwo"},
+				})
+			})
+
+		})
+
+	})
+
+}
+
+func TestEventStreamMatching(t *testing.T) {
+
+	Convey("Given an expected event stream", t, func() {
+
+		expected := EventStreamExpectation{
+			Events: map[string]interface{}{
+				"snapshot": expectedSnapshotEvent{},
+				"seat":     expectedSeatEvent{},
+			},
+			Order:  []string{"snapshot", "seat"},
+			Counts: map[string]int{"snapshot": 1},
+		}
+
+		Convey("When the stream matches", func() {
+
+			stream := strings.NewReader("event: snapshot\ndata: {\"total\": 2}\n\nevent: seat\ndata: {\"seat\": \"A1\", \"available\": false}\n\nevent: heartbeat\ndata: ok\n\nevent: seat\ndata: {\"seat\": \"A2\", \"available\": true}\n\n")
+
+			Convey("It should return success and capture from every event", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchEventStream(stream, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["seats"], ShouldResemble, []interface{}{"A1", "A2"})
+			})
+
+		})
+
+		Convey("When events don't match, arrive out of order or too often", func() {
+
+			stream := strings.NewReader("event: seat\nid: 7\ndata: {\"seat\": \"A1\"}\n\nevent: snapshot\ndata: {\"total\": 2}\n\nevent: snapshot\ndata: {\"total\": 2}\n\n")
+
+			Convey("It should report every problem", func() {
+				failure := ShouldMatchEventStream(stream, expected, nil)
+				So(failure, ShouldStartWith, "Event 'seat' (id: '7'): No field 'available' found in response\n")
+				So(failure, ShouldEndWith, "\nExpected events in the order: snapshot, seat (but 'seat' never arrived after 'snapshot')!\nExpected 1 'snapshot' events (but got: 2)!")
+			})
+
+		})
+
+		Convey("When the stream doesn't end before the timeout", func() {
+
+			stream := &stalledReader{
+				data:   strings.NewReader("event: snapshot\ndata: {\"total\": 2}\n\n"),
+				closed: make(chan struct{}),
+			}
+			expected.Timeout = 50 * time.Millisecond
+
+			Convey("It should check the events received so far", func() {
+				failure := ShouldMatchEventStream(stream, expected, nil)
+				So(failure, ShouldEqual, "Expected events in the order: snapshot, seat (but 'seat' never arrived after 'snapshot')!")
+			})
+
+		})
+
+	})
+
+}
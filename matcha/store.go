@@ -0,0 +1,96 @@
+package matcha
+
+import (
+	"sort"
+	"sync"
+)
+
+// CaptureStore holds captured values that are shared between tests running in parallel. It can be
+// passed to any assertion in place of a CapturedValues map. Each assertion captures into its own map,
+// which is merged into the store when the assertion returns, so values captured by one call stay
+// together and in document order.
+type CaptureStore struct {
+	mutex      *sync.Mutex // Shared by a store and all of its namespaces
+	values     CapturedValues
+	parent     *CaptureStore
+	namespaces map[string]*CaptureStore
+}
+
+// NewCaptureStore returns an empty capture store
+func NewCaptureStore() *CaptureStore {
+	return &CaptureStore{mutex: &sync.Mutex{}, values: make(CapturedValues)}
+}
+
+// Namespace returns a store for values captured by one subtest, creating it the first time a name is
+// used. Values captured in a namespace aren't seen by the store it's in, but a namespace sees the
+// values of the stores it's in unless it has captured values under the same key.
+func (s *CaptureStore) Namespace(name string) *CaptureStore {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.namespaces == nil {
+		s.namespaces = make(map[string]*CaptureStore)
+	}
+	namespace, ok := s.namespaces[name]
+	if !ok {
+		namespace = &CaptureStore{mutex: s.mutex, values: make(CapturedValues), parent: s}
+		s.namespaces[name] = namespace
+	}
+	return namespace
+}
+
+// Snapshot returns a copy of the values in the store, which can be read without holding a lock
+func (s *CaptureStore) Snapshot() CapturedValues {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.snapshot()
+}
+
+func (s *CaptureStore) snapshot() CapturedValues {
+	snapshot := make(CapturedValues)
+	for store := s; store != nil; store = store.parent {
+		for key, values := range store.values {
+			if _, ok := snapshot[key]; !ok {
+				snapshot[key] = append([]interface{}(nil), values...)
+			}
+		}
+	}
+	return snapshot
+}
+
+// Merge appends captured values to the store. A nil store ignores them.
+func (s *CaptureStore) Merge(capturedValues CapturedValues) {
+	if s == nil || len(capturedValues) == 0 {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make([]string, 0, len(capturedValues))
+	for key := range capturedValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s.values[key] = append(s.values[key], capturedValues[key]...)
+	}
+}
+
+// captureArgument reads the argument that holds captured values, which can be a CapturedValues map,
+// a *CaptureStore or nil. Values for a store are captured into a new map, to be merged into the store
+// once the assertion is done.
+func captureArgument(argument interface{}) (CapturedValues, *CaptureStore, bool) {
+	switch captures := argument.(type) {
+	case nil:
+		return nil, nil, true
+	case CapturedValues:
+		return captures, nil, true
+	case *CaptureStore:
+		if captures == nil {
+			return nil, nil, true
+		}
+		return make(CapturedValues), captures, true
+	}
+	return nil, nil, false
+}
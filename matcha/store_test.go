@@ -0,0 +1,117 @@
+package matcha
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedSeats struct {
+	Booking string `json:"booking" capture:"booking"`
+	Seats   []struct {
+		ID string `json:"id" capture:"seat"`
+	} `json:"seats"`
+}
+
+func TestCaptureStore(t *testing.T) {
+
+	Convey("Given a capture store shared by assertions running at the same time", t, func() {
+
+		store := NewCaptureStore()
+		var wait sync.WaitGroup
+		results := make([]string, 20)
+		for i := range results {
+			wait.Add(1)
+			go func(i int) {
+				defer wait.Done()
+				document := []byte(fmt.Sprintf(`{"booking": "b%d", "seats": [{"id": "b%d-1"}, {"id": "b%d-2"}, {"id": "b%d-3"}]}`, i, i, i, i))
+				results[i] = ShouldMatchExpectedJSONResponse(document, expectedSeats{}, store)
+			}(i)
+		}
+		wait.Wait()
+
+		Convey("It should hold every captured value", func() {
+			for _, result := range results {
+				So(result, ShouldEqual, "")
+			}
+			snapshot := store.Snapshot()
+			So(snapshot["booking"], ShouldHaveLength, 20)
+			So(snapshot["seat"], ShouldHaveLength, 60)
+		})
+
+		Convey("It should keep the values from each assertion together and in order", func() {
+			snapshot := store.Snapshot()
+			for i, booking := range snapshot["booking"] {
+				for j := 0; j < 3; j++ {
+					So(snapshot["seat"][i*3+j], ShouldEqual, fmt.Sprintf("%v-%d", booking, j+1))
+				}
+			}
+		})
+
+	})
+
+	Convey("Given a capture store with namespaces", t, func() {
+
+		store := NewCaptureStore()
+		store.Merge(CapturedValues{"booking": {"shared"}})
+		first := store.Namespace("first")
+		second := store.Namespace("second")
+		So(ShouldMatchExpectedJSONResponse([]byte(`{"booking": "b1", "seats": []}`), expectedSeats{}, first), ShouldEqual, "")
+		So(ShouldMatchExpectedJSONResponse([]byte(`{"booking": "b2", "seats": [{"id": "s2"}]}`), expectedSeats{}, second), ShouldEqual, "")
+
+		Convey("Each namespace should only see its own values and those of the store it's in", func() {
+			So(first.Snapshot(), ShouldResemble, CapturedValues{"booking": {"b1"}})
+			So(second.Snapshot(), ShouldResemble, CapturedValues{"booking": {"b2"}, "seat": {"s2"}})
+			So(store.Namespace("third").Snapshot(), ShouldResemble, CapturedValues{"booking": {"shared"}})
+			So(store.Snapshot(), ShouldResemble, CapturedValues{"booking": {"shared"}})
+		})
+
+		Convey("The same name should give the same namespace", func() {
+			So(store.Namespace("first"), ShouldEqual, first)
+		})
+
+		Convey("A snapshot should not change when the store does", func() {
+			snapshot := first.Snapshot()
+			first.Merge(CapturedValues{"booking": {"b3"}})
+			So(snapshot, ShouldResemble, CapturedValues{"booking": {"b1"}})
+			So(first.Snapshot(), ShouldResemble, CapturedValues{"booking": {"b1", "b3"}})
+		})
+
+		Convey("Variables should be resolved against the store", func() {
+			type expectedBookingID struct {
+				Booking string `json:"booking" equals:"${booking}"`
+			}
+			So(ShouldMatchExpectedJSONResponse([]byte(`{"booking": "b1"}`), expectedBookingID{}, first), ShouldEqual, "")
+			So(ShouldMatchExpectedJSONResponse([]byte(`{"booking": "b2"}`), expectedBookingID{}, first), ShouldContainSubstring,
+				`Booking: "b2" does not equal ${booking} ("b1")`)
+		})
+
+	})
+
+	Convey("Given GraphQL options with a capture store", t, func() {
+
+		store := NewCaptureStore()
+		type expectedData struct {
+			Booking string `json:"booking" capture:"booking"`
+		}
+		result := ShouldMatchGraphQLResponse([]byte(`{"data": {"booking": "b1"}}`), expectedData{}, GraphQLOptions{CaptureStore: store})
+
+		Convey("It should merge the captured values into the store", func() {
+			So(result, ShouldEqual, "")
+			So(store.Snapshot(), ShouldResemble, CapturedValues{"booking": {"b1"}})
+		})
+
+	})
+
+	Convey("Given something that can't hold captured values", t, func() {
+
+		Convey("It should return an error string", func() {
+			So(ShouldMatchExpectedJSONResponse([]byte(`{}`), expectedSeats{}, "values"), ShouldEqual,
+				"Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+		})
+
+	})
+
+}
@@ -0,0 +1,244 @@
+package matcha
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// streamMatcher walks the tokens of a JSON document alongside the expected type, so that memory use
+// depends on how deeply the document is nested rather than how big it is. Only values that are
+// captured, matched against a pattern or checked against constraints are decoded in full.
+type streamMatcher struct {
+	*Matcher
+	decoder *json.Decoder
+}
+
+// ShouldMatchExpectedJSONStream works like ShouldMatchExpectedJSONResponse but reads the document
+// from an io.Reader as it is validated. Fields that aren't in the expected struct are skipped
+// without being decoded.
+func ShouldMatchExpectedJSONStream(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) != 2 {
+		return fmt.Sprintf("ShouldMatchExpectedJSONStream expects three arguments: the actual JSON as an io.Reader, the expected JSON format as a Struct, and a map to hold captured values")
+	}
+
+	var reader io.Reader
+	switch actualJSON := actual.(type) {
+	case io.Reader:
+		reader = actualJSON
+	case []byte:
+		reader = bytes.NewReader(actualJSON)
+	default:
+		return fmt.Sprintf("Expected first argument to be an io.Reader")
+	}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+
+	matcher := &streamMatcher{
+		Matcher: &Matcher{format: "json", capturedValues: capturedValues, store: store},
+		decoder: json.NewDecoder(reader),
+	}
+	result, err := matcher.shouldMatchExpectedValue(expectedTypeOf(expectedList[0]), "Result")
+	if err == nil {
+		if _, err = matcher.decoder.Token(); err == io.EOF {
+			err = nil
+		} else if err == nil {
+			err = fmt.Errorf("unexpected data after the end of the document")
+		}
+	}
+	if err != nil {
+		return fmt.Sprintf("Was not possible to unmarshal JSON stream: %v", err)
+	}
+	return result
+}
+
+func (s *streamMatcher) shouldMatchExpectedValue(expectedType reflect.Type, fieldName string) (string, error) {
+	switch expectedType.Kind() {
+	case reflect.Slice:
+		return s.shouldMatchExpectedArray(expectedType, fieldName)
+	case reflect.Struct:
+		if expectedType != timeType {
+			return s.shouldMatchExpectedObject(expectedType, fieldName)
+		}
+	}
+
+	token, err := s.decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	if delim, ok := token.(json.Delim); ok {
+		if err := s.skipContainer(delim); err != nil {
+			return "", err
+		}
+		return s.shouldMatchExpectedField(placeholderFor(delim), expectedType, fieldName), nil
+	}
+	return s.shouldMatchExpectedField(token, expectedType, fieldName), nil
+}
+
+func (s *streamMatcher) shouldMatchExpectedArray(expectedType reflect.Type, fieldName string) (string, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	if token != json.Delim('[') {
+		if delim, ok := token.(json.Delim); ok {
+			err = s.skipContainer(delim)
+		}
+		return fmt.Sprintf("Was expecting an array for field: %v", fieldName), err
+	}
+
+	var errorList []string
+	newFieldName := fmt.Sprintf("%v array values", fieldName)
+	for i := 0; s.decoder.More(); i++ {
+		s.enterPath(fmt.Sprintf("[%d]", i))
+		equal, err := s.shouldMatchExpectedValue(expectedType.Elem(), newFieldName)
+		s.leavePath()
+		if err != nil {
+			return "", err
+		}
+		if equal != success {
+			errorList = append(errorList, equal)
+		}
+	}
+	if _, err := s.decoder.Token(); err != nil {
+		return "", err
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n"), nil
+	}
+	return success, nil
+}
+
+func (s *streamMatcher) shouldMatchExpectedObject(expectedType reflect.Type, fieldName string) (string, error) {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	if token != json.Delim('{') {
+		actual := token
+		if delim, ok := token.(json.Delim); ok {
+			err = s.skipContainer(delim)
+			actual = placeholderFor(delim)
+		}
+		return fmt.Sprintf("Was expecting an object for field: %v, but got %v", fieldName, kindOf(actual)), err
+	}
+
+	// Errors are kept in the same order as the expected fields, whatever order the document is in
+	fields := make(map[string]int)
+	for i := 0; i < expectedType.NumField(); i++ {
+		fields[s.getFieldName(expectedType.Field(i))] = i
+	}
+	fieldErrors := make([]string, expectedType.NumField())
+	seen := make([]bool, expectedType.NumField())
+	for s.decoder.More() {
+		keyToken, err := s.decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		key, _ := keyToken.(string)
+		i, expected := fields[key]
+		if !expected || seen[i] {
+			if err := s.skipValue(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		seen[i] = true
+		s.enterPath("." + key)
+		fieldErrors[i], err = s.shouldMatchExpectedStructField(expectedType.Field(i), key)
+		s.leavePath()
+		if err != nil {
+			return "", err
+		}
+	}
+	if _, err := s.decoder.Token(); err != nil {
+		return "", err
+	}
+
+	var errorList []string
+	for i := range fieldErrors {
+		if !seen[i] {
+			fieldErrors[i] = fmt.Sprintf("No field '%v' found in response", s.getFieldName(expectedType.Field(i)))
+		}
+		if fieldErrors[i] != success {
+			errorList = append(errorList, fieldErrors[i])
+		}
+	}
+	if errorList != nil {
+		return strings.Join(errorList, "\n"), nil
+	}
+	return success, nil
+}
+
+func (s *streamMatcher) shouldMatchExpectedStructField(expectedField reflect.StructField, fieldName string) (string, error) {
+	if !hasValueTags(expectedField) {
+		return s.shouldMatchExpectedValue(expectedField.Type, fieldName)
+	}
+
+	// The whole value is needed to capture it, match it against a pattern or check its constraints
+	var actualField interface{}
+	if err := s.decoder.Decode(&actualField); err != nil {
+		return "", err
+	}
+	s.captureValue(expectedField, actualField, nil)
+	if equal := s.shouldMatchPattern(actualField, expectedField); equal != success {
+		return equal, nil
+	}
+	if equal := s.Matcher.shouldMatchExpectedField(actualField, expectedField.Type, fieldName); equal != success {
+		return equal, nil
+	}
+	if equal := s.shouldMatchConstraints(actualField, expectedField); equal != success {
+		return equal, nil
+	}
+	return s.shouldMatchVariables(actualField, expectedField), nil
+}
+
+// skipValue reads past the next value without decoding it
+func (s *streamMatcher) skipValue() error {
+	token, err := s.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); ok {
+		return s.skipContainer(delim)
+	}
+	return nil
+}
+
+// skipContainer reads past the rest of an array or object whose opening delimiter has been read
+func (s *streamMatcher) skipContainer(opening json.Delim) error {
+	if opening != '[' && opening != '{' {
+		return fmt.Errorf("unexpected '%v'", opening)
+	}
+	for depth := 1; depth > 0; {
+		token, err := s.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// placeholderFor stands in for a skipped array or object so that error messages show the same type
+// as when the whole document is decoded
+func placeholderFor(opening json.Delim) interface{} {
+	if opening == '[' {
+		return []interface{}{}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,105 @@
+package matcha
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedStreamCatalogue struct {
+	Count    float64 `capture:"count"`
+	Products []struct {
+		SKU   string  `json:"sku" pattern:"^[A-Z]{3}-[0-9]+$"`
+		Price float64 `json:"price"`
+	} `json:"products"`
+}
+
+// catalogueStream generates a catalogue with many products, each with a large undeclared field
+func catalogueStream(products int) io.Reader {
+	readers := []io.Reader{strings.NewReader(`{"products": [`)}
+	for i := 0; i < products; i++ {
+		if i > 0 {
+			readers = append(readers, strings.NewReader(","))
+		}
+		readers = append(readers, strings.NewReader(`{"description": {"text": "`+strings.Repeat("x", 1000)+`", "tags": [1, [2, {"3": 4}]]}, "sku": "ABC-1", "price": 9.99}`))
+	}
+	readers = append(readers, strings.NewReader(`], "count": 2}`))
+	return io.MultiReader(readers...)
+}
+
+func TestJSONStreamMatching(t *testing.T) {
+
+	Convey("Given an expected format", t, func() {
+
+		Convey("When streaming the same documents as the non-streaming tests", func() {
+
+			Convey("It should return the same results", func() {
+				fixtures := []struct {
+					expected interface{}
+					json     string
+				}{
+					{expectedJSONString{}, `{"string_field": "some string", "another_field": [1, {"a": 2}]}`},
+					{expectedJSONString{}, `{"string_field": 5}`},
+					{expectedJSONString{}, `{"string_field": null}`},
+					{expectedJSONString{}, `{}`},
+					{expectedJSONNumber{}, `{"number_field": "5"}`},
+					{expectedJSONBool{}, `{"boolean_field": {"nested": true}}`},
+					{expectedJSONArray{}, `{"array_field": ["one", 2]}`},
+					{expectedJSONArray{}, `{"array_field": 5}`},
+					{ExpectedJSONComplex{}, `{"result": [1, 2, 3]}`},
+					{ExpectedJSONComplex{}, `{"result": null}`},
+					{expectedListOfObjects{}, `[ { "result": {} }, { "result": { "attributes": { "string_field": "fantastic" } } } ]`},
+					{expectedURL{}, `{"url": "https:www.google.com"}`},
+				}
+				for _, fixture := range fixtures {
+					expected := ShouldMatchExpectedJSONResponse([]byte(fixture.json), fixture.expected, nil)
+					streamed := ShouldMatchExpectedJSONStream(strings.NewReader(fixture.json), fixture.expected, nil)
+					So(strings.SplitN(expected, "\nDifferences", 2)[0], ShouldEqual, streamed)
+				}
+			})
+
+		})
+
+		Convey("When streaming a large document", func() {
+
+			var expected expectedStreamCatalogue
+
+			Convey("It should skip undeclared fields and capture values", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedJSONStream(catalogueStream(5000), expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["count"], ShouldResemble, []interface{}{float64(2)})
+			})
+
+		})
+
+		Convey("When fields are in a different order to the struct", func() {
+
+			var expected expectedStreamCatalogue
+			stream := strings.NewReader(`{"products": [{"price": "free", "sku": "abc"}]}`)
+
+			Convey("It should report errors in the order of the struct", func() {
+				failure := ShouldMatchExpectedJSONStream(stream, expected, nil)
+				So(failure, ShouldEqual, "No field 'count' found in response\nSKU: 'abc' does not match expected pattern: ^[A-Z]{3}-[0-9]+$\n"+TypeErrorString("price", "float64", "string"))
+			})
+
+		})
+
+		Convey("When the stream is invalid", func() {
+
+			var expected expectedStreamCatalogue
+
+			Convey("It should return an error string", func() {
+				failure := ShouldMatchExpectedJSONStream(strings.NewReader(`{"products": [{"sku": `), expected, nil)
+				So(failure, ShouldStartWith, "Was not possible to unmarshal JSON stream")
+				failure = ShouldMatchExpectedJSONStream(strings.NewReader(`{"count": 1, "products": []} {}`), expected, nil)
+				So(failure, ShouldEqual, "Was not possible to unmarshal JSON stream: unexpected data after the end of the document")
+			})
+
+		})
+
+	})
+
+}
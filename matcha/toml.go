@@ -0,0 +1,44 @@
+package matcha
+
+import (
+	"fmt"
+)
+
+func ShouldMatchExpectedTOMLResponse(actual interface{}, expectedList ...interface{}) string {
+
+	// Check number of arguments
+	if len(expectedList) < 2 {
+		return fmt.Sprintf("ShouldMatchExpectedTOMLResponse expects three arguments: the actual TOML response as a byte slice, the expected TOML format as a Struct, and a map to hold captured values, optionally followed by Options")
+	}
+
+	actualTOML, ok := actual.([]byte)
+	if !ok {
+		return fmt.Sprintf("Expected first argument to be a byte slice")
+	}
+	expectedResponseStruct := expectedList[0]
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
+	}
+	defer store.Merge(capturedValues)
+	actualResponse, err := parseTOML(actualTOML)
+	if err != nil {
+		return fmt.Sprintf("Was not possible to unmarshal TOML into a Go struct (%v). TOML data:\n%v", err, string(actualTOML))
+	}
+
+	matcher := Matcher{format: "toml", capturedValues: capturedValues, store: store}
+	if equal := matcher.applyOptions(expectedList[2:]); equal != success {
+		return equal
+	}
+	if equal := matcher.capturePaths(actualResponse); equal != success {
+		return equal
+	}
+
+	result := matcher.shouldMatchExpectedField(actualResponse, expectedTypeOf(expectedResponseStruct), "Result")
+	if result != success {
+		if differences := matcher.describeDifferences(actualResponse, expectedTypeOf(expectedResponseStruct)); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
+	}
+	return result
+}
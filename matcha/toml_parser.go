@@ -0,0 +1,497 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This is a small TOML parser that decodes a document into the same maps, slices and scalars as JSON.
+// Integers and floats are both decoded as a float64, and every kind of datetime as a time.Time.
+// Local datetimes, dates and times have no offset, so they are decoded in UTC.
+
+var (
+	tomlBareKeyPattern    = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+	tomlDatePattern       = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}$`)
+	tomlDatePrefixPattern = regexp.MustCompile(`^[0-9]{4}-[0-9]{2}-[0-9]{2}`)
+	tomlTimePattern       = regexp.MustCompile(`^[0-9]{2}:[0-9]{2}`)
+	// Decimal numbers have no leading zeros, digits on both sides of a point and underscores only between digits
+	tomlDecimalPattern  = regexp.MustCompile(`^[-+]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][-+]?[0-9](_?[0-9])*)?$`)
+	tomlDatetimeLayouts = []string{
+		time.RFC3339Nano,
+		"2006-01-02T15:04:05.999999999",
+		"2006-01-02",
+		"15:04:05.999999999",
+	}
+)
+
+type tomlParser struct {
+	text    string
+	pos     int
+	line    int
+	root    map[string]interface{}
+	current map[string]interface{}
+	defined map[uintptr]bool   // Tables that have been defined with a header or key
+	static  map[tomlValue]bool // Arrays and inline tables given as a value, which can't be extended
+}
+
+// tomlValue identifies the value of a key in a table
+type tomlValue struct {
+	table uintptr
+	key   string
+}
+
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	p := &tomlParser{text: string(data), line: 1, root: root, current: root, defined: make(map[uintptr]bool), static: make(map[tomlValue]bool)}
+	for {
+		p.skipWhitespace(true)
+		if p.pos >= len(p.text) {
+			return root, nil
+		}
+		var err error
+		if p.text[p.pos] == '[' {
+			err = p.parseTableHeader()
+		} else {
+			err = p.parseKeyValue(p.current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expectEndOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %v", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipWhitespace skips spaces, tabs and comments, and newlines as well if asked
+func (p *tomlParser) skipWhitespace(newlines bool) {
+	for p.pos < len(p.text) {
+		switch c := p.text[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.text) && p.text[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '\n' && newlines:
+			p.line++
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) expectEndOfLine() error {
+	p.skipWhitespace(false)
+	if p.pos < len(p.text) && p.text[p.pos] != '\n' {
+		return p.errorf("unexpected '%v' at end of line", p.text[p.pos:p.lineEnd()])
+	}
+	return nil
+}
+
+func (p *tomlParser) lineEnd() int {
+	end := strings.IndexByte(p.text[p.pos:], '\n')
+	if end < 0 {
+		return len(p.text)
+	}
+	return p.pos + end
+}
+
+func (p *tomlParser) parseTableHeader() error {
+	isArray := strings.HasPrefix(p.text[p.pos:], "[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.text[p.pos:], closing) {
+		return p.errorf("expected '%v' after table name", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(p.root, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if isArray {
+		existing, ok := parent[last]
+		if !ok {
+			existing = []interface{}{}
+		}
+		tables, ok := existing.([]interface{})
+		if !ok || p.static[tomlValue{tableID(parent), last}] {
+			return p.errorf("'%v' is not an array of tables", strings.Join(keys, "."))
+		}
+		table := make(map[string]interface{})
+		parent[last] = append(tables, table)
+		p.current = table
+		return nil
+	}
+
+	existing, ok := parent[last]
+	if !ok {
+		existing = make(map[string]interface{})
+		parent[last] = existing
+	}
+	table, ok := existing.(map[string]interface{})
+	if !ok || p.defined[tableID(table)] {
+		return p.errorf("table '%v' is defined more than once", strings.Join(keys, "."))
+	}
+	p.defined[tableID(table)] = true
+	p.current = table
+	return nil
+}
+
+// descend follows a list of keys from a table, creating tables that don't exist yet. For an array
+// of tables, the last table in the array is used. Inline tables and arrays can't be extended.
+func (p *tomlParser) descend(table map[string]interface{}, keys []string) (map[string]interface{}, error) {
+	for _, key := range keys {
+		if p.static[tomlValue{tableID(table), key}] {
+			return nil, p.errorf("'%v' is defined inline and can't be extended", key)
+		}
+		existing, ok := table[key]
+		if !ok {
+			existing = make(map[string]interface{})
+			table[key] = existing
+		}
+		switch value := existing.(type) {
+		case map[string]interface{}:
+			table = value
+		case []interface{}:
+			if len(value) == 0 {
+				return nil, p.errorf("'%v' is not a table", key)
+			}
+			last, ok := value[len(value)-1].(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("'%v' is not a table", key)
+			}
+			table = last
+		default:
+			return nil, p.errorf("'%v' is not a table", key)
+		}
+	}
+	return table, nil
+}
+
+func tableID(table map[string]interface{}) uintptr {
+	return reflect.ValueOf(table).Pointer()
+}
+
+func (p *tomlParser) parseKeyValue(table map[string]interface{}) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.pos >= len(p.text) || p.text[p.pos] != '=' {
+		return p.errorf("expected '=' after key '%v'", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipWhitespace(false)
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.descend(table, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, exists := parent[last]; exists {
+		return p.errorf("key '%v' is defined more than once", strings.Join(keys, "."))
+	}
+	parent[last] = value
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		p.static[tomlValue{tableID(parent), last}] = true
+	}
+	if valueTable, ok := value.(map[string]interface{}); ok {
+		p.defined[tableID(valueTable)] = true
+	}
+	return nil
+}
+
+// parseKey parses a dotted key such as 'server."host name".port'
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipWhitespace(false)
+		if p.pos >= len(p.text) {
+			return nil, p.errorf("expected a key")
+		}
+		switch p.text[p.pos] {
+		case '"', '\'':
+			key, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		default:
+			key := tomlBareKeyPattern.FindString(p.text[p.pos:])
+			if key == "" {
+				return nil, p.errorf("invalid key '%v'", p.text[p.pos:p.lineEnd()])
+			}
+			p.pos += len(key)
+			keys = append(keys, key)
+		}
+		p.skipWhitespace(false)
+		if p.pos < len(p.text) && p.text[p.pos] == '.' {
+			p.pos++
+			continue
+		}
+		return keys, nil
+	}
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.pos >= len(p.text) {
+		return nil, p.errorf("expected a value")
+	}
+	switch c := p.text[p.pos]; c {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+
+	// Everything else is a bare token: a boolean, number or datetime
+	start := p.pos
+	for p.pos < len(p.text) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.text[p.pos])) {
+		p.pos++
+	}
+	token := p.text[start:p.pos]
+	// A space may separate the date and time of a datetime
+	if tomlDatePattern.MatchString(token) && p.pos+1 < len(p.text) && p.text[p.pos] == ' ' && tomlTimePattern.MatchString(p.text[p.pos+1:]) {
+		p.pos++
+		for p.pos < len(p.text) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.text[p.pos])) {
+			p.pos++
+		}
+		token = strings.Replace(p.text[start:p.pos], " ", "T", 1)
+	}
+
+	switch token {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if tomlDatePrefixPattern.MatchString(token) || tomlTimePattern.MatchString(token) {
+		for _, layout := range tomlDatetimeLayouts {
+			if datetime, err := time.Parse(layout, token); err == nil {
+				return datetime, nil
+			}
+		}
+		return nil, p.errorf("invalid datetime '%v'", token)
+	}
+	return p.parseNumber(token)
+}
+
+func (p *tomlParser) parseNumber(token string) (interface{}, error) {
+	if strings.Contains(token, "__") || strings.HasPrefix(token, "_") || strings.HasSuffix(token, "_") {
+		return nil, p.errorf("invalid number '%v'", token)
+	}
+	digits := strings.Replace(token, "_", "", -1)
+	for prefix, base := range map[string]int{"0x": 16, "0o": 8, "0b": 2} {
+		if strings.HasPrefix(digits, prefix) {
+			number, err := strconv.ParseUint(digits[2:], base, 64)
+			if err != nil {
+				return nil, p.errorf("invalid number '%v'", token)
+			}
+			return float64(number), nil
+		}
+	}
+	if !tomlDecimalPattern.MatchString(token) {
+		return nil, p.errorf("invalid value '%v'", token)
+	}
+	number, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return nil, p.errorf("invalid value '%v'", token)
+	}
+	return number, nil
+}
+
+func (p *tomlParser) parseArray() (interface{}, error) {
+	p.pos++
+	array := []interface{}{}
+	for {
+		p.skipWhitespace(true)
+		if p.pos >= len(p.text) {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.text[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+		p.skipWhitespace(true)
+		if p.pos < len(p.text) && p.text[p.pos] == ',' {
+			p.pos++
+		} else if p.pos >= len(p.text) || p.text[p.pos] != ']' {
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+	p.pos++
+	table := make(map[string]interface{})
+	p.skipWhitespace(false)
+	if p.pos < len(p.text) && p.text[p.pos] == '}' {
+		p.pos++
+		return table, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipWhitespace(false)
+		if p.pos >= len(p.text) {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.text[p.pos] {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return table, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// parseString parses basic, literal and multi-line strings
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.text[p.pos : p.pos+1]
+	multiLine := strings.HasPrefix(p.text[p.pos:], strings.Repeat(quote, 3))
+	delimiter := quote
+	if multiLine {
+		delimiter = strings.Repeat(quote, 3)
+		// A newline straight after the opening delimiter is trimmed
+		p.pos += 3
+		if strings.HasPrefix(p.text[p.pos:], "\r\n") {
+			p.pos += 2
+			p.line++
+		} else if strings.HasPrefix(p.text[p.pos:], "\n") {
+			p.pos++
+			p.line++
+		}
+	} else {
+		p.pos++
+	}
+
+	var value []byte
+	for p.pos < len(p.text) {
+		if strings.HasPrefix(p.text[p.pos:], delimiter) {
+			p.pos += len(delimiter)
+			// Up to two quotes are allowed right before the closing delimiter
+			for extra := 0; multiLine && extra < 2 && p.pos < len(p.text) && p.text[p.pos:p.pos+1] == quote; extra++ {
+				value = append(value, quote[0])
+				p.pos++
+			}
+			return string(value), nil
+		}
+		c := p.text[p.pos]
+		switch {
+		case c == '\n' && !multiLine:
+			return "", p.errorf("unterminated string")
+		case c == '\n':
+			p.line++
+			value = append(value, c)
+			p.pos++
+		case c == '\\' && quote == `"`:
+			escaped, err := p.parseEscape(multiLine)
+			if err != nil {
+				return "", err
+			}
+			value = append(value, escaped...)
+		default:
+			value = append(value, c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *tomlParser) parseEscape(multiLine bool) (string, error) {
+	p.pos++
+	if p.pos >= len(p.text) {
+		return "", p.errorf("unterminated string")
+	}
+	c := p.text[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		return "\b", nil
+	case 't':
+		return "\t", nil
+	case 'n':
+		return "\n", nil
+	case 'f':
+		return "\f", nil
+	case 'r':
+		return "\r", nil
+	case 'e':
+		return "\x1b", nil
+	case '"', '\\':
+		return string(c), nil
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.text) {
+			return "", p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.text[p.pos:p.pos+size], 16, 32)
+		if err != nil {
+			return "", p.errorf("invalid unicode escape")
+		}
+		p.pos += size
+		return string(rune(code)), nil
+	case ' ', '\t', '\r', '\n':
+		if multiLine {
+			// A backslash at the end of a line trims all whitespace up to the next character
+			p.pos--
+			for p.pos < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.pos])) {
+				if p.text[p.pos] == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+			return "", nil
+		}
+	}
+	return "", p.errorf("invalid escape sequence '\\%c'", c)
+}
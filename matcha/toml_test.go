@@ -0,0 +1,223 @@
+package matcha
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedTOMLManifest struct {
+	Name     string    `toml:"service"`
+	Deployed time.Time `capture:"deployed"`
+	Server   struct {
+		Host string
+		Port float64
+	}
+	Targets []struct {
+		Region string `pattern:"^[a-z]+-[a-z]+$"`
+		Canary bool
+	} `toml:"target"`
+}
+
+func TestTOMLParsing(t *testing.T) {
+
+	Convey("Given a TOML document", t, func() {
+
+		Convey("When it has tables, arrays of tables and inline tables", func() {
+
+			document := []byte(`
+# Deployment manifest
+title = "seats" # trailing comment
+ports = [ 80,
+  443, ]  
+owner = { name = "Jane", "team name" = 'box office' }
+site.region = "eu-west"
+
+[server]
+host = "localhost"
+
+[server.limits]
+connections = 1_000
+
+[[target]]
+region = "eu-west"
+
+[[target]]
+region = "us-east"
+weights = [0.5, 1e2]
+`)
+
+			Convey("It should decode them into maps and slices", func() {
+				value, err := parseTOML(document)
+				So(err, ShouldBeNil)
+				So(value, ShouldResemble, map[string]interface{}{
+					"title": "seats",
+					"ports": []interface{}{float64(80), float64(443)},
+					"owner": map[string]interface{}{"name": "Jane", "team name": "box office"},
+					"site":  map[string]interface{}{"region": "eu-west"},
+					"server": map[string]interface{}{
+						"host":   "localhost",
+						"limits": map[string]interface{}{"connections": float64(1000)},
+					},
+					"target": []interface{}{
+						map[string]interface{}{"region": "eu-west"},
+						map[string]interface{}{"region": "us-east", "weights": []interface{}{0.5, float64(100)}},
+					},
+				})
+			})
+
+		})
+
+		Convey("When it has strings, numbers and datetimes", func() {
+
+			document := []byte(`
+basic = "tab\tquote\" \u00e9"
+literal = 'C:\path'
+multi = """
+one \
+  two"""
+raw = '''
+line'''
+hex = 0xff
+negative = -17
+float = 6.626e-34
+yes = true
+offset = 1979-05-27T07:32:00-08:00
+spaced = 1979-05-27 07:32:00Z
+local = 1979-05-27T07:32:00
+date = 1979-05-27
+time = 07:32:00.5
+`)
+
+			Convey("It should decode each of them", func() {
+				value, err := parseTOML(document)
+				So(err, ShouldBeNil)
+				So(value["basic"], ShouldEqual, "tab\tquote\" é")
+				So(value["literal"], ShouldEqual, `C:\path`)
+				So(value["multi"], ShouldEqual, "one two")
+				So(value["raw"], ShouldEqual, "line")
+				So(value["hex"], ShouldEqual, float64(255))
+				So(value["negative"], ShouldEqual, float64(-17))
+				So(value["float"], ShouldEqual, 6.626e-34)
+				So(value["yes"], ShouldEqual, true)
+				So(value["offset"].(time.Time).UTC(), ShouldResemble, time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC))
+				So(value["spaced"].(time.Time), ShouldResemble, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC))
+				So(value["local"], ShouldResemble, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC))
+				So(value["date"], ShouldResemble, time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC))
+				So(value["time"], ShouldResemble, time.Date(0, 1, 1, 7, 32, 0, 500000000, time.UTC))
+			})
+
+		})
+
+		Convey("When it is invalid", func() {
+
+			Convey("It should return an error with the line number", func() {
+				_, err := parseTOML([]byte("a = 1\na = 2\n"))
+				So(err.Error(), ShouldEqual, "line 2: key 'a' is defined more than once")
+				_, err = parseTOML([]byte("[a]\nb = 1\n[a]\n"))
+				So(err.Error(), ShouldEqual, "line 3: table 'a' is defined more than once")
+				_, err = parseTOML([]byte("a = \"unterminated\n"))
+				So(err.Error(), ShouldEqual, "line 1: unterminated string")
+				_, err = parseTOML([]byte("a = 1 2\n"))
+				So(err.Error(), ShouldEqual, "line 1: unexpected '2' at end of line")
+			})
+
+			Convey("It should not extend arrays and tables defined inline", func() {
+				_, err := parseTOML([]byte("a = [1]\n[[a]]\nb = 2\n"))
+				So(err.Error(), ShouldEqual, "line 2: 'a' is not an array of tables")
+				_, err = parseTOML([]byte("a = {b = 1}\na.c = 2\n"))
+				So(err.Error(), ShouldEqual, "line 2: 'a' is defined inline and can't be extended")
+				_, err = parseTOML([]byte("a = {b = {c = 1}}\n[a.b.d]\n"))
+				So(err.Error(), ShouldEqual, "line 2: 'a' is defined inline and can't be extended")
+				_, err = parseTOML([]byte("a = [{b = 1}]\n[a.c]\n"))
+				So(err.Error(), ShouldEqual, "line 2: 'a' is defined inline and can't be extended")
+			})
+
+			Convey("It should reject numbers with leading zeros or a bare point", func() {
+				for _, number := range []string{"01", "-01", ".5", "5.", "1.e5", "1_.5", "+0_1"} {
+					_, err := parseTOML([]byte("a = " + number + "\n"))
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEqual, "line 1: invalid value '"+number+"'")
+				}
+				value, err := parseTOML([]byte("a = [0, -0.5, 1_000, 6.02e+23, 1e-2, +3]\n"))
+				So(err, ShouldBeNil)
+				So(value["a"], ShouldResemble, []interface{}{0.0, -0.5, 1000.0, 6.02e+23, 0.01, 3.0})
+			})
+
+		})
+
+	})
+
+}
+
+func TestTOMLMatching(t *testing.T) {
+
+	Convey("Given an expected TOML format", t, func() {
+
+		var expected expectedTOMLManifest
+
+		Convey("When the document has the same structure", func() {
+
+			fakeTOML := []byte(`
+service = "seats"
+deployed = 2017-05-01T10:00:00Z
+
+[server]
+host = "localhost"
+port = 8080
+
+[[target]]
+region = "eu-west"
+canary = true
+`)
+
+			Convey("It should return success and capture the datetime", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedTOMLResponse(fakeTOML, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["deployed"][0], ShouldResemble, time.Date(2017, 5, 1, 10, 0, 0, 0, time.UTC))
+			})
+
+			Convey("It should apply Options", func() {
+				capturedValues := make(CapturedValues)
+				var records CaptureRecords
+				success := ShouldMatchExpectedTOMLResponse(fakeTOML, expected, capturedValues,
+					Capture("$.server.host", "host"), RecordCaptures(&records))
+				So(success, ShouldEqual, "")
+				So(capturedValues["host"], ShouldResemble, []interface{}{"localhost"})
+				So(records.ByKey("deployed")[0].Path, ShouldEqual, "deployed")
+			})
+
+		})
+
+		Convey("When a datetime is a string instead", func() {
+
+			fakeTOML := []byte(`
+service = "seats"
+deployed = "2017-05-01T10:00:00Z"
+server = { host = "localhost", port = 8080 }
+target = []
+`)
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedTOMLResponse(fakeTOML, expected, nil)
+				So(success, ShouldStartWith, TypeErrorString("deployed", "time.Time", "string"))
+			})
+
+		})
+
+		Convey("When the document is invalid", func() {
+
+			fakeTOML := []byte(`service = `)
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedTOMLResponse(fakeTOML, expected, nil)
+				So(success, ShouldStartWith, "Was not possible to unmarshal TOML into a Go struct")
+			})
+
+		})
+
+	})
+
+}
@@ -0,0 +1,215 @@
+package matcha
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// variablePattern matches a reference to captured values, e.g. '${booking_id}'
+var variablePattern = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// Variables sets the captured values that '${name}' references in 'equals' and 'in' tags are resolved
+// against. Without this option they are resolved against the map of captured values passed to the
+// assertion, or the values in a CaptureStore along with those captured so far, so values captured by
+// one assertion can be expected by the next.
+func Variables(store CapturedValues) Option {
+	return func(m *Matcher) {
+		m.variables = store
+	}
+}
+
+// shouldMatchVariables checks the 'equals' and 'in' tags of a field. An 'equals' tag is a value, a
+// reference to the first value captured under a name such as '${booking_id}', or a string with
+// references in it. An 'in' tag is a list of values separated by '|', where a reference stands for
+// every value captured under its name.
+func (m *Matcher) shouldMatchVariables(actual interface{}, expectedField reflect.StructField) string {
+
+	var errorList []string
+	if tag, ok := expectedField.Tag.Lookup("equals"); ok {
+		expected, err := m.resolveEquals(tag, expectedField)
+		if err != nil {
+			errorList = append(errorList, err.Error())
+		} else if !variableEqual(actual, expected) {
+			errorList = append(errorList, fmt.Sprintf("%v: %v does not equal %v (%v)", expectedField.Name, diffValue(actual), tag, diffValue(expected)))
+		}
+	}
+
+	if tag, ok := expectedField.Tag.Lookup("in"); ok {
+		candidates, err := m.resolveIn(tag, expectedField)
+		if err != nil {
+			errorList = append(errorList, err.Error())
+		} else {
+			found := false
+			descriptions := make([]string, len(candidates))
+			for i, candidate := range candidates {
+				found = found || variableEqual(actual, candidate)
+				descriptions[i] = diffValue(candidate)
+			}
+			if !found {
+				errorList = append(errorList, fmt.Sprintf("%v: %v is not in %v (%v)", expectedField.Name, diffValue(actual), tag, strings.Join(descriptions, ", ")))
+			}
+		}
+	}
+
+	if errorList != nil {
+		return strings.Join(errorList, "\n")
+	}
+	return success
+}
+
+// variableStore gives every value that '${name}' references can refer to. The store is only read
+// once per assertion, as it can hold many values and doesn't change while a document is matched.
+func (m *Matcher) variableStore() CapturedValues {
+	if m.variables != nil {
+		return m.variables
+	}
+	if m.store != nil {
+		snapshot := m.storeValues()
+		store := make(CapturedValues, len(snapshot)+len(m.capturedValues))
+		for key, values := range snapshot {
+			store[key] = values
+		}
+		for key, values := range m.capturedValues {
+			store[key] = append(append([]interface{}(nil), store[key]...), values...)
+		}
+		return store
+	}
+	return m.capturedValues
+}
+
+func (m *Matcher) storeValues() CapturedValues {
+	if m.storeSnapshot == nil {
+		m.storeSnapshot = m.store.Snapshot()
+	}
+	return m.storeSnapshot
+}
+
+// variableValues gives the values that '${name}' refers to, without copying the whole store
+func (m *Matcher) variableValues(name string) []interface{} {
+	if m.variables != nil || m.store == nil {
+		return m.variableStore()[name]
+	}
+	values := m.storeValues()[name]
+	if captured := m.capturedValues[name]; len(captured) > 0 {
+		values = append(append([]interface{}(nil), values...), captured...)
+	}
+	return values
+}
+
+func (m *Matcher) resolveEquals(tag string, expectedField reflect.StructField) (interface{}, error) {
+	references := variablePattern.FindAllStringSubmatchIndex(tag, -1)
+	if references == nil {
+		return literalValue("equals", tag, expectedField)
+	}
+	if len(references) == 1 && references[0][0] == 0 && references[0][1] == len(tag) {
+		values, err := m.resolveVariable(tag[references[0][2]:references[0][3]], expectedField)
+		if err != nil {
+			return nil, err
+		}
+		return values[0], nil
+	}
+
+	// Variables in the middle of a string are put into it
+	if expectedField.Type.Kind() != reflect.String {
+		return nil, fmt.Errorf("'equals' tag can only put variables into a string on string fields: %v", expectedField.Name)
+	}
+	var resolveError error
+	resolved := variablePattern.ReplaceAllStringFunc(tag, func(reference string) string {
+		name := variablePattern.FindStringSubmatch(reference)[1]
+		values := m.variableValues(name)
+		if len(values) == 0 {
+			resolveError = fmt.Errorf("No value captured for variable '%v' used by field: %v", name, expectedField.Name)
+			return reference
+		}
+		return fmt.Sprintf("%v", values[0])
+	})
+	return resolved, resolveError
+}
+
+func (m *Matcher) resolveIn(tag string, expectedField reflect.StructField) ([]interface{}, error) {
+	var candidates []interface{}
+	for _, part := range strings.Split(tag, "|") {
+		reference := variablePattern.FindStringSubmatch(part)
+		if reference == nil || reference[0] != part {
+			value, err := literalValue("in", part, expectedField)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, value)
+			continue
+		}
+		values, err := m.resolveVariable(reference[1], expectedField)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, values...)
+	}
+	return candidates, nil
+}
+
+// resolveVariable finds the values captured under a name, with the elements of any captured arrays,
+// and checks they can be compared with the field
+func (m *Matcher) resolveVariable(name string, expectedField reflect.StructField) ([]interface{}, error) {
+	var values []interface{}
+	for _, value := range m.variableValues(name) {
+		if elements, ok := value.([]interface{}); ok {
+			values = append(values, elements...)
+		} else {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("No value captured for variable '%v' used by field: %v", name, expectedField.Name)
+	}
+	for i, value := range values {
+		converted, ok := variableOfType(value, expectedField.Type)
+		if !ok {
+			return nil, fmt.Errorf("Variable '%v' is a %v, which can't be compared with %v field: %v", name, kindOf(value), expectedField.Type, expectedField.Name)
+		}
+		values[i] = converted
+	}
+	return values, nil
+}
+
+// literalValue parses a value written in a tag into the type of its field
+func literalValue(tagName string, literal string, expectedField reflect.StructField) (interface{}, error) {
+	value := csvValue(literal, expectedField.Type)
+	if _, ok := variableOfType(value, expectedField.Type); !ok {
+		return nil, fmt.Errorf("Received invalid '%v' value for %v field %v: %v", tagName, expectedField.Type, expectedField.Name, literal)
+	}
+	return value, nil
+}
+
+// variableOfType checks a value has a type that can be compared with the expected type, converting
+// datetime strings to a time.Time
+func variableOfType(value interface{}, expectedType reflect.Type) (interface{}, bool) {
+	switch expectedType.Kind() {
+	case reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, ok := numberValue(value)
+		return value, ok
+	case reflect.String:
+		_, ok := value.(string)
+		return value, ok
+	case reflect.Bool:
+		_, ok := value.(bool)
+		return value, ok
+	case reflect.Struct:
+		if expectedType == timeType {
+			datetime, err := decodeTime(value)
+			return datetime, err == nil
+		}
+	}
+	return value, false
+}
+
+func variableEqual(actual interface{}, expected interface{}) bool {
+	if expectedTime, ok := expected.(time.Time); ok {
+		actualTime, err := decodeTime(actual)
+		return err == nil && actualTime.Equal(expectedTime)
+	}
+	return jsonPathEqual(actual, expected)
+}
@@ -0,0 +1,133 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedBooking struct {
+	BookingID string  `json:"booking_id" equals:"${booking_id}"`
+	SeatID    string  `json:"seat_id" in:"${seat_ids}"`
+	Reference string  `json:"reference" equals:"REF-${booking_id}"`
+	Total     float64 `json:"total" equals:"${total}"`
+	Currency  string  `json:"currency" in:"GBP|EUR"`
+}
+
+func TestVariables(t *testing.T) {
+
+	Convey("Given values captured by an earlier response", t, func() {
+
+		capturedValues := CapturedValues{
+			"booking_id": {"b-1"},
+			"seat_ids":   {[]interface{}{"A1", "A2"}},
+			"total":      {int64(20)},
+		}
+
+		Convey("When a later response has the same values", func() {
+
+			document := []byte(`{"booking_id": "b-1", "seat_id": "A2", "reference": "REF-b-1", "total": 20.0,
+				"currency": "GBP"}`)
+
+			Convey("It should match", func() {
+				So(ShouldMatchExpectedJSONResponse(document, expectedBooking{}, capturedValues), ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When a later response has different values", func() {
+
+			document := []byte(`{"booking_id": "b-2", "seat_id": "A9", "reference": "REF-b-2", "total": 21,
+				"currency": "USD"}`)
+			failure := ShouldMatchExpectedJSONResponse(document, expectedBooking{}, capturedValues)
+
+			Convey("It should say which values differ", func() {
+				So(failure, ShouldContainSubstring, `BookingID: "b-2" does not equal ${booking_id} ("b-1")`)
+				So(failure, ShouldContainSubstring, `SeatID: "A9" is not in ${seat_ids} ("A1", "A2")`)
+				So(failure, ShouldContainSubstring, `Reference: "REF-b-2" does not equal REF-${booking_id} ("REF-b-1")`)
+				So(failure, ShouldContainSubstring, `Total: 21 does not equal ${total} (20)`)
+				So(failure, ShouldContainSubstring, `Currency: "USD" is not in GBP|EUR ("GBP", "EUR")`)
+			})
+
+		})
+
+		Convey("When the values are in a separate store", func() {
+
+			document := []byte(`{"booking_id": "b-3", "seat_id": "C1", "reference": "REF-b-3", "total": 5,
+				"currency": "EUR"}`)
+			store := CapturedValues{
+				"booking_id": {"b-3"},
+				"seat_ids":   {"C1"},
+				"total":      {5.0},
+			}
+
+			Convey("It should resolve variables from the store", func() {
+				So(ShouldMatchExpectedJSONResponse(document, expectedBooking{}, capturedValues, Variables(store)), ShouldEqual, "")
+			})
+
+		})
+
+		Convey("When the values are in a CaptureStore", func() {
+
+			store := NewCaptureStore()
+			store.Merge(CapturedValues{"booking_id": {"b-3"}})
+			matcher := Matcher{format: "json", capturedValues: make(CapturedValues), store: store}
+
+			Convey("It should read the store once per assertion", func() {
+				So(matcher.variableValues("booking_id"), ShouldResemble, []interface{}{"b-3"})
+				store.Merge(CapturedValues{"booking_id": {"b-4"}})
+				So(matcher.variableValues("booking_id"), ShouldResemble, []interface{}{"b-3"})
+			})
+
+			Convey("It should still see values captured during the assertion", func() {
+				matcher.capturedValues["booking_id"] = []interface{}{"b-5"}
+				So(matcher.variableValues("booking_id"), ShouldResemble, []interface{}{"b-3", "b-5"})
+				So(matcher.variableStore()["booking_id"], ShouldResemble, []interface{}{"b-3", "b-5"})
+				So(store.Snapshot()["booking_id"], ShouldResemble, []interface{}{"b-3"})
+			})
+
+		})
+
+		Convey("When a variable hasn't been captured", func() {
+
+			delete(capturedValues, "booking_id")
+			document := []byte(`{"booking_id": "b-1", "seat_id": "A1", "reference": "REF-b-1", "total": 20,
+				"currency": "GBP"}`)
+			failure := ShouldMatchExpectedJSONResponse(document, expectedBooking{}, capturedValues)
+
+			Convey("It should say which variable is missing", func() {
+				So(failure, ShouldContainSubstring, "No value captured for variable 'booking_id' used by field: BookingID")
+				So(failure, ShouldContainSubstring, "No value captured for variable 'booking_id' used by field: Reference")
+			})
+
+		})
+
+		Convey("When a variable has the wrong type for its field", func() {
+
+			capturedValues["total"] = []interface{}{"twenty"}
+			document := []byte(`{"booking_id": "b-1", "seat_id": "A1", "reference": "REF-b-1", "total": 20,
+				"currency": "GBP"}`)
+			failure := ShouldMatchExpectedJSONResponse(document, expectedBooking{}, capturedValues)
+
+			Convey("It should return a type error rather than a mismatch", func() {
+				So(failure, ShouldContainSubstring, "Variable 'total' is a string, which can't be compared with float64 field: Total")
+			})
+
+		})
+
+	})
+
+	Convey("Given a literal that doesn't suit its field", t, func() {
+
+		type expectedCount struct {
+			Count int `json:"count" equals:"many"`
+		}
+
+		Convey("It should say the tag is invalid", func() {
+			failure := ShouldMatchExpectedJSONResponse([]byte(`{"count": 1}`), expectedCount{}, nil)
+			So(failure, ShouldContainSubstring, "Received invalid 'equals' value for int field Count: many")
+		})
+
+	})
+
+}
@@ -2,7 +2,6 @@ package matcha
 
 import (
 	"fmt"
-	"reflect"
 
 	"github.com/clbanning/mxj"
 )
@@ -10,8 +9,8 @@ import (
 func ShouldMatchExpectedXMLResponse(actual interface{}, expectedList ...interface{}) string {
 
 	// Check number of arguments
-	if len(expectedList) != 2 {
-		return fmt.Sprintf("ShouldMatchExpectedXMLResponse expects three arguments: the actual XML response as a byte slice, the expected XML format as a Struct, and a map to hold captured values")
+	if len(expectedList) < 2 {
+		return fmt.Sprintf("ShouldMatchExpectedXMLResponse expects three arguments: the actual XML response as a byte slice, the expected XML format as a Struct, and a map to hold captured values, optionally followed by Options")
 	}
 
 	actualXML, ok := actual.([]byte)
@@ -19,13 +18,11 @@ func ShouldMatchExpectedXMLResponse(actual interface{}, expectedList ...interfac
 		return fmt.Sprintf("Expected first argument to be a byte slice")
 	}
 	expectedResponseStruct := expectedList[0]
-	var capturedValues CapturedValues
-	if expectedList[1] != nil {
-		capturedValues, ok = expectedList[1].(CapturedValues)
-		if !ok {
-			return fmt.Sprintf("Expected third argument to be a map[string]interface or nil")
-		}
+	capturedValues, store, ok := captureArgument(expectedList[1])
+	if !ok {
+		return fmt.Sprintf("Expected third argument to be a map[string]interface, a *CaptureStore or nil")
 	}
+	defer store.Merge(capturedValues)
 	//var actualResponse interface{}
 	var actualResponse map[string]interface{}
 	actualResponse, err := mxj.NewMapXml(actualXML, true)
@@ -33,11 +30,19 @@ func ShouldMatchExpectedXMLResponse(actual interface{}, expectedList ...interfac
 		return fmt.Sprintf("Was not possible to unmarshal XML into a Go struct. XML data:\n%v", string(actualXML))
 	}
 
-	matcher := Matcher{format: "xml", capturedValues: capturedValues}
+	matcher := Matcher{format: "xml", capturedValues: capturedValues, store: store}
+	if equal := matcher.applyOptions(expectedList[2:]); equal != success {
+		return equal
+	}
+	if equal := matcher.capturePaths(actualResponse); equal != success {
+		return equal
+	}
 
-	result := matcher.shouldMatchExpectedField(actualResponse, reflect.TypeOf(expectedResponseStruct), "Result")
+	result := matcher.shouldMatchExpectedField(actualResponse, expectedTypeOf(expectedResponseStruct), "Result")
 	if result != success {
-		result = fmt.Sprintf("%v\nXML data:\n%v", result, string(actualXML))
+		if differences := matcher.describeDifferences(actualResponse, expectedTypeOf(expectedResponseStruct)); differences != success {
+			result = fmt.Sprintf("%v\n%v", result, differences)
+		}
 	}
 	return result
 }
@@ -0,0 +1,729 @@
+package matcha
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This is a small YAML parser that handles the parts of YAML that APIs actually use: block and flow
+// mappings and sequences, plain, quoted and block scalars, anchors, aliases and merge keys, and the
+// core schema's typed scalars. Documents are decoded into the same maps, slices and scalars as JSON,
+// with every number as a float64. Only the first document in a stream is read.
+
+var (
+	yamlIntPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlOctPattern   = regexp.MustCompile(`^0o[0-7]+$`)
+	yamlHexPattern   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	yamlFloatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+)
+
+type yamlLine struct {
+	number int    // Line number in the document, starting at 1
+	indent int    // Number of leading spaces
+	text   string // The rest of the line, including any comment
+}
+
+type yamlParser struct {
+	lines   []yamlLine
+	pos     int
+	anchors map[string]interface{}
+}
+
+func parseYAML(data []byte) (interface{}, error) {
+	p := &yamlParser{anchors: make(map[string]interface{})}
+	started := false
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		if !started && strings.HasPrefix(raw, "%") {
+			// Directive
+			continue
+		}
+		if raw == "---" || strings.HasPrefix(raw, "--- ") || raw == "..." {
+			if started {
+				break
+			}
+			started = true
+			if rest := strings.TrimSpace(strings.TrimPrefix(raw, "---")); rest != "" && raw != "..." {
+				p.lines = append(p.lines, yamlLine{number: i + 1, indent: 0, text: rest})
+			}
+			continue
+		}
+		if stripYAMLComment(text) != "" {
+			started = true
+		}
+		p.lines = append(p.lines, yamlLine{number: i + 1, indent: len(raw) - len(text), text: text})
+	}
+
+	value, err := p.parseNode(-1)
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected content '%v'", p.lines[p.pos].text)
+	}
+	return value, nil
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	line := 0
+	if p.pos < len(p.lines) {
+		line = p.lines[p.pos].number
+	} else if len(p.lines) > 0 {
+		line = p.lines[len(p.lines)-1].number
+	}
+	return fmt.Errorf("line %d: %v", line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank moves past blank lines and lines that only contain a comment
+func (p *yamlParser) skipBlank() {
+	for p.pos < len(p.lines) && stripYAMLComment(p.lines[p.pos].text) == "" {
+		p.pos++
+	}
+}
+
+// checkIndentation rejects the next line if it is indented with tabs, which YAML doesn't allow. Tabs
+// are only checked where a line starts a node, as they are allowed in the content of block scalars.
+func (p *yamlParser) checkIndentation() error {
+	if p.pos < len(p.lines) && strings.HasPrefix(p.lines[p.pos].text, "\t") {
+		return p.errorf("tabs can't be used for indentation")
+	}
+	return nil
+}
+
+// parseNode parses a block node indented further than its parent, or returns nil if there isn't one
+func (p *yamlParser) parseNode(parentIndent int) (interface{}, error) {
+	p.skipBlank()
+	if err := p.checkIndentation(); err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent <= parentIndent {
+		return nil, nil
+	}
+	line := p.lines[p.pos]
+	text := stripYAMLComment(line.text)
+	switch {
+	case isYAMLSequenceItem(text):
+		return p.parseSequence(line.indent)
+	case isYAMLMappingLine(text):
+		return p.parseMapping(line.indent)
+	}
+	p.pos++
+	return p.parseValue(text, parentIndent, false)
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	sequence := []interface{}{}
+	for {
+		p.skipBlank()
+		if err := p.checkIndentation(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.lines) {
+			break
+		}
+		line := p.lines[p.pos]
+		text := stripYAMLComment(line.text)
+		if line.indent != indent || !isYAMLSequenceItem(text) {
+			if line.indent > indent {
+				return nil, p.errorf("bad indentation of a sequence entry")
+			}
+			break
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+		var value interface{}
+		var err error
+		if content := stripYAMLComment(rest); isYAMLMappingLine(content) || isYAMLSequenceItem(content) {
+			// A compact nested collection, e.g. '- name: value'. Treat the rest of the line as if
+			// it started on a new line at the same column.
+			p.lines[p.pos] = yamlLine{number: line.number, indent: indent + len(line.text) - len(rest), text: rest}
+			value, err = p.parseNode(indent)
+		} else {
+			p.pos++
+			value, err = p.parseValue(content, indent, false)
+		}
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+	}
+	return sequence, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	mapping := make(map[string]interface{})
+	var merges []interface{}
+	for {
+		p.skipBlank()
+		if err := p.checkIndentation(); err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.lines) {
+			break
+		}
+		line := p.lines[p.pos]
+		text := stripYAMLComment(line.text)
+		if line.indent < indent || (line.indent == indent && isYAMLSequenceItem(text)) {
+			break
+		}
+		if line.indent > indent || !isYAMLMappingLine(text) {
+			return nil, p.errorf("expected a mapping key but got '%v'", text)
+		}
+
+		key, rest := splitYAMLKey(text)
+		p.pos++
+		value, err := p.parseValue(rest, indent, true)
+		if err != nil {
+			return nil, err
+		}
+		if key == "<<" {
+			merges = append(merges, value)
+			continue
+		}
+		mapping[key] = value
+	}
+
+	// Keys from merged mappings never override keys set explicitly, and earlier merges take precedence
+	for _, merge := range merges {
+		mergeList, ok := merge.([]interface{})
+		if !ok {
+			mergeList = []interface{}{merge}
+		}
+		for _, mergeValue := range mergeList {
+			mergeMap, ok := mergeValue.(map[string]interface{})
+			if !ok {
+				return nil, p.errorf("can only merge mappings into a mapping")
+			}
+			for key, value := range mergeMap {
+				if _, exists := mapping[key]; !exists {
+					mapping[key] = value
+				}
+			}
+		}
+	}
+	return mapping, nil
+}
+
+// parseValue parses the value following a mapping key or sequence indicator, which may continue on
+// the lines after it. In a mapping a sequence is allowed at the same indentation as its key.
+func (p *yamlParser) parseValue(text string, indent int, inMapping bool) (interface{}, error) {
+	text = strings.TrimSpace(text)
+	anchor, tag := "", ""
+	for strings.HasPrefix(text, "&") || strings.HasPrefix(text, "!") {
+		property, rest := splitYAMLWord(text)
+		if strings.HasPrefix(property, "&") {
+			anchor = property[1:]
+		} else {
+			tag = property
+		}
+		text = rest
+	}
+
+	var value interface{}
+	var err error
+	switch {
+	case text == "":
+		value, err = p.parseNode(indent)
+		if value == nil && err == nil && inMapping && p.pos < len(p.lines) {
+			if line := p.lines[p.pos]; line.indent == indent && isYAMLSequenceItem(stripYAMLComment(line.text)) {
+				value, err = p.parseSequence(indent)
+			}
+		}
+		if value == nil && tag == "!!str" {
+			value = ""
+		}
+	case strings.HasPrefix(text, "*"):
+		var ok bool
+		value, ok = p.anchors[text[1:]]
+		if !ok {
+			return nil, p.errorf("unknown alias '%v'", text)
+		}
+	case strings.HasPrefix(text, "|") || strings.HasPrefix(text, ">"):
+		value, err = p.parseBlockScalar(text, indent)
+	case strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{"):
+		// Flow collections can span several lines
+		for !yamlFlowComplete(text) && p.pos < len(p.lines) {
+			text += " " + stripYAMLComment(p.lines[p.pos].text)
+			p.pos++
+		}
+		flow := &yamlFlowParser{text: text, parser: p}
+		value, err = flow.parse()
+	case strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'"):
+		for !yamlQuoteComplete(text) && p.pos < len(p.lines) {
+			text += " " + strings.TrimSpace(p.lines[p.pos].text)
+			p.pos++
+		}
+		value, _, err = parseYAMLQuoted(text)
+	default:
+		// Plain scalars can be folded over several more indented lines
+		for {
+			p.skipBlank()
+			if err := p.checkIndentation(); err != nil {
+				return nil, err
+			}
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				break
+			}
+			if isYAMLMappingLine(stripYAMLComment(p.lines[p.pos].text)) {
+				return nil, p.errorf("bad indentation of a mapping entry")
+			}
+			text += " " + stripYAMLComment(p.lines[p.pos].text)
+			p.pos++
+		}
+		value = resolveYAMLScalar(text, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if anchor != "" {
+		p.anchors[anchor] = value
+	}
+	return value, nil
+}
+
+// parseBlockScalar parses a literal (|) or folded (>) scalar from the lines that follow it
+func (p *yamlParser) parseBlockScalar(header string, indent int) (interface{}, error) {
+	folded := header[0] == '>'
+	chomping := "clip"
+	contentIndent := -1
+	for _, c := range header[1:] {
+		switch {
+		case c == '-':
+			chomping = "strip"
+		case c == '+':
+			chomping = "keep"
+		case c >= '1' && c <= '9':
+			contentIndent = indent + int(c-'0')
+			if indent < 0 {
+				contentIndent = int(c - '0')
+			}
+		case c == ' ':
+		default:
+			return nil, p.errorf("invalid block scalar header '%v'", header)
+		}
+	}
+
+	var lines []string
+	trailingBlank := 0
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line.text) == "" {
+			lines = append(lines, "")
+			trailingBlank++
+			p.pos++
+			continue
+		}
+		if contentIndent < 0 {
+			contentIndent = line.indent
+		}
+		if line.indent < contentIndent || line.indent <= indent {
+			break
+		}
+		lines = append(lines, strings.Repeat(" ", line.indent-contentIndent)+line.text)
+		trailingBlank = 0
+		p.pos++
+	}
+	lines = lines[:len(lines)-trailingBlank]
+
+	var content string
+	if folded {
+		for i, line := range lines {
+			switch {
+			case i == 0:
+				content = line
+			case line == "":
+				content += "\n"
+			case lines[i-1] == "":
+				content += line
+			case strings.HasPrefix(line, " ") || strings.HasPrefix(lines[i-1], " "):
+				content += "\n" + line
+			default:
+				content += " " + line
+			}
+		}
+	} else {
+		content = strings.Join(lines, "\n")
+	}
+
+	switch chomping {
+	case "strip":
+		return content, nil
+	case "keep":
+		return content + "\n" + strings.Repeat("\n", trailingBlank), nil
+	}
+	if len(lines) == 0 {
+		return "", nil
+	}
+	return content + "\n", nil
+}
+
+type yamlFlowParser struct {
+	text   string
+	pos    int
+	parser *yamlParser
+}
+
+func (f *yamlFlowParser) parse() (interface{}, error) {
+	value, err := f.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpaces()
+	if f.pos < len(f.text) {
+		return nil, f.parser.errorf("unexpected '%v' after flow collection", f.text[f.pos:])
+	}
+	return value, nil
+}
+
+func (f *yamlFlowParser) skipSpaces() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *yamlFlowParser) parseValue() (interface{}, error) {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return nil, f.parser.errorf("unexpected end of flow collection")
+	}
+
+	anchor, tag := "", ""
+	for f.pos < len(f.text) && (f.text[f.pos] == '&' || f.text[f.pos] == '!') {
+		start := f.pos
+		for f.pos < len(f.text) && !strings.ContainsRune(" ,[]{}", rune(f.text[f.pos])) {
+			f.pos++
+		}
+		if f.text[start] == '&' {
+			anchor = f.text[start+1 : f.pos]
+		} else {
+			tag = f.text[start:f.pos]
+		}
+		f.skipSpaces()
+	}
+	if f.pos >= len(f.text) {
+		return nil, f.parser.errorf("unexpected end of flow collection")
+	}
+
+	var value interface{}
+	var err error
+	switch c := f.text[f.pos]; {
+	case c == '[':
+		value, err = f.parseSequence()
+	case c == '{':
+		value, err = f.parseMapping()
+	case c == '*':
+		name := f.parsePlain(false)
+		var ok bool
+		value, ok = f.parser.anchors[name[1:]]
+		if !ok {
+			return nil, f.parser.errorf("unknown alias '%v'", name)
+		}
+	case c == '"' || c == '\'':
+		var length int
+		value, length, err = parseYAMLQuoted(f.text[f.pos:])
+		f.pos += length
+	default:
+		value = resolveYAMLScalar(f.parsePlain(false), tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if anchor != "" {
+		f.parser.anchors[anchor] = value
+	}
+	return value, nil
+}
+
+// parsePlain reads a plain scalar, which ends at a flow indicator or, for keys, a ': '
+func (f *yamlFlowParser) parsePlain(isKey bool) string {
+	start := f.pos
+	for f.pos < len(f.text) {
+		c := f.text[f.pos]
+		if c == ',' || c == ']' || c == '}' || c == '[' || c == '{' {
+			break
+		}
+		if c == ':' && (isKey || f.pos+1 == len(f.text) || strings.ContainsRune(" ,]}", rune(f.text[f.pos+1]))) {
+			break
+		}
+		f.pos++
+	}
+	return strings.TrimSpace(f.text[start:f.pos])
+}
+
+func (f *yamlFlowParser) parseSequence() (interface{}, error) {
+	f.pos++
+	sequence := []interface{}{}
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return sequence, nil
+		}
+		value, err := f.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, value)
+		f.skipSpaces()
+		if f.pos >= len(f.text) {
+			return nil, f.parser.errorf("unterminated flow sequence")
+		}
+		if f.text[f.pos] == ',' {
+			f.pos++
+		} else if f.text[f.pos] != ']' {
+			return nil, f.parser.errorf("expected ',' or ']' in flow sequence")
+		}
+	}
+}
+
+func (f *yamlFlowParser) parseMapping() (interface{}, error) {
+	f.pos++
+	mapping := make(map[string]interface{})
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return mapping, nil
+		}
+		if f.pos >= len(f.text) {
+			return nil, f.parser.errorf("unterminated flow mapping")
+		}
+
+		var key string
+		if c := f.text[f.pos]; c == '"' || c == '\'' {
+			quoted, length, err := parseYAMLQuoted(f.text[f.pos:])
+			if err != nil {
+				return nil, err
+			}
+			key = quoted.(string)
+			f.pos += length
+		} else {
+			key = f.parsePlain(true)
+		}
+		f.skipSpaces()
+
+		var value interface{}
+		if f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			f.skipSpaces()
+			if f.pos < len(f.text) && f.text[f.pos] != ',' && f.text[f.pos] != '}' {
+				var err error
+				if value, err = f.parseValue(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		mapping[key] = value
+
+		f.skipSpaces()
+		if f.pos >= len(f.text) {
+			return nil, f.parser.errorf("unterminated flow mapping")
+		}
+		if f.text[f.pos] == ',' {
+			f.pos++
+		} else if f.text[f.pos] != '}' {
+			return nil, f.parser.errorf("expected ',' or '}' in flow mapping")
+		}
+	}
+}
+
+// parseYAMLQuoted parses a single or double quoted scalar at the start of text, returning its value
+// and the number of bytes it took up
+func parseYAMLQuoted(text string) (interface{}, int, error) {
+	quote := text[0]
+	var value []byte
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == quote && quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			value = append(value, '\'')
+			i++
+		case c == quote:
+			return string(value), i + 1, nil
+		case c == '\\' && quote == '"' && i+1 < len(text):
+			i++
+			switch e := text[i]; e {
+			case 'n':
+				value = append(value, '\n')
+			case 't':
+				value = append(value, '\t')
+			case 'r':
+				value = append(value, '\r')
+			case '0':
+				value = append(value, 0)
+			case 'b':
+				value = append(value, '\b')
+			case 'f':
+				value = append(value, '\f')
+			case 'e':
+				value = append(value, 0x1b)
+			case ' ', '"', '/', '\\':
+				value = append(value, e)
+			case 'x', 'u', 'U':
+				size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[e]
+				if i+size >= len(text) {
+					return nil, 0, fmt.Errorf("invalid escape sequence in %v", text)
+				}
+				code, err := strconv.ParseUint(text[i+1:i+1+size], 16, 32)
+				if err != nil {
+					return nil, 0, fmt.Errorf("invalid escape sequence in %v", text)
+				}
+				value = append(value, string(rune(code))...)
+				i += size
+			default:
+				return nil, 0, fmt.Errorf("invalid escape sequence '\\%c' in %v", e, text)
+			}
+		default:
+			value = append(value, c)
+		}
+	}
+	return nil, 0, fmt.Errorf("unterminated quoted scalar %v", text)
+}
+
+// resolveYAMLScalar works out the type of a plain scalar using the YAML core schema
+func resolveYAMLScalar(text string, tag string) interface{} {
+	switch tag {
+	case "!!str":
+		return text
+	case "!!float", "!!int":
+		if number, err := strconv.ParseFloat(text, 64); err == nil {
+			return number
+		}
+	case "!!bool":
+		return text == "true" || text == "True" || text == "TRUE"
+	case "!!null":
+		return nil
+	}
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	switch {
+	case yamlIntPattern.MatchString(text), yamlFloatPattern.MatchString(text):
+		number, _ := strconv.ParseFloat(text, 64)
+		return number
+	case yamlOctPattern.MatchString(text):
+		number, _ := strconv.ParseUint(text[2:], 8, 64)
+		return float64(number)
+	case yamlHexPattern.MatchString(text):
+		number, _ := strconv.ParseUint(text[2:], 16, 64)
+		return float64(number)
+	}
+	return text
+}
+
+// stripYAMLComment removes a trailing comment, ignoring any '#' inside quotes or a word
+func stripYAMLComment(text string) string {
+	var quote rune
+	for i, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.ContainsRune(" [{,:", lastRune(text[:i]))):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return strings.TrimSpace(text[:i])
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+func lastRune(text string) rune {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return r
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// isYAMLMappingLine checks for a key followed by ': ', or by ':' at the end of the line
+func isYAMLMappingLine(text string) bool {
+	return yamlKeyEnd(text) >= 0
+}
+
+func yamlKeyEnd(text string) int {
+	if text == "" || strings.ContainsRune("[{#&*!|>%@`", rune(text[0])) || isYAMLSequenceItem(text) {
+		return -1
+	}
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		_, length, err := parseYAMLQuoted(text)
+		if err != nil {
+			return -1
+		}
+		start = length
+	}
+	for i := start; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t') {
+			return i
+		}
+		if start > 0 && text[i] != ' ' {
+			// Only spaces are allowed between a quoted key and its colon
+			return -1
+		}
+	}
+	return -1
+}
+
+func splitYAMLKey(text string) (string, string) {
+	end := yamlKeyEnd(text)
+	key := strings.TrimSpace(text[:end])
+	if key != "" && (key[0] == '"' || key[0] == '\'') {
+		quoted, _, _ := parseYAMLQuoted(key)
+		key, _ = quoted.(string)
+	}
+	return key, text[end+1:]
+}
+
+func splitYAMLWord(text string) (string, string) {
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+	return text, ""
+}
+
+func yamlFlowComplete(text string) bool {
+	depth := 0
+	var quote rune
+	for _, c := range text {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+func yamlQuoteComplete(text string) bool {
+	_, _, err := parseYAMLQuoted(text)
+	return err == nil
+}
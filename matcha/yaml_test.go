@@ -0,0 +1,278 @@
+package matcha
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type expectedYAMLService struct {
+	Name     string  `yaml:"service_name" capture:""`
+	Replicas float64 `yaml:"replicas"`
+	Enabled  bool
+	Image    string `pattern:"^[a-z]+:[0-9.]+$"`
+	Ports    []struct {
+		Port     float64
+		Protocol string
+	}
+}
+
+func TestYAMLParsing(t *testing.T) {
+
+	Convey("Given a YAML document", t, func() {
+
+		Convey("When it has nested mappings and sequences", func() {
+
+			document := []byte(`
+# A service
+service:
+  name: seats   # trailing comment
+  ports:
+    - port: 80
+      protocol: http
+    - port: 443
+      protocol: "https"
+  tags:
+  - live
+  - 'eu-west'
+`)
+
+			Convey("It should decode them into maps and slices", func() {
+				value, err := parseYAML(document)
+				So(err, ShouldBeNil)
+				So(value, ShouldResemble, map[string]interface{}{
+					"service": map[string]interface{}{
+						"name": "seats",
+						"ports": []interface{}{
+							map[string]interface{}{"port": float64(80), "protocol": "http"},
+							map[string]interface{}{"port": float64(443), "protocol": "https"},
+						},
+						"tags": []interface{}{"live", "eu-west"},
+					},
+				})
+			})
+
+		})
+
+		Convey("When it has typed scalars", func() {
+
+			document := []byte(`
+int: 42
+hex: 0x1F
+float: -2.5e3
+bool: True
+null_value: ~
+empty:
+string: "tab\tand é"
+single: 'it''s'
+forced: !!str 123
+version: 1.2.3
+time: 10:30
+`)
+
+			Convey("It should resolve their types", func() {
+				value, err := parseYAML(document)
+				So(err, ShouldBeNil)
+				So(value, ShouldResemble, map[string]interface{}{
+					"int":        float64(42),
+					"hex":        float64(31),
+					"float":      float64(-2500),
+					"bool":       true,
+					"null_value": nil,
+					"empty":      nil,
+					"string":     "tab\tand é",
+					"single":     "it's",
+					"forced":     "123",
+					"version":    "1.2.3",
+					"time":       "10:30",
+				})
+			})
+
+		})
+
+		Convey("When it has anchors, aliases and merge keys", func() {
+
+			document := []byte(`---
+defaults: &defaults
+  region: eu-west
+  replicas: 2
+seats:
+  <<: *defaults
+  replicas: 4
+mirror: *defaults
+...
+ignored: true
+`)
+
+			Convey("It should resolve them", func() {
+				value, err := parseYAML(document)
+				So(err, ShouldBeNil)
+				defaults := map[string]interface{}{"region": "eu-west", "replicas": float64(2)}
+				So(value, ShouldResemble, map[string]interface{}{
+					"defaults": defaults,
+					"seats":    map[string]interface{}{"region": "eu-west", "replicas": float64(4)},
+					"mirror":   defaults,
+				})
+			})
+
+		})
+
+		Convey("When it has block scalars and flow collections", func() {
+
+			document := []byte(`
+literal: |
+  line one
+    indented # not a comment
+
+  line three
+folded: >-
+  folded
+  text
+
+  new paragraph
+flow: {name: seats, ports: [80, 443], "quoted key": 'x'}
+multi_line: [
+  a, b,
+  c
+]
+`)
+
+			Convey("It should decode them", func() {
+				value, err := parseYAML(document)
+				So(err, ShouldBeNil)
+				So(value, ShouldResemble, map[string]interface{}{
+					"literal":    "line one\n  indented # not a comment\n\nline three\n",
+					"folded":     "folded text\nnew paragraph",
+					"flow":       map[string]interface{}{"name": "seats", "ports": []interface{}{float64(80), float64(443)}, "quoted key": "x"},
+					"multi_line": []interface{}{"a", "b", "c"},
+				})
+			})
+
+		})
+
+		Convey("When it is invalid", func() {
+
+			document := []byte("a: 1\n  b: 2\n")
+
+			Convey("It should return an error with the line number", func() {
+				_, err := parseYAML(document)
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldStartWith, "line 2:")
+			})
+
+		})
+
+		Convey("When a flow collection ends after an anchor or tag", func() {
+
+			Convey("It should return an error instead of panicking", func() {
+				for _, document := range []string{"[!", "x: [&a", "{a: !!str"} {
+					_, err := parseYAML([]byte(document))
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEndWith, "unexpected end of flow collection")
+				}
+			})
+
+		})
+
+		Convey("When it is indented with tabs", func() {
+
+			Convey("It should return an error", func() {
+				for _, document := range []string{"a:\n\tb: 1\n", "a:\n  - 1\n\t- 2\n", "a: one\n\ttwo\n"} {
+					_, err := parseYAML([]byte(document))
+					So(err, ShouldNotBeNil)
+					So(err.Error(), ShouldEndWith, "tabs can't be used for indentation")
+				}
+			})
+
+			Convey("Tabs in the content of a block scalar should be kept", func() {
+				value, err := parseYAML([]byte("a: |\n  \tindented\n  \t# not a comment\n"))
+				So(err, ShouldBeNil)
+				So(value, ShouldResemble, map[string]interface{}{"a": "\tindented\n\t# not a comment\n"})
+			})
+
+		})
+
+	})
+
+}
+
+func TestYAMLMatching(t *testing.T) {
+
+	Convey("Given an expected YAML format", t, func() {
+
+		var expected expectedYAMLService
+
+		Convey("When a value has a different type", func() {
+
+			fakeYAML := []byte(`
+service_name: seats
+replicas: 3
+enabled: yes_but_not_a_bool
+image: seats:1.4
+ports:
+  - {port: 80, protocol: http}
+`)
+
+			Convey("It should return the type mismatch", func() {
+				success := ShouldMatchExpectedYAMLResponse(fakeYAML, expected, nil)
+				So(success, ShouldStartWith, TypeErrorString("enabled", "bool", "string"))
+			})
+
+		})
+
+		Convey("When the document has the same structure", func() {
+
+			fakeYAML := []byte(`
+service_name: seats
+replicas: 3
+enabled: true
+image: seats:1.4
+ports:
+  - port: 80
+    protocol: http
+`)
+
+			Convey("It should return success and capture values", func() {
+				capturedValues := make(CapturedValues)
+				success := ShouldMatchExpectedYAMLResponse(fakeYAML, expected, capturedValues)
+				So(success, ShouldEqual, "")
+				So(capturedValues["service_name"][0], ShouldEqual, "seats")
+			})
+
+			Convey("It should apply Options", func() {
+				capturedValues := make(CapturedValues)
+				var records CaptureRecords
+				success := ShouldMatchExpectedYAMLResponse(fakeYAML, expected, capturedValues,
+					Capture("$.ports[0].protocol", "protocol"), RecordCaptures(&records))
+				So(success, ShouldEqual, "")
+				So(capturedValues["protocol"], ShouldResemble, []interface{}{"http"})
+				So(records.ByKey("service_name")[0].Path, ShouldEqual, "service_name")
+			})
+
+		})
+
+		Convey("When a value doesn't match its pattern", func() {
+
+			fakeYAML := []byte("service_name: seats\nreplicas: 3\nenabled: false\nimage: latest\nports: []\n")
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedYAMLResponse(fakeYAML, expected, nil)
+				So(success, ShouldStartWith, "Image: 'latest' does not match expected pattern: ^[a-z]+:[0-9.]+$")
+			})
+
+		})
+
+		Convey("When the document is invalid", func() {
+
+			fakeYAML := []byte("ports: [80, 443")
+
+			Convey("It should return an error string", func() {
+				success := ShouldMatchExpectedYAMLResponse(fakeYAML, expected, nil)
+				So(success, ShouldStartWith, "Was not possible to unmarshal YAML into a Go struct")
+			})
+
+		})
+
+	})
+
+}